@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic replaces the file at path with data, so that readers see
+// either the old or the new file but never a partial one. data is written to
+// a temporary file in the same directory and synced, then checked with verify
+// if it isn't nil. Up to backups previous versions of the file are kept
+// before the temporary file is renamed over it. If path is a symlink, the
+// file it points to is replaced.
+func writeFileAtomic(path string, data []byte, backups int, verify func(name string) error) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	// Fails once the file has been renamed into place, which is fine.
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// CreateTemp creates the file with 0600 already, but the umask may not
+	// allow it.
+	if err := os.Chmod(tmp.Name(), 0o600); err != nil {
+		return err
+	}
+
+	if verify != nil {
+		if err := verify(tmp.Name()); err != nil {
+			return err
+		}
+	}
+
+	if err := rotateBackups(path, data, backups); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// backupName returns the name of the nth backup of path, 1 being the newest.
+func backupName(path string, n int) string {
+	return fmt.Sprintf("%s.bak.%d", path, n)
+}
+
+// rotateBackups copies the file at path to its first backup, shifting the
+// existing backups up to n. Nothing is done if the file doesn't exist or
+// already contains data, so that rendering the same kubeconfig again doesn't
+// push out older versions.
+func rotateBackups(path string, data []byte, n int) error {
+	if n <= 0 {
+		return nil
+	}
+
+	current, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(current, data) {
+		return nil
+	}
+
+	for i := n; i > 1; i-- {
+		if err := os.Rename(backupName(path, i-1), backupName(path, i)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.WriteFile(backupName(path, 1), current, 0o600)
+}
+
+// symlinkAtomic points the symlink at link to target, replacing whatever is at
+// link in a single rename so that link never goes missing.
+func symlinkAtomic(target, link string) error {
+	tmp := fmt.Sprintf("%s.tmp-%d", link, os.Getpid())
+	if err := os.Remove(tmp); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	syncDir(filepath.Dir(link))
+	return nil
+}
+
+// syncDir syncs the directory dir so that renames in it survive a crash.
+// Errors are ignored since not every platform supports syncing directories.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	_ = d.Sync()
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// completionRuntime loads and compiles the config for shell completion.
+// Encrypted fields are left as they are so that completing never asks for a
+// passphrase, and configuration errors are not printed. Cobra doesn't run
+// its initializers when completing, so the config file is set up here.
+func completionRuntime() (*config.RuntimeConfig, error) {
+	initConfig()
+	if err := loadConfig(false); err != nil {
+		return nil, err
+	}
+	return config.NewCompiler(config.WithoutDecryption()).Compile(&cfg)
+}
+
+// completeWith returns a cobra completion function that completes the first
+// maxArgs arguments using fn. A negative maxArgs completes any number.
+func completeWith(maxArgs int, fn func(rc *config.RuntimeConfig, args []string, toComplete string) []string) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if maxArgs >= 0 && len(args) >= maxArgs {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		rc, err := completionRuntime()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return fn(rc, args, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeWorkspaces completes workspace names.
+func completeWorkspaces(rc *config.RuntimeConfig, _ []string, toComplete string) []string {
+	return workspaceCompletions(rc, toComplete)
+}
+
+// completeRefs completes workspaces and references to kubeconfigs, see
+// config.RuntimeConfig.Resolve.
+func completeRefs(rc *config.RuntimeConfig, _ []string, toComplete string) []string {
+	return append(workspaceCompletions(rc, toComplete), refCompletions(rc, toComplete, false)...)
+}
+
+// completeRender completes a workspace or reference, and then a kubeconfig of
+// that workspace.
+func completeRender(rc *config.RuntimeConfig, args []string, toComplete string) []string {
+	if len(args) == 1 {
+		rw := rc.Workspace(args[0])
+		if rw == nil {
+			return nil
+		}
+		return kubeconfigCompletions(rc, rw, "", toComplete)
+	}
+	return completeRefs(rc, args, toComplete)
+}
+
+// completeLogin completes a reference including kc/ctx, and then a context of
+// the kubeconfig it resolves to.
+func completeLogin(rc *config.RuntimeConfig, args []string, toComplete string) []string {
+	if len(args) == 1 {
+		ref, err := rc.Resolve(args[0])
+		if err != nil || ref.Context != nil {
+			return nil
+		}
+		return contextCompletions(ref.Kubeconfig, "", toComplete)
+	}
+	return refCompletions(rc, toComplete, true)
+}
+
+func workspaceCompletions(rc *config.RuntimeConfig, toComplete string) []string {
+	var res []string
+	for _, name := range sortedNames(rc.Workspaces) {
+		if strings.HasPrefix(name, toComplete) {
+			res = append(res, completion(name, rc.Workspaces[name].Description))
+		}
+	}
+	return res
+}
+
+// refCompletions completes kubeconfig names and aliases, and ws/kc once a
+// workspace has been typed. With contexts, kc/ctx is completed as well.
+func refCompletions(rc *config.RuntimeConfig, toComplete string, contexts bool) []string {
+	var res []string
+
+	if ws, rest, ok := strings.Cut(toComplete, "/"); ok {
+		if rw := rc.Workspace(ws); rw != nil && !strings.Contains(rest, "/") {
+			res = append(res, kubeconfigCompletions(rc, rw, ws+"/", rest)...)
+		}
+		if rk, ok := rc.KubeconfigAliases[ws]; ok && contexts {
+			res = append(res, contextCompletions(rk, ws+"/", rest)...)
+		}
+		return res
+	}
+
+	for _, name := range sortedNames(rc.KubeconfigAliases) {
+		if !strings.HasPrefix(name, toComplete) {
+			continue
+		}
+		rk := rc.KubeconfigAliases[name]
+		if name != rk.Name {
+			res = append(res, completion(name, "alias of "+rk.Name))
+			continue
+		}
+		res = append(res, completion(name, kubeconfigDescription(rc, rk)))
+	}
+	return res
+}
+
+// kubeconfigCompletions completes the kubeconfigs of rw, prefixed with prefix.
+func kubeconfigCompletions(rc *config.RuntimeConfig, rw *config.RuntimeWorkspace, prefix, toComplete string) []string {
+	var res []string
+	for _, name := range sortedNames(rw.Kubeconfigs) {
+		if strings.HasPrefix(name, toComplete) {
+			res = append(res, completion(prefix+name, kubeconfigDescription(rc, rw.Kubeconfigs[name])))
+		}
+	}
+	return res
+}
+
+// contextCompletions completes the contexts of rk, prefixed with prefix.
+func contextCompletions(rk *config.RuntimeKubeconfig, prefix, toComplete string) []string {
+	var res []string
+	for _, name := range sortedNames(rk.Contexts) {
+		if !strings.HasPrefix(name, toComplete) {
+			continue
+		}
+		description := "context of " + rk.Name
+		if ns := rk.Contexts[name].Namespace; ns != "" {
+			description += ", namespace " + ns
+		}
+		res = append(res, completion(prefix+name, description))
+	}
+	return res
+}
+
+// kubeconfigDescription describes rk by the workspace it resolves to.
+func kubeconfigDescription(rc *config.RuntimeConfig, rk *config.RuntimeKubeconfig) string {
+	ref, err := rc.Resolve(rk.Name)
+	if err != nil || ref.Workspace == nil {
+		return "kubeconfig"
+	}
+	description := "kubeconfig in " + ref.Workspace.Name
+	if ref.Workspace.Description != "" {
+		description += ": " + ref.Workspace.Description
+	}
+	if rk.Protected {
+		description += " " + protectedMarker
+	}
+	return description
+}
+
+// completion returns a completion with a description, as cobra expects them.
+func completion(value, description string) string {
+	if description == "" {
+		return value
+	}
+	return value + "\t" + description
+}
+
+// sortedNames returns the keys of m in order.
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// registerWorkspaceCompletion completes workspace names for the --workspace
+// flag of cmd.
+func registerWorkspaceCompletion(cmd *cobra.Command) {
+	if err := cmd.RegisterFlagCompletionFunc("workspace", completeWith(-1, completeWorkspaces)); err != nil {
+		panic(err)
+	}
+}
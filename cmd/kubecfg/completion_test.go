@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func newCompletionTestRuntime(t *testing.T) *config.RuntimeConfig {
+	t.Helper()
+
+	c := newRenderCommandTestConfig(filepath.Join(t.TempDir(), "vgr.yaml"))
+	c.Workspaces["work"].Description = "Day job clusters"
+	c.Kubeconfigs["vgr"].Aliases = []string{"v"}
+	c.Kubeconfigs["vgr"].Contexts["context"].Namespace = "apps"
+
+	rc, err := config.NewCompiler(config.WithoutDecryption()).Compile(&c)
+	require.NoError(t, err)
+	return rc
+}
+
+func TestCompleteRefs(t *testing.T) {
+	rc := newCompletionTestRuntime(t)
+
+	require.Equal(t, []string{
+		"work\tDay job clusters",
+		"v\talias of vgr",
+		"vgr\tkubeconfig in work: Day job clusters",
+	}, completeRefs(rc, nil, ""))
+	require.Equal(t, []string{"vgr\tkubeconfig in work: Day job clusters"}, completeRefs(rc, nil, "vg"))
+	require.Equal(t, []string{"work/vgr\tkubeconfig in work: Day job clusters"}, completeRefs(rc, nil, "work/"))
+	require.Empty(t, completeRefs(rc, nil, "vgr/"))
+}
+
+func TestCompleteRender(t *testing.T) {
+	rc := newCompletionTestRuntime(t)
+
+	require.Equal(t, []string{"vgr\tkubeconfig in work: Day job clusters"}, completeRender(rc, []string{"work"}, ""))
+	require.Empty(t, completeRender(rc, []string{"vgr"}, ""))
+}
+
+func TestCompleteLogin(t *testing.T) {
+	rc := newCompletionTestRuntime(t)
+
+	require.Equal(t, []string{"vgr/context\tcontext of vgr, namespace apps"}, completeLogin(rc, nil, "vgr/"))
+	require.Equal(t, []string{"v/context\tcontext of vgr, namespace apps"}, completeLogin(rc, nil, "v/c"))
+	require.Equal(t, []string{"context\tcontext of vgr, namespace apps"}, completeLogin(rc, []string{"work/vgr"}, ""))
+	require.Empty(t, completeLogin(rc, []string{"vgr/context"}, ""))
+}
+
+func TestCompleteWorkspaces(t *testing.T) {
+	rc := newCompletionTestRuntime(t)
+
+	require.Equal(t, []string{"work\tDay job clusters"}, completeWorkspaces(rc, nil, "w"))
+	require.Empty(t, completeWorkspaces(rc, nil, "x"))
+}
+
+func TestCompleteWithBrokenConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "kubecfg.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("kubeconfigs: [\n"), 0o600))
+
+	originalCfg, originalConfigFile := cfg, configFile
+	t.Cleanup(func() {
+		cfg, configFile = originalCfg, originalConfigFile
+		viper.Reset()
+	})
+	configFile = file
+
+	called := false
+	complete := completeWith(1, func(*config.RuntimeConfig, []string, string) []string {
+		called = true
+		return nil
+	})
+
+	completions, directive := complete(newRenderCmd(), nil, "")
+	require.Empty(t, completions)
+	require.Equal(t, cobra.ShellCompDirectiveNoFileComp, directive)
+	require.False(t, called)
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "config",
+		Short:        "Inspect kubecfg.yaml",
+		Long:         `Inspect the kubecfg configuration as kubecfg reads it.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+	}
+
+	cmd.AddCommand(newConfigViewCmd())
+
+	return cmd
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+const redacted = "<redacted>"
+
+var configViewStdout io.Writer = os.Stdout
+
+type configViewOptions struct {
+	effective bool
+	raw       bool
+}
+
+func newConfigViewCmd() *cobra.Command {
+	var opts configViewOptions
+
+	cmd := &cobra.Command{
+		Use:   "view",
+		Short: "Print the config",
+		Long: `Print kubecfg.yaml merged with its included files. With --effective, the
+overlays matching this host are applied and variables are interpolated, showing
+the config that other commands use. Secrets are redacted unless --raw is given.`,
+		Example: `  kubecfg config view
+  kubecfg config view --effective
+  kubecfg config view --effective --raw`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: withConfig(func(cmd *cobra.Command, args []string) error {
+			return runConfigViewCmd(opts, configViewStdout)
+		}),
+	}
+
+	cmd.Flags().BoolVar(&opts.effective, "effective", false, "Apply overlays and interpolation")
+	cmd.Flags().BoolVar(&opts.raw, "raw", false, "Show secrets instead of redacting them")
+
+	return cmd
+}
+
+func runConfigViewCmd(opts configViewOptions, stdout io.Writer) error {
+	view := fileCfg
+	if opts.effective {
+		view = cfg
+
+		// The overlays are already applied, list them instead.
+		for _, i := range cfg.AppliedOverlays {
+			overlay := cfg.Overlays[i]
+			if overlay.Name != "" {
+				fmt.Fprintf(stdout, "# Applied overlays[%d] (%s)\n", i, overlay.Name)
+			} else {
+				fmt.Fprintf(stdout, "# Applied overlays[%d]\n", i)
+			}
+		}
+		view.Overlays = nil
+	}
+
+	if !opts.raw {
+		view = redactConfig(view)
+	}
+
+	b, err := view.Marshal()
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(b)
+	return err
+}
+
+// redactConfig returns a copy of c with the secrets of its auth infos
+// replaced. Encrypted values are kept since they are safe to show.
+func redactConfig(c config.Config) config.Config {
+	c.AuthInfos = redactAuthInfos(c.AuthInfos)
+
+	kubeconfigs := make(map[string]*config.Kubeconfig, len(c.Kubeconfigs))
+	for name, kc := range c.Kubeconfigs {
+		if kc != nil {
+			copied := *kc
+			copied.AuthInfos = redactAuthInfos(kc.AuthInfos)
+			kc = &copied
+		}
+		kubeconfigs[name] = kc
+	}
+	c.Kubeconfigs = kubeconfigs
+
+	return c
+}
+
+func redactAuthInfos(authInfos map[string]*config.AuthInfo) map[string]*config.AuthInfo {
+	if authInfos == nil {
+		return nil
+	}
+
+	res := make(map[string]*config.AuthInfo, len(authInfos))
+	for name, ai := range authInfos {
+		if ai != nil {
+			copied := *ai
+			if copied.Token != "" {
+				copied.Token = redacted
+			}
+			if copied.Password != "" {
+				copied.Password = redacted
+			}
+			if len(copied.ClientKeyData) > 0 {
+				copied.ClientKeyData = []byte(redacted)
+			}
+			ai = &copied
+		}
+		res[name] = ai
+	}
+	return res
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func newConfigViewTestConfig() config.Config {
+	return config.Config{
+		Version: "v1",
+		BaseDir: "~/.kube",
+		Kubeconfigs: map[string]*config.Kubeconfig{
+			"prod": {
+				Path: "@/prod.yaml",
+				AuthInfos: map[string]*config.AuthInfo{
+					"admin": {Token: "secret", EncryptedPassword: "age"},
+				},
+			},
+		},
+		Overlays: []*config.Overlay{
+			{Name: "ci", Match: config.OverlayMatch{Env: "CI"}, BaseDir: "/builds/kube"},
+		},
+	}
+}
+
+func TestRunConfigViewCmdPrintsFileConfig(t *testing.T) {
+	originalCfg, originalFileCfg := cfg, fileCfg
+	t.Cleanup(func() {
+		cfg, fileCfg = originalCfg, originalFileCfg
+	})
+
+	fileCfg = newConfigViewTestConfig()
+
+	var stdout bytes.Buffer
+	err := runConfigViewCmd(configViewOptions{}, &stdout)
+	require.NoError(t, err)
+	require.Equal(t, `version: v1
+kubeconfigs:
+  prod:
+    path: '@/prod.yaml'
+    auth_infos:
+      admin:
+        token: <redacted>
+        encryptedPassword: age
+base_dir: ~/.kube
+overlays:
+  - name: ci
+    match:
+      env: CI
+    base_dir: /builds/kube
+`, stdout.String())
+
+	// The config itself is not redacted.
+	require.Equal(t, "secret", fileCfg.Kubeconfigs["prod"].AuthInfos["admin"].Token)
+}
+
+func TestRunConfigViewCmdPrintsEffectiveConfig(t *testing.T) {
+	originalCfg, originalFileCfg := cfg, fileCfg
+	t.Cleanup(func() {
+		cfg, fileCfg = originalCfg, originalFileCfg
+	})
+
+	fileCfg = newConfigViewTestConfig()
+	overlaid, err := fileCfg.ApplyOverlays(config.Host{
+		LookupEnv: func(key string) (string, bool) { return "true", key == "CI" },
+	})
+	require.NoError(t, err)
+	cfg = *overlaid
+
+	var stdout bytes.Buffer
+	err = runConfigViewCmd(configViewOptions{effective: true, raw: true}, &stdout)
+	require.NoError(t, err)
+	require.Equal(t, `# Applied overlays[0] (ci)
+version: v1
+kubeconfigs:
+  prod:
+    path: '@/prod.yaml'
+    auth_infos:
+      admin:
+        token: secret
+        encryptedPassword: age
+base_dir: /builds/kube
+`, stdout.String())
+}
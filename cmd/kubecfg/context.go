@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/amimof/kubecfg/pkg/cmdutil"
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// previousStateFile is kept in the base directory and holds the context and
+// namespace each kubeconfig had before the last switch, for "-".
+const previousStateFile = ".kubecfg-previous.json"
+
+// togglePrevious is the argument that switches back to the previous value.
+const togglePrevious = "-"
+
+func newContextCmd() *cobra.Command {
+	var save bool
+
+	cmd := &cobra.Command{
+		Use:   "context [CONTEXT|-]",
+		Short: "Switch context of the active kubeconfig",
+		Long: `Change the current context of the kubeconfig that ~/.kube/config points to,
+without logging in or rendering it again.
+
+Without CONTEXT, the context is picked in a fuzzy finder. "-" switches back to
+the previous context. With --save, the context is also written to kubecfg.yaml
+as current_context so the next render keeps it.`,
+		Example: `  kubecfg context
+  kubecfg context admin
+  kubecfg context -
+  kubecfg context admin --save`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: withConfig(func(cmd *cobra.Command, args []string) error {
+			var name string
+			if len(args) == 1 {
+				name = args[0]
+			}
+			return runContextCmd(name, save)
+		}),
+	}
+
+	cmd.Flags().BoolVar(&save, "save", false, "Write the context to kubecfg.yaml as current_context")
+
+	return cmd
+}
+
+func runContextCmd(name string, save bool) error {
+	active, err := loadActiveKubeconfig()
+	if err != nil {
+		return err
+	}
+	kc := active.Config
+	current := kc.CurrentContext
+
+	switch name {
+	case "":
+		name, err = pickFrom(sortedNames(kc.Contexts), current)
+		if err != nil {
+			if errors.Is(err, errNoSelection) {
+				return nil
+			}
+			return err
+		}
+	case togglePrevious:
+		name = active.Previous.Context
+		if name == "" {
+			return fmt.Errorf("no previous context for %s", active.Path)
+		}
+	}
+
+	if _, ok := kc.Contexts[name]; !ok {
+		return fmt.Errorf("context does not exist: %s", name)
+	}
+
+	err = active.update(previousState{Context: current, Namespace: active.Previous.Namespace}, func(kc *api.Config) error {
+		if _, ok := kc.Contexts[name]; !ok {
+			return fmt.Errorf("context does not exist: %s", name)
+		}
+		kc.CurrentContext = name
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if save {
+		if err := active.save("current_context", name); err != nil {
+			return err
+		}
+	}
+
+	cmdutil.Printf(`{{ "✔" | FgGreen }} Switched to context {{ .Name | FgCyan }}`, cmdutil.Data{"Name": name})
+	return nil
+}
+
+// activeKubeconfig is the kubeconfig file ~/.kube/config points to, together
+// with the kubeconfig it was rendered from, if any.
+type activeKubeconfig struct {
+	Path     string
+	Config   *api.Config
+	Runtime  *config.RuntimeKubeconfig
+	BaseDir  string
+	Previous previousState
+}
+
+// previousState is the context and namespace of a kubeconfig before it was
+// last switched.
+type previousState struct {
+	Context   string `json:"context,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+func loadActiveKubeconfig() (*activeKubeconfig, error) {
+	runtime, err := config.NewCompiler(config.WithoutDecryption()).Compile(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := activeKubeconfigPath(runtime.BaseDir)
+	if err != nil {
+		return nil, fmt.Errorf("no active kubeconfig: %w", err)
+	}
+
+	kc, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	states, err := readPreviousStates(runtime.BaseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &activeKubeconfig{
+		Path:     path,
+		Config:   kc,
+		Runtime:  kubeconfigForFile(runtime, path),
+		BaseDir:  runtime.BaseDir,
+		Previous: states[path],
+	}, nil
+}
+
+// update applies change to the kubeconfig as it is on disk and writes it back,
+// then remembers previous for the next "-". The kubeconfig is locked and
+// reloaded first, so that a render that finished since it was loaded isn't
+// undone.
+func (a *activeKubeconfig) update(previous previousState, change func(kc *api.Config) error) error {
+	lock, err := lockPath(context.Background(), a.Path, printWaiting)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	kc, err := clientcmd.LoadFromFile(a.Path)
+	if err != nil {
+		return err
+	}
+	if err := change(kc); err != nil {
+		return err
+	}
+	if err := writeKubeconfig(a.Path, kc); err != nil {
+		return err
+	}
+	a.Config = kc
+
+	return a.writePrevious(previous)
+}
+
+// writePrevious remembers previous as the state of the kubeconfig before the
+// last switch.
+func (a *activeKubeconfig) writePrevious(previous previousState) error {
+	lock, err := lockBaseDir(context.Background(), a.BaseDir, printWaiting)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	states, err := readPreviousStates(a.BaseDir)
+	if err != nil {
+		return err
+	}
+	states[a.Path] = previous
+
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(a.BaseDir, previousStateFile), data, 0, nil)
+}
+
+// save writes value to key of the kubeconfig in the file of kubecfg.yaml it
+// is defined in.
+func (a *activeKubeconfig) save(key, value string) error {
+	if a.Runtime == nil {
+		return fmt.Errorf("%s is not rendered by kubecfg, can't save %s", a.Path, key)
+	}
+
+	file := configFile
+	if kc := fileCfg.Kubeconfigs[a.Runtime.Name]; kc != nil && kc.Source != "" {
+		file = kc.Source
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	doc, err := config.ParseDocument(data)
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("kubeconfigs.%s.%s", a.Runtime.Name, key)
+	if err := doc.SetString(path, value); err != nil {
+		return err
+	}
+
+	if err := writeConfigDocument(file, doc); err != nil {
+		return err
+	}
+
+	cmdutil.Printf(`{{ "✔" | FgGreen }} Saved {{ .Path | FgYellow }} in {{ .File | FgHiBlack }}`, cmdutil.Data{"Path": path, "File": file})
+	return nil
+}
+
+func readPreviousStates(baseDir string) (map[string]previousState, error) {
+	states := make(map[string]previousState)
+
+	data, err := os.ReadFile(filepath.Join(baseDir, previousStateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return states, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("read %s: %w", previousStateFile, err)
+	}
+	return states, nil
+}
+
+// pickFrom lets the user pick one of items in the fuzzy finder. current is
+// marked as such.
+func pickFrom(items []string, current string) (string, error) {
+	inputChan := make(chan string)
+	go func() {
+		for _, item := range items {
+			if item == current {
+				item += "\t(current)"
+			}
+			inputChan <- item
+		}
+		close(inputChan)
+	}()
+
+	selected, err := pick(inputChan)
+	if err != nil {
+		return "", err
+	}
+	selected, _, _ = strings.Cut(selected, "\t")
+
+	return selected, nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// setupActiveKubeconfig writes a kubeconfig with contexts admin and view,
+// activates it and sets cfg to a config that renders it from configPath.
+func setupActiveKubeconfig(t *testing.T, kubecfgYAML string) (string, string) {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	targetPath := filepath.Join(tmpDir, "vgr.yaml")
+	configPath := filepath.Join(tmpDir, "kubecfg.yaml")
+
+	originalCfg := cfg
+	originalFileCfg := fileCfg
+	originalConfigFile := configFile
+	t.Cleanup(func() {
+		cfg = originalCfg
+		fileCfg = originalFileCfg
+		configFile = originalConfigFile
+	})
+
+	cfg = newRenderCommandTestConfig(targetPath)
+	cfg.Kubeconfigs["vgr"].Contexts = map[string]*config.Context{
+		"admin": {Cluster: "cluster", AuthInfo: "user", Namespace: "payments"},
+		"view":  {Cluster: "cluster", AuthInfo: "user"},
+	}
+	fileCfg = cfg
+	configFile = configPath
+	require.NoError(t, os.WriteFile(configPath, []byte(kubecfgYAML), 0o600))
+
+	kc := api.NewConfig()
+	kc.Clusters["cluster"] = &api.Cluster{Server: "https://example.com"}
+	kc.AuthInfos["user"] = &api.AuthInfo{}
+	kc.Contexts["admin"] = &api.Context{Cluster: "cluster", AuthInfo: "user", Namespace: "payments"}
+	kc.Contexts["view"] = &api.Context{Cluster: "cluster", AuthInfo: "user"}
+	kc.CurrentContext = "admin"
+	require.NoError(t, writeKubeconfig(targetPath, kc))
+	require.NoError(t, setConfig(tmpDir, targetPath))
+
+	return targetPath, configPath
+}
+
+func loadTestKubeconfig(t *testing.T, path string) *api.Config {
+	t.Helper()
+	kc, err := clientcmd.LoadFromFile(path)
+	require.NoError(t, err)
+	return kc
+}
+
+func TestRunContextCmdSwitchesAndToggles(t *testing.T) {
+	targetPath, _ := setupActiveKubeconfig(t, "")
+
+	require.NoError(t, runContextCmd("view", false))
+	require.Equal(t, "view", loadTestKubeconfig(t, targetPath).CurrentContext)
+
+	require.NoError(t, runContextCmd(togglePrevious, false))
+	require.Equal(t, "admin", loadTestKubeconfig(t, targetPath).CurrentContext)
+
+	require.NoError(t, runContextCmd(togglePrevious, false))
+	require.Equal(t, "view", loadTestKubeconfig(t, targetPath).CurrentContext)
+
+	err := runContextCmd("missing", false)
+	require.EqualError(t, err, "context does not exist: missing")
+}
+
+func TestRunContextCmdWithoutPrevious(t *testing.T) {
+	targetPath, _ := setupActiveKubeconfig(t, "")
+
+	err := runContextCmd(togglePrevious, false)
+	require.EqualError(t, err, "no previous context for "+targetPath)
+}
+
+func TestRunContextCmdSave(t *testing.T) {
+	_, configPath := setupActiveKubeconfig(t, `kubeconfigs:
+  vgr:
+    # picked by hand
+    current_context: admin
+`)
+
+	require.NoError(t, runContextCmd("view", true))
+
+	data, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	require.Equal(t, `kubeconfigs:
+  vgr:
+    # picked by hand
+    current_context: view
+`, string(data))
+}
+
+func TestRunNsCmdSwitchesAndToggles(t *testing.T) {
+	targetPath, _ := setupActiveKubeconfig(t, "")
+
+	require.NoError(t, runNsCmd("search", false))
+	require.Equal(t, "search", loadTestKubeconfig(t, targetPath).Contexts["admin"].Namespace)
+
+	require.NoError(t, runNsCmd(togglePrevious, false))
+	require.Equal(t, "payments", loadTestKubeconfig(t, targetPath).Contexts["admin"].Namespace)
+}
+
+func TestRunNsCmdSave(t *testing.T) {
+	_, configPath := setupActiveKubeconfig(t, `kubeconfigs:
+  vgr:
+    contexts:
+      admin:
+        namespace: payments
+`)
+
+	// The context sets its own namespace, so that is the one that is saved.
+	require.NoError(t, runNsCmd("search", true))
+	require.NoError(t, runContextCmd("view", false))
+	require.NoError(t, runNsCmd("web", true))
+
+	data, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	require.Equal(t, `kubeconfigs:
+  vgr:
+    contexts:
+      admin:
+        namespace: search
+    default_namespace: web
+`, string(data))
+}
+
+func TestKnownNamespaces(t *testing.T) {
+	setupActiveKubeconfig(t, "")
+	cfg.Kubeconfigs["vgr"].DefaultNamespace = "web"
+
+	active, err := loadActiveKubeconfig()
+	require.NoError(t, err)
+	require.Equal(t, []string{"default", "payments", "web"}, knownNamespaces(active))
+}
+
+func TestRunContextCmdDoesNotUndoConcurrentRender(t *testing.T) {
+	targetPath, _ := setupActiveKubeconfig(t, "")
+
+	for i := range 10 {
+		server := fmt.Sprintf("https://%d.example.com", i)
+		cfg.Kubeconfigs["vgr"].Clusters["cluster"].Server = server
+
+		contextName := "view"
+		if i%2 == 1 {
+			contextName = "admin"
+		}
+
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		wg.Go(func() {
+			errs[0] = runRenderCmd(context.Background(), "work", "vgr", true, false, time.Second)
+		})
+		wg.Go(func() {
+			errs[1] = runContextCmd(contextName, false)
+		})
+		wg.Wait()
+		require.NoError(t, errors.Join(errs...))
+
+		// Whichever ran last, the rendered server is kept.
+		require.Equal(t, server, loadTestKubeconfig(t, targetPath).Clusters["cluster"].Server)
+	}
+}
+
+func TestRunNsCmdWaitsForRender(t *testing.T) {
+	targetPath, _ := setupActiveKubeconfig(t, "")
+
+	// A render holds the lock while ns starts.
+	held, err := lockPath(context.Background(), targetPath, nil)
+	require.NoError(t, err)
+
+	done := make(chan error)
+	go func() {
+		done <- runNsCmd("kube-system", false)
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	kc := loadTestKubeconfig(t, targetPath)
+	kc.Clusters["cluster"].Server = "https://rendered.example.com"
+	require.NoError(t, writeKubeconfig(targetPath, kc))
+	require.NoError(t, held.Release())
+	require.NoError(t, <-done)
+
+	kc = loadTestKubeconfig(t, targetPath)
+	require.Equal(t, "https://rendered.example.com", kc.Clusters["cluster"].Server)
+	require.Equal(t, "kube-system", kc.Contexts["admin"].Namespace)
+}
@@ -1,35 +1,52 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/amimof/kubecfg/pkg/cmdutil"
 	"github.com/amimof/kubecfg/pkg/config"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 var describeWorkspaceStdout io.Writer = os.Stdout
 
 func newDescribeWorkspaceCmd() *cobra.Command {
+	var selector string
+
 	cmd := &cobra.Command{
-		Use:   "workspace [WORKSPACE]",
+		Use:   "workspace [WORKSPACE|REF]...",
 		Short: "Show workspace details",
-		Long:  `Show a workspace and its kubeconfigs in a readable format.`,
+		Long: `Show a workspace and its kubeconfigs in a readable format.
+
+Arguments that aren't workspaces are resolved like in kubecfg render and show
+only the kubeconfig or context they resolve to.`,
 		Example: `  kubecfg describe workspace homelab
-	  kubecfg describe workspace homelab`,
-		Args:         cobra.MinimumNArgs(0),
-		SilenceUsage: true,
+  kubecfg describe workspace homelab/mainframe
+  kubecfg describe workspace prod-eu/admin`,
+		Args:              cobra.MinimumNArgs(0),
+		ValidArgsFunction: completeWith(-1, completeRefs),
+		SilenceUsage:      true,
 		RunE: withConfig(func(cmd *cobra.Command, args []string) error {
-			return runDescribeWorkspaceCmd(args, describeWorkspaceStdout)
+			sel, err := parseSelector(selector)
+			if err != nil {
+				return err
+			}
+			return runDescribeWorkspaceCmd(args, sel, describeWorkspaceStdout)
 		}),
 	}
 
+	addSelectorFlag(cmd, &selector)
+
 	return cmd
 }
 
-func runDescribeWorkspaceCmd(args []string, stdout io.Writer) error {
+func runDescribeWorkspaceCmd(args []string, selector labels.Selector, stdout io.Writer) error {
 	compiler, err := newCompilerWithOptionalDecryptor(&cfg, cfg.IdentityFiles)
 	if err != nil {
 		return err
@@ -40,26 +57,47 @@ func runDescribeWorkspaceCmd(args []string, stdout io.Writer) error {
 		return err
 	}
 
-	names := args
+	var workspaces []config.RuntimeWorkspace
 
-	if len(names) == 0 {
+	if len(args) == 0 {
 		for _, ws := range runtime.Workspaces {
-			names = append(names, ws.Name)
+			workspaces = append(workspaces, *ws)
 		}
 	}
 
-	// Check so that all workspaces exist
-	for _, name := range names {
-		if !runtime.WorkspaceExists(name) {
-			return fmt.Errorf("workspace does not exist: %s", name)
+	// Arguments that aren't workspaces are resolved as references and describe
+	// only the kubeconfig, or context, they resolve to.
+	for _, arg := range args {
+		if runtime.WorkspaceExists(arg) {
+			workspaces = append(workspaces, *runtime.Workspace(arg))
+			continue
+		}
+
+		ref, err := runtime.Resolve(arg)
+		if errors.Is(err, config.ErrNoMatch) {
+			return fmt.Errorf("workspace does not exist: %s", arg)
 		}
+		if err != nil {
+			return err
+		}
+		if ref.Workspace == nil {
+			return fmt.Errorf("kubeconfig %s is not in any workspace", ref.Kubeconfig.Name)
+		}
+
+		rk := *ref.Kubeconfig
+		if ref.Context != nil {
+			rk.Contexts = map[string]*config.RuntimeContext{ref.Context.Name: ref.Context}
+		}
+		rw := *ref.Workspace
+		rw.Kubeconfigs = map[string]*config.RuntimeKubeconfig{rk.Name: &rk}
+		workspaces = append(workspaces, rw)
 	}
 
-	for i, name := range names {
-		if err := renderWorkspaceDescription(stdout, *runtime.Workspace(name)); err != nil {
+	for i, rw := range workspaces {
+		if err := renderWorkspaceDescription(stdout, rw, selector); err != nil {
 			return fmt.Errorf("render error: %w", err)
 		}
-		if i != len(names)-1 {
+		if i != len(workspaces)-1 {
 			if err := cmdutil.RenderLine(stdout, 60); err != nil {
 				return fmt.Errorf("render error: %w", err)
 			}
@@ -69,26 +107,40 @@ func runDescribeWorkspaceCmd(args []string, stdout io.Writer) error {
 	return nil
 }
 
-func renderWorkspaceDescription(stdout io.Writer, rw config.RuntimeWorkspace) error {
+// renderWorkspaceDescription describes rw. Only kubeconfigs and contexts whose
+// labels match selector are included.
+func renderWorkspaceDescription(stdout io.Writer, rw config.RuntimeWorkspace, selector labels.Selector) error {
 	containers := []*cmdutil.Container{
 		cmdutil.NewContainer(nil,
 			cmdutil.NewElement(`{{ "Name" | FgHiGreen }}:               {{ .Workspace.Name }}`),
 			cmdutil.NewElement(`{{ "Description" | FgHiGreen }}:        {{ .Workspace.Description }}`),
+			cmdutil.NewElement(`{{ "Source" | FgHiGreen }}:             {{ .Workspace.Source }}`),
 			cmdutil.NewElement(`{{ "Default Kubeconfig" | FgHiGreen }}: {{ .WorkspaceDefaultKubeconfig }}`),
 			cmdutil.NewElement(`{{ "Kubeconfigs" | FgHiGreen }}:        {{ .Workspace.Kubeconfigs | len | string | FgBlue }}`),
 		).WithLayout(cmdutil.Layout{Dimensions: [2]int{1024, 0}}),
 	}
 
+	for _, exp := range describeExpansions("workspaces." + rw.Name) {
+		containers = append(containers, cmdutil.NewContainer(cmdutil.Data{
+			"Expansion": exp,
+		},
+			cmdutil.NewElement(`{{ "Expanded" | FgHiGreen }}:           {{ .Container.Expansion.Path }}: {{ .Container.Expansion.Value }} {{ .Container.Expansion.Raw | printf "(%s)" | FgHiBlack }}`),
+		).WithLayout(cmdutil.Layout{Dimensions: [2]int{1024, 0}}))
+	}
+
 	var i int
 
-	for _, kubeconfig := range rw.Kubeconfigs {
+	for _, kubeconfig := range selectKubeconfigs(rw.Kubeconfigs, selector) {
 		container := cmdutil.NewContainer(cmdutil.Data{
 			"Kubeconfig": kubeconfig,
+			"Labels":     labels.Set(kubeconfig.Labels).String(),
 			"Index":      i,
 		},
 			cmdutil.NewElement(`{{ .Container.Index  | string | FgMagenta }}: {{ "Kubeconfig" | FgHiGreen }}:           {{ .Container.Kubeconfig.Name }}`),
 			cmdutil.NewElement(`     {{ "Path" | FgHiGreen}}:                  {{ .Container.Kubeconfig.Path }}`),
+			cmdutil.NewElement(`     {{ "Source" | FgHiGreen}}:                {{ .Container.Kubeconfig.Source }}`),
 			cmdutil.NewElement(`     {{ "Aliases" | FgHiGreen}}:               {{ .Container.Kubeconfig.Aliases }}`),
+			cmdutil.NewElement(`     {{ "Labels" | FgHiGreen}}:                {{ .Container.Labels }}`),
 			cmdutil.NewElement(`     {{ "Protected" | FgHiGreen }}:            {{ .Container.Kubeconfig.Protected | string | FgYellow }}`),
 			cmdutil.NewElement(`     {{ "Current Context" | FgHiGreen }}:      {{ .Container.Kubeconfig.CurrentContext.Name }}`),
 			cmdutil.NewElement(`     {{ "Default Context" | FgHiGreen }}:      {{ .Container.Kubeconfig.DefaultContext.Name }}`),
@@ -99,17 +151,30 @@ func renderWorkspaceDescription(stdout io.Writer, rw config.RuntimeWorkspace) er
 
 		containers = append(containers, container)
 
+		for _, exp := range describeExpansions("kubeconfigs." + kubeconfig.Name) {
+			containers = append(containers, cmdutil.NewContainer(cmdutil.Data{
+				"Expansion": exp,
+			},
+				cmdutil.NewElement(`     {{ "Expanded" | FgHiGreen }}:              {{ .Container.Expansion.Path }}: {{ .Container.Expansion.Value }} {{ .Container.Expansion.Raw | printf "(%s)" | FgHiBlack }}`),
+			).WithLayout(cmdutil.Layout{Dimensions: [2]int{1024, 0}}))
+		}
+
 		var y int
 
 		for _, context := range kubeconfig.Contexts {
+			if !selector.Matches(labels.Set(context.Labels)) {
+				continue
+			}
 			containers = append(containers, cmdutil.NewContainer(cmdutil.Data{
 				"Context": context,
+				"Labels":  labels.Set(context.Labels).String(),
 				"Index":   y,
 			},
-				cmdutil.NewElement(`     {{ .Container.Index | string | FgMagenta}}: {{ "Context:" | FgHiGreen }}            {{ .Container.Context.Name }}`),
+				cmdutil.NewElement(`     {{ .Container.Index | string | FgMagenta}}: {{ "Context:" | FgHiGreen }}            {{ .Container.Context.Name }}{{ if .Container.Context.Template }} {{ .Container.Context.Template | printf "(generated by %s)" | FgHiBlack }}{{ end }}`),
 				cmdutil.NewElement(`        {{ "Cluster:" | FgHiGreen }}            {{ .Container.Context.Cluster.Name }}`),
 				cmdutil.NewElement(`        {{ "AuthInfo:" | FgHiGreen }}           {{ .Container.Context.AuthInfo.Name }}`),
 				cmdutil.NewElement(`        {{ "Namespace:"  | FgHiGreen}}          {{ .Container.Context.Namespace }}`),
+				cmdutil.NewElement(`        {{ "Labels:"  | FgHiGreen}}             {{ .Container.Labels }}`),
 				cmdutil.NewElement(`        {{ "Import:" | FgHiGreen }}`),
 				cmdutil.NewElement(`          {{ "Login Source:" | FgHiGreen }}      {{ .Container.Context.Import.LoginSourceName }}`),
 				cmdutil.NewElement(`          {{ "Context:" | FgHiGreen }}           {{ .Container.Context.Import.ContextName }}`),
@@ -127,6 +192,28 @@ func renderWorkspaceDescription(stdout io.Writer, rw config.RuntimeWorkspace) er
 	}, containers...)
 }
 
+// describeExpansions returns the interpolated values of the kubeconfig or
+// workspace at prefix, with paths relative to it. Expanded auth info values are
+// redacted since they usually hold credentials.
+func describeExpansions(prefix string) []config.Expansion {
+	var res []config.Expansion
+	for _, exp := range cfg.Expansions {
+		path, ok := strings.CutPrefix(exp.Path, prefix+".")
+		if !ok {
+			continue
+		}
+		if strings.HasPrefix(path, "auth_infos.") {
+			exp.Value = "<redacted>"
+		}
+		exp.Path = path
+		res = append(res, exp)
+	}
+	slices.SortFunc(res, func(a, b config.Expansion) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return res
+}
+
 func workspaceDefaultKubeconfigDisplay(rk *config.RuntimeKubeconfig) string {
 	if rk == nil {
 		return ""
@@ -6,7 +6,9 @@ import (
 	"testing"
 
 	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/fatih/color"
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 func TestRunDescribeWorkspaceCmdRendersDefaultKubeconfig(t *testing.T) {
@@ -18,7 +20,7 @@ func TestRunDescribeWorkspaceCmdRendersDefaultKubeconfig(t *testing.T) {
 	cfg = newDescribeWorkspaceTestConfig()
 
 	var stdout bytes.Buffer
-	err := runDescribeWorkspaceCmd([]string{"work"}, &stdout)
+	err := runDescribeWorkspaceCmd([]string{"work"}, labels.Everything(), &stdout)
 	require.NoError(t, err)
 
 	output := stdout.String()
@@ -70,7 +72,7 @@ func TestRunDescribeWorkspaceCmdRendersEmptyDefaultKubeconfigWhenUnset(t *testin
 	cfg.Workspaces["work"].DefaultKubeconfig = ""
 
 	var stdout bytes.Buffer
-	err := runDescribeWorkspaceCmd([]string{"work"}, &stdout)
+	err := runDescribeWorkspaceCmd([]string{"work"}, labels.Everything(), &stdout)
 	require.NoError(t, err)
 
 	for _, line := range strings.Split(stdout.String(), "\n") {
@@ -82,3 +84,76 @@ func TestRunDescribeWorkspaceCmdRendersEmptyDefaultKubeconfigWhenUnset(t *testin
 
 	t.Fatal("default kubeconfig line not found")
 }
+
+func TestRunDescribeWorkspaceCmdRendersSourceFile(t *testing.T) {
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	cfg = newDescribeWorkspaceTestConfig()
+	cfg.Workspaces["work"].Source = "/home/me/.config/kubecfg.yaml"
+	cfg.Kubeconfigs["vgr"].Source = "/home/me/.config/kubecfg.d/vgr.yaml"
+
+	var stdout bytes.Buffer
+	err := runDescribeWorkspaceCmd([]string{"work"}, labels.Everything(), &stdout)
+	require.NoError(t, err)
+
+	require.Regexp(t, `Source:\s+/home/me/.config/kubecfg.yaml`, stdout.String())
+	require.Regexp(t, `Source:\s+/home/me/.config/kubecfg.d/vgr.yaml`, stdout.String())
+}
+
+func TestRunDescribeWorkspaceCmdRendersExpandedValues(t *testing.T) {
+	originalCfg := cfg
+	originalNoColor := color.NoColor
+	t.Cleanup(func() {
+		cfg = originalCfg
+		color.NoColor = originalNoColor
+	})
+	color.NoColor = true
+
+	raw := newDescribeWorkspaceTestConfig()
+	raw.Vars = map[string]string{"dir": "/tmp"}
+	raw.Kubeconfigs["vgr"].Path = "${var:dir}/vgr.yaml"
+	raw.Kubeconfigs["vgr"].AuthInfos["user"].Token = "${var:dir}"
+
+	expanded, diags := raw.Expand()
+	require.Empty(t, diags)
+	cfg = *expanded
+
+	var stdout bytes.Buffer
+	err := runDescribeWorkspaceCmd([]string{"work"}, labels.Everything(), &stdout)
+	require.NoError(t, err)
+
+	output := stdout.String()
+	require.Contains(t, output, "Expanded:              path: /tmp/vgr.yaml (${var:dir}/vgr.yaml)")
+	require.Contains(t, output, "auth_infos.user.token: <redacted> (${var:dir})")
+}
+
+func TestRunDescribeWorkspaceCmdMarksGeneratedContexts(t *testing.T) {
+	originalCfg := cfg
+	originalNoColor := color.NoColor
+	t.Cleanup(func() {
+		cfg = originalCfg
+		color.NoColor = originalNoColor
+	})
+	color.NoColor = true
+
+	cfg = newDescribeWorkspaceTestConfig()
+	cfg.Kubeconfigs["vgr"].ContextTemplates = map[string]*config.ContextTemplate{
+		"matrix": {
+			Name:       "{{.namespace}}",
+			Clusters:   []string{"cluster"},
+			Namespaces: []string{"payments"},
+			Users:      []string{"user"},
+		},
+	}
+
+	var stdout bytes.Buffer
+	err := runDescribeWorkspaceCmd([]string{"work"}, labels.Everything(), &stdout)
+	require.NoError(t, err)
+
+	output := stdout.String()
+	require.Regexp(t, `Context:\s+payments \(generated by matrix\)`, output)
+	require.Regexp(t, `Context:\s+admin\n`, output)
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/amimof/kubecfg/pkg/cmdutil"
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+var (
+	envStdout io.Writer = os.Stdout
+
+	// envSessionDir returns the directory of the kubeconfigs kubecfg env
+	// writes to select a context.
+	envSessionDir = func() (string, error) {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "kubecfg", "env"), nil
+	}
+)
+
+// envShells are the shells kubecfg env can print exports for.
+var envShells = []string{"bash", "zsh", "fish", "sh"}
+
+type envOptions struct {
+	shell       string
+	unset       bool
+	noLogin     bool
+	yes         bool
+	selector    labels.Selector
+	waitTimeout time.Duration
+}
+
+func newEnvCmd() *cobra.Command {
+	var (
+		opts     envOptions
+		selector string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "env [REF|WORKSPACE]",
+		Short: "Print shell exports for a kubeconfig",
+		Long: `Print the exports that point KUBECONFIG at a kubeconfig in the current shell,
+for use with eval. The kubeconfig is rendered first if it doesn't exist, and
+~/.kube/config is left alone.
+
+KUBECFG_WORKSPACE and KUBECFG_KUBECONFIG are exported as well. For a context,
+KUBECONFIG also lists a kubeconfig in the user cache directory that only selects
+the context and holds no credentials, so other terminals keep their context. The
+next kubecfg env or --unset in the shell removes it. For a workspace, KUBECONFIG
+lists every kubeconfig in it. The shell is taken from $SHELL unless --shell is
+given. Without REF, the kubeconfig is picked in a fuzzy finder.`,
+		Example: `  eval "$(kubecfg env prod-eu)"
+  eval "$(kubecfg env homelab)"
+  kubecfg env prod-eu --shell fish | source
+  eval "$(kubecfg env --unset)"`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeWith(1, completeRefs),
+		SilenceUsage:      true,
+		RunE: withConfig(func(cmd *cobra.Command, args []string) error {
+			sel, err := parseSelector(selector)
+			if err != nil {
+				return err
+			}
+			opts.selector = sel
+
+			var ref string
+			if len(args) == 1 {
+				ref = args[0]
+			}
+			return runEnvCmd(cmd.Context(), ref, opts, envStdout)
+		}),
+	}
+
+	cmd.PersistentFlags().StringVar(&opts.shell, "shell", "", "Shell to print exports for, one of "+strings.Join(envShells, ", "))
+	cmd.PersistentFlags().BoolVar(&opts.unset, "unset", false, "Print commands that unset the variables instead")
+	cmd.PersistentFlags().BoolVar(&opts.noLogin, "no-login", false, "Skip execution of login flow if the kubeconfig has to be rendered")
+	addSelectorFlag(cmd, &selector)
+	addYesFlag(cmd, &opts.yes)
+	cmd.PersistentFlags().DurationVar(&opts.waitTimeout, "timeout", time.Second*30, "How long in seconds to wait for login opearation to finish before giving up")
+
+	return cmd
+}
+
+func runEnvCmd(ctx context.Context, ref string, opts envOptions, stdout io.Writer) error {
+	shell, err := envShell(opts.shell)
+	if err != nil {
+		return err
+	}
+
+	if opts.unset {
+		if err := removeEnvSessions(os.Getenv("KUBECONFIG")); err != nil {
+			return err
+		}
+		for _, name := range []string{"KUBECONFIG", envWorkspace, envKubeconfig} {
+			fmt.Fprintln(stdout, unsetLine(shell, name))
+		}
+		return nil
+	}
+
+	compiler, err := newCompilerWithOptionalDecryptor(&cfg, cfg.IdentityFiles)
+	if err != nil {
+		return err
+	}
+
+	runtime, err := compiler.Compile(&cfg)
+	if err != nil {
+		return err
+	}
+	refreshPromptCache(runtime)
+
+	var (
+		workspace   string
+		kubeconfig  string
+		contextName string
+		kubeconfigs []*config.RuntimeKubeconfig
+	)
+
+	if ref != "" && runtime.WorkspaceExists(ref) {
+		workspace = ref
+		kubeconfigs = selectKubeconfigs(runtime.Workspace(ref).Kubeconfigs, opts.selector)
+		if len(kubeconfigs) == 0 {
+			return fmt.Errorf("no kubeconfigs found in workspace %s", ref)
+		}
+	} else {
+		resolved, err := resolveOrPick(runtime, ref)
+		if err != nil {
+			if errors.Is(err, errNoSelection) {
+				return nil
+			}
+			return err
+		}
+		rk := resolved.Kubeconfig
+		workspace = workspaceDisplayName(resolved.Workspace)
+		kubeconfig = rk.Name
+		kubeconfigs = []*config.RuntimeKubeconfig{rk}
+
+		if resolved.Context != nil {
+			contextName = resolved.Context.Name
+		}
+	}
+
+	var (
+		paths []string
+		tasks []renderTask
+	)
+	for _, rk := range kubeconfigs {
+		name := config.Ref{Workspace: runtime.Workspace(workspace), Kubeconfig: rk}.String()
+		if err := confirmProtected(rk, name, opts.yes); err != nil {
+			return err
+		}
+		if _, err := os.Stat(rk.Path); errors.Is(err, os.ErrNotExist) {
+			tasks = append(tasks, renderTask{displayName: name, rk: rk})
+		}
+		paths = append(paths, rk.Path)
+	}
+
+	// Stdout is meant for eval, progress goes to stderr.
+	if len(tasks) > 0 {
+		cmdutil.Fprintf(os.Stderr, "Rendering kubeconfig\n", nil)
+		if err := renderKubeconfigs(ctx, os.Stderr, tasks, opts.noLogin, opts.waitTimeout); err != nil {
+			return err
+		}
+	}
+
+	// Other terminals may use the rendered kubeconfig, so a context is
+	// selected by a kubeconfig listed before it that only this shell uses.
+	if contextName != "" {
+		session, err := writeEnvSession(contextName)
+		if err != nil {
+			return err
+		}
+		paths = append([]string{session}, paths...)
+	}
+
+	// The session of a previous kubecfg env in this shell is no longer used.
+	if err := removeEnvSessions(os.Getenv("KUBECONFIG")); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, exportLine(shell, "KUBECONFIG", strings.Join(paths, string(filepath.ListSeparator))))
+	fmt.Fprintln(stdout, exportLine(shell, envWorkspace, workspace))
+	if kubeconfig != "" {
+		fmt.Fprintln(stdout, exportLine(shell, envKubeconfig, kubeconfig))
+	} else {
+		fmt.Fprintln(stdout, unsetLine(shell, envKubeconfig))
+	}
+
+	return nil
+}
+
+// envSessionPrefix starts the name of the kubeconfigs written by
+// writeEnvSession.
+const envSessionPrefix = "kubecfg-env-"
+
+// writeEnvSession writes a kubeconfig to envSessionDir that only sets
+// contextName as the current context, and returns its path. Listed first in
+// KUBECONFIG, it selects the context in the kubeconfigs that follow.
+func writeEnvSession(contextName string) (string, error) {
+	dir, err := envSessionDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(dir, 0o700); err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp(dir, envSessionPrefix+"*.yaml")
+	if err != nil {
+		return "", err
+	}
+	session := f.Name()
+	f.Close()
+
+	kc := api.NewConfig()
+	kc.CurrentContext = contextName
+	if err := clientcmd.WriteToFile(*kc, session); err != nil {
+		os.Remove(session)
+		return "", err
+	}
+	return session, nil
+}
+
+// removeEnvSessions removes the kubeconfigs written by writeEnvSession among
+// the kubeconfigs listed in kubeconfigEnv, a KUBECONFIG value.
+func removeEnvSessions(kubeconfigEnv string) error {
+	dir, err := envSessionDir()
+	if err != nil {
+		return err
+	}
+	for _, path := range filepath.SplitList(kubeconfigEnv) {
+		if filepath.Dir(path) != filepath.Clean(dir) || !strings.HasPrefix(filepath.Base(path), envSessionPrefix) {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// envShell returns the shell to print exports for. Without name, the shell is
+// taken from $SHELL, falling back to sh.
+func envShell(name string) (string, error) {
+	if name == "" {
+		name = filepath.Base(os.Getenv("SHELL"))
+		for _, shell := range envShells {
+			if shell == name {
+				return shell, nil
+			}
+		}
+		return "sh", nil
+	}
+
+	for _, shell := range envShells {
+		if shell == name {
+			return shell, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported shell %q, use one of %s", name, strings.Join(envShells, ", "))
+}
+
+func exportLine(shell, name, value string) string {
+	switch shell {
+	case "fish":
+		return fmt.Sprintf("set -gx %s %s;", name, fishQuote(value))
+	case "sh":
+		return fmt.Sprintf("%s=%s; export %s", name, shQuote(value), name)
+	default:
+		return fmt.Sprintf("export %s=%s", name, shQuote(value))
+	}
+}
+
+func unsetLine(shell, name string) string {
+	if shell == "fish" {
+		return fmt.Sprintf("set -e %s;", name)
+	}
+	return "unset " + name
+}
+
+func shQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func newEnvTestOptions(shell string) envOptions {
+	return envOptions{shell: shell, noLogin: true, selector: labels.Everything(), waitTimeout: time.Second}
+}
+
+func TestRunEnvCmd(t *testing.T) {
+	targetPath := filepath.Join(t.TempDir(), "vgr's.yaml")
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+	cfg = newRenderCommandTestConfig(targetPath)
+
+	tests := []struct {
+		shell string
+		want  string
+	}{
+		{shell: "bash", want: `export KUBECONFIG='%s'
+export KUBECFG_WORKSPACE='work'
+export KUBECFG_KUBECONFIG='vgr'
+`},
+		{shell: "sh", want: `KUBECONFIG='%s'; export KUBECONFIG
+KUBECFG_WORKSPACE='work'; export KUBECFG_WORKSPACE
+KUBECFG_KUBECONFIG='vgr'; export KUBECFG_KUBECONFIG
+`},
+		{shell: "fish", want: `set -gx KUBECONFIG '%s';
+set -gx KUBECFG_WORKSPACE 'work';
+set -gx KUBECFG_KUBECONFIG 'vgr';
+`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			var stdout bytes.Buffer
+			err := runEnvCmd(context.Background(), "vgr", newEnvTestOptions(tt.shell), &stdout)
+			require.NoError(t, err)
+
+			quoted := shQuote(targetPath)
+			if tt.shell == "fish" {
+				quoted = fishQuote(targetPath)
+			}
+			require.Equal(t, fmt.Sprintf(tt.want, quoted[1:len(quoted)-1]), stdout.String())
+		})
+	}
+
+	// The kubeconfig is rendered since it didn't exist.
+	_, err := os.Stat(targetPath)
+	require.NoError(t, err)
+}
+
+func TestRunEnvCmdWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	targetPath := filepath.Join(dir, "vgr.yaml")
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+	cfg = newRenderCommandTestConfig(targetPath)
+	other := *cfg.Kubeconfigs["vgr"]
+	other.Path = filepath.Join(dir, "other.yaml")
+	cfg.Kubeconfigs["other"] = &other
+	cfg.Workspaces["work"].Kubeconfigs = []string{"vgr", "other"}
+
+	var stdout bytes.Buffer
+	err := runEnvCmd(context.Background(), "work", newEnvTestOptions("zsh"), &stdout)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf(`export KUBECONFIG='%s:%s'
+export KUBECFG_WORKSPACE='work'
+unset KUBECFG_KUBECONFIG
+`, other.Path, targetPath), stdout.String())
+}
+
+func TestRunEnvCmdSelectsContextInSessionKubeconfig(t *testing.T) {
+	t.Setenv("KUBECONFIG", "")
+	targetPath := filepath.Join(t.TempDir(), "vgr.yaml")
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+	cfg = newRenderCommandTestConfig(targetPath)
+	cfg.Kubeconfigs["vgr"].Contexts["other"] = &config.Context{Cluster: "cluster", AuthInfo: "user"}
+	require.NoError(t, runRenderCmd(context.Background(), "work", "vgr/context", true, false, time.Second))
+	rendered, err := os.ReadFile(targetPath)
+	require.NoError(t, err)
+
+	var stdout bytes.Buffer
+	require.NoError(t, runEnvCmd(context.Background(), "vgr/other", newEnvTestOptions("bash"), &stdout))
+	kubeconfigEnv := envExport(t, stdout.String(), "KUBECONFIG")
+	paths := filepath.SplitList(kubeconfigEnv)
+	require.Len(t, paths, 2)
+	session := paths[0]
+	require.Equal(t, targetPath, paths[1])
+
+	loaded, err := (&clientcmd.ClientConfigLoadingRules{Precedence: paths}).Load()
+	require.NoError(t, err)
+	require.Equal(t, "other", loaded.CurrentContext)
+	require.Contains(t, loaded.AuthInfos, "user")
+
+	// The session kubeconfig holds no credentials.
+	loaded, err = clientcmd.LoadFromFile(session)
+	require.NoError(t, err)
+	require.Equal(t, "other", loaded.CurrentContext)
+	require.Empty(t, loaded.AuthInfos)
+	require.Empty(t, loaded.Clusters)
+	info, err := os.Stat(filepath.Dir(session))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+
+	// Other terminals using the rendered kubeconfig are not affected.
+	again, err := os.ReadFile(targetPath)
+	require.NoError(t, err)
+	require.Equal(t, string(rendered), string(again))
+
+	// Switching again or unsetting removes the session kubeconfig.
+	t.Setenv("KUBECONFIG", kubeconfigEnv)
+	stdout.Reset()
+	require.NoError(t, runEnvCmd(context.Background(), "vgr/context", newEnvTestOptions("bash"), &stdout))
+	_, err = os.Stat(session)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	kubeconfigEnv = envExport(t, stdout.String(), "KUBECONFIG")
+	session = filepath.SplitList(kubeconfigEnv)[0]
+	t.Setenv("KUBECONFIG", kubeconfigEnv)
+	opts := newEnvTestOptions("bash")
+	opts.unset = true
+	require.NoError(t, runEnvCmd(context.Background(), "", opts, &stdout))
+	_, err = os.Stat(session)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// The rendered kubeconfig is kept.
+	_, err = os.Stat(targetPath)
+	require.NoError(t, err)
+}
+
+// envExport returns the value exported for name in bash syntax by kubecfg env.
+func envExport(t *testing.T, out, name string) string {
+	t.Helper()
+
+	for _, line := range strings.Split(out, "\n") {
+		if value, ok := strings.CutPrefix(line, "export "+name+"="); ok {
+			return strings.Trim(value, "'")
+		}
+	}
+	require.Failf(t, "missing export", "%s is not exported in %q", name, out)
+	return ""
+}
+
+func TestRunEnvCmdUnset(t *testing.T) {
+	var stdout bytes.Buffer
+	opts := newEnvTestOptions("fish")
+	opts.unset = true
+
+	require.NoError(t, runEnvCmd(context.Background(), "", opts, &stdout))
+	require.Equal(t, "set -e KUBECONFIG;\nset -e KUBECFG_WORKSPACE;\nset -e KUBECFG_KUBECONFIG;\n", stdout.String())
+}
+
+func TestEnvShell(t *testing.T) {
+	t.Setenv("SHELL", "/usr/bin/zsh")
+	shell, err := envShell("")
+	require.NoError(t, err)
+	require.Equal(t, "zsh", shell)
+
+	t.Setenv("SHELL", "/bin/tcsh")
+	shell, err = envShell("")
+	require.NoError(t, err)
+	require.Equal(t, "sh", shell)
+
+	_, err = envShell("tcsh")
+	require.EqualError(t, err, `unsupported shell "tcsh", use one of bash, zsh, fish, sh`)
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/amimof/kubecfg/pkg/cmdutil"
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/amimof/kubecfg/pkg/decrypt"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+var importStdout io.Writer = os.Stdout
+
+type importOptions struct {
+	workspace  string
+	encryptTo  []string
+	dryRun     bool
+	configFile string
+}
+
+// importedKubeconfig is a kubeconfig file converted into a config entry.
+type importedKubeconfig struct {
+	File       string
+	Name       string
+	Kubeconfig *config.Kubeconfig
+}
+
+func newImportCmd() *cobra.Command {
+	var opts importOptions
+
+	cmd := &cobra.Command{
+		Use:   "import FILE...",
+		Short: "Add existing kubeconfig files to kubecfg.yaml",
+		Long: `Convert existing kubeconfig files into kubeconfig entries in kubecfg.yaml, with
+the clusters, users and contexts they contain. Each entry is named after its
+file and renders to @/<name>.yaml. Names that are already taken get a numeric
+suffix.
+
+The entries are added to the end of kubecfg.yaml and comments in the file are
+kept. With --encrypt-to, tokens, passwords and client key data are encrypted to
+the given age recipients.`,
+		Example: `  kubecfg import ~/Downloads/prod.yaml --workspace work
+  kubecfg import ~/.kube/*.yaml --workspace work --encrypt-to age1...
+  kubecfg import prod.yaml --dry-run`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: withConfig(func(cmd *cobra.Command, args []string) error {
+			opts.configFile = configFile
+			return runImportCmd(args, opts, importStdout)
+		}),
+	}
+
+	cmd.Flags().StringVarP(&opts.workspace, "workspace", "w", "", "Workspace to add the kubeconfigs to, created if it doesn't exist")
+	cmd.Flags().StringSliceVar(&opts.encryptTo, "encrypt-to", nil, "Encrypt secret fields to these age public keys")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Print the YAML that would be added instead of writing it")
+	registerWorkspaceCompletion(cmd)
+
+	return cmd
+}
+
+func runImportCmd(files []string, opts importOptions, stdout io.Writer) error {
+	var encryptor config.SecretEncryptor
+	if len(opts.encryptTo) > 0 {
+		recipients := make([]age.Recipient, 0, len(opts.encryptTo))
+		for _, key := range opts.encryptTo {
+			recipient, err := age.ParseX25519Recipient(key)
+			if err != nil {
+				return fmt.Errorf("--encrypt-to %s: %w", key, err)
+			}
+			recipients = append(recipients, recipient)
+		}
+		enc, err := decrypt.NewAgeEncryptor(recipients...)
+		if err != nil {
+			return err
+		}
+		encryptor = enc
+	}
+
+	doc, err := readConfigDocument(opts.configFile)
+	if err != nil {
+		return err
+	}
+	taken := takenKubeconfigNames(doc)
+
+	imported := make([]importedKubeconfig, 0, len(files))
+	for _, file := range files {
+		kc, err := clientcmd.LoadFromFile(file)
+		if err != nil {
+			return fmt.Errorf("load %s: %w", file, err)
+		}
+		if len(kc.Contexts) == 0 {
+			return fmt.Errorf("%s has no contexts", file)
+		}
+
+		entry := config.FromAPIConfig(kc)
+		if encryptor != nil {
+			for name, authInfo := range entry.AuthInfos {
+				if err := authInfo.Encrypt(encryptor); err != nil {
+					return fmt.Errorf("%s: user %s: %w", file, name, err)
+				}
+			}
+		}
+
+		name := uniqueName(importName(file), taken)
+		taken[name] = true
+		entry.Path = "@/" + name + ".yaml"
+
+		imported = append(imported, importedKubeconfig{File: file, Name: name, Kubeconfig: entry})
+	}
+
+	if opts.dryRun {
+		added := config.Config{Kubeconfigs: make(map[string]*config.Kubeconfig, len(imported))}
+		for _, ik := range imported {
+			added.Kubeconfigs[ik.Name] = ik.Kubeconfig
+		}
+		if opts.workspace != "" {
+			added.Workspaces = map[string]*config.Workspace{
+				opts.workspace: {Kubeconfigs: importedNames(imported)},
+			}
+		}
+		out, err := added.Marshal()
+		if err != nil {
+			return err
+		}
+		_, err = stdout.Write(out)
+		return err
+	}
+
+	for _, ik := range imported {
+		node, err := config.Encode(ik.Kubeconfig)
+		if err != nil {
+			return err
+		}
+		if err := doc.SetKey("kubeconfigs", ik.Name, node); err != nil {
+			return err
+		}
+	}
+	if opts.workspace != "" {
+		if err := addToWorkspace(doc, opts.workspace, importedNames(imported)); err != nil {
+			return err
+		}
+	}
+
+	if err := writeConfigDocument(opts.configFile, doc); err != nil {
+		return err
+	}
+
+	for _, ik := range imported {
+		cmdutil.Fprintf(stdout, `{{ "✔" | FgGreen }} Imported {{ .File }} as {{ .Name | FgCyan }} {{ printf "(%d contexts)" .Contexts | FgHiBlack }}`, cmdutil.Data{
+			"File":     ik.File,
+			"Name":     ik.Name,
+			"Contexts": len(ik.Kubeconfig.Contexts),
+		})
+	}
+	if opts.workspace != "" {
+		cmdutil.Fprintf(stdout, `{{ "✔" | FgGreen }} Added to workspace {{ .Workspace | FgCyan }}, run kubecfg render {{ .Workspace }} to render them`, cmdutil.Data{"Workspace": opts.workspace})
+	}
+
+	return nil
+}
+
+// readConfigDocument reads file into a Document. A missing or empty file
+// results in a document with only the current version set.
+func readConfigDocument(file string) (*config.Document, error) {
+	data, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	doc, err := config.ParseDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", file, err)
+	}
+	if len(data) == 0 {
+		if err := doc.SetString("version", config.CurrentVersion); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// takenKubeconfigNames returns the names of the kubeconfigs in the config,
+// including its includes, and in doc.
+func takenKubeconfigNames(doc *config.Document) map[string]bool {
+	taken := make(map[string]bool)
+	for name := range cfg.Kubeconfigs {
+		taken[name] = true
+	}
+	if node := doc.Lookup("kubeconfigs"); node != nil && node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			taken[node.Content[i].Value] = true
+		}
+	}
+	return taken
+}
+
+// addToWorkspace appends names to the kubeconfigs of the workspace in doc,
+// creating the workspace if it doesn't exist.
+func addToWorkspace(doc *config.Document, workspace string, names []string) error {
+	ws := doc.Lookup("workspaces." + workspace)
+	if ws == nil {
+		node, err := config.Encode(&config.Workspace{Kubeconfigs: names})
+		if err != nil {
+			return err
+		}
+		return doc.SetKey("workspaces", workspace, node)
+	}
+
+	list := doc.Lookup("workspaces." + workspace + ".kubeconfigs")
+	if list == nil || list.Kind != yaml.SequenceNode {
+		node, err := config.Encode(names)
+		if err != nil {
+			return err
+		}
+		return doc.SetKey("workspaces."+workspace, "kubeconfigs", node)
+	}
+
+	for _, name := range names {
+		list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name})
+	}
+	return nil
+}
+
+// importName returns the kubeconfig name for file, its base name without
+// extension.
+func importName(file string) string {
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	if name == "" || name == "." {
+		return "imported"
+	}
+	return name
+}
+
+// uniqueName returns name, or name with the first numeric suffix that isn't
+// taken.
+func uniqueName(name string, taken map[string]bool) string {
+	if !taken[name] {
+		return name
+	}
+	for i := 2; ; i++ {
+		candidate := name + "-" + strconv.Itoa(i)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+func importedNames(imported []importedKubeconfig) []string {
+	names := make([]string, len(imported))
+	for i, ik := range imported {
+		names[i] = ik.Name
+	}
+	return names
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+const importTestKubeconfigYAML = `apiVersion: v1
+kind: Config
+current-context: admin
+clusters:
+  - name: main
+    cluster:
+      server: https://prod.example.com
+users:
+  - name: admin
+    user:
+      token: secret
+contexts:
+  - name: admin
+    context:
+      cluster: main
+      user: admin
+      namespace: apps
+`
+
+const importTestConfigYAML = `# clusters at work
+version: v1
+workspaces:
+  work:
+    kubeconfigs:
+      - prod # the old one
+kubeconfigs:
+  prod:
+    path: /tmp/prod.yaml
+`
+
+func setupImportTest(t *testing.T) (string, string) {
+	t.Helper()
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+	cfg = config.Config{Kubeconfigs: map[string]*config.Kubeconfig{"prod": {Path: "/tmp/prod.yaml"}}}
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "kubecfg.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(importTestConfigYAML), 0o600))
+	kubeconfigPath := filepath.Join(dir, "prod.yaml")
+	require.NoError(t, os.WriteFile(kubeconfigPath, []byte(importTestKubeconfigYAML), 0o600))
+	return configPath, kubeconfigPath
+}
+
+func TestRunImportCmd(t *testing.T) {
+	configPath, kubeconfigPath := setupImportTest(t)
+
+	var stdout bytes.Buffer
+	err := runImportCmd([]string{kubeconfigPath}, importOptions{workspace: "work", configFile: configPath}, &stdout)
+	require.NoError(t, err)
+	require.Contains(t, stdout.String(), "Imported "+kubeconfigPath+" as prod-2 (1 contexts)")
+
+	contents, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	require.Equal(t, `# clusters at work
+version: v1
+workspaces:
+  work:
+    kubeconfigs:
+      - prod # the old one
+      - prod-2
+kubeconfigs:
+  prod:
+    path: /tmp/prod.yaml
+  prod-2:
+    path: '@/prod-2.yaml'
+    current_context: admin
+    clusters:
+      main:
+        server: https://prod.example.com
+    auth_infos:
+      admin:
+        token: secret
+    contexts:
+      admin:
+        cluster: main
+        authInfo: admin
+        namespace: apps
+`, string(contents))
+}
+
+func TestRunImportCmdEncryptsSecrets(t *testing.T) {
+	configPath, kubeconfigPath := setupImportTest(t)
+
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	var stdout bytes.Buffer
+	err = runImportCmd([]string{kubeconfigPath}, importOptions{
+		workspace:  "new",
+		encryptTo:  []string{identity.Recipient().String()},
+		configFile: configPath,
+	}, &stdout)
+	require.NoError(t, err)
+
+	contents, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	require.NotContains(t, string(contents), "secret")
+	require.Contains(t, string(contents), "encryptedToken: |")
+	require.Contains(t, string(contents), "  new:\n    kubeconfigs:\n      - prod-2\n")
+}
+
+func TestRunImportCmdDryRunDoesNotWrite(t *testing.T) {
+	configPath, kubeconfigPath := setupImportTest(t)
+
+	var stdout bytes.Buffer
+	err := runImportCmd([]string{kubeconfigPath, kubeconfigPath}, importOptions{dryRun: true, configFile: configPath}, &stdout)
+	require.NoError(t, err)
+	require.Contains(t, stdout.String(), "  prod-2:\n    path: '@/prod-2.yaml'\n")
+	require.Contains(t, stdout.String(), "  prod-3:\n")
+
+	contents, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	require.Equal(t, importTestConfigYAML, string(contents))
+}
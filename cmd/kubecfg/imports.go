@@ -9,6 +9,13 @@ import (
 )
 
 func applyImportedContexts(rk *config.RuntimeKubeconfig) error {
+	return applyImportedContextsFrom(rk, nil)
+}
+
+// applyImportedContextsFrom is like applyImportedContexts, except that
+// contexts imported from login sources that haven't run are taken from
+// rendered, the kubeconfig as it is currently written, if it has them.
+func applyImportedContextsFrom(rk *config.RuntimeKubeconfig, rendered *api.Config) error {
 	if rk == nil {
 		return fmt.Errorf("runtime kubeconfig is nil")
 	}
@@ -28,7 +35,11 @@ func applyImportedContexts(rk *config.RuntimeKubeconfig) error {
 			)
 		}
 
-		if source.ImportedConfig == nil {
+		imported := source.ImportedConfig
+		if imported == nil {
+			imported = renderedImport(rendered, ctx)
+		}
+		if imported == nil {
 			return fmt.Errorf(
 				"kubeconfig %q context %q import login source %q has no imported config; run login first",
 				rk.Name,
@@ -37,7 +48,7 @@ func applyImportedContexts(rk *config.RuntimeKubeconfig) error {
 			)
 		}
 
-		if err := applyImportedContext(rk, ctx, source.ImportedConfig); err != nil {
+		if err := applyImportedContext(rk, ctx, imported); err != nil {
 			return err
 		}
 	}
@@ -126,6 +137,33 @@ func applyImportedContext(rk *config.RuntimeKubeconfig, ctx *config.RuntimeConte
 	return nil
 }
 
+// renderedImport returns the context ctx as it is in rendered, along with
+// its cluster and user, as if it was imported from a login source. Returns nil
+// if rendered is nil or doesn't have the context.
+func renderedImport(rendered *api.Config, ctx *config.RuntimeContext) *api.Config {
+	if rendered == nil {
+		return nil
+	}
+	current, ok := rendered.Contexts[ctx.Name]
+	if !ok {
+		return nil
+	}
+	cluster, ok := rendered.Clusters[current.Cluster]
+	if !ok {
+		return nil
+	}
+	authInfo, ok := rendered.AuthInfos[current.AuthInfo]
+	if !ok {
+		return nil
+	}
+
+	imported := api.NewConfig()
+	imported.Contexts[ctx.Import.ContextName] = &api.Context{Cluster: current.Cluster, AuthInfo: current.AuthInfo}
+	imported.Clusters[current.Cluster] = cluster
+	imported.AuthInfos[current.AuthInfo] = authInfo
+	return imported
+}
+
 func firstNonEmptyString(values ...string) string {
 	for _, value := range values {
 		if strings.TrimSpace(value) != "" {
@@ -10,31 +10,47 @@ import (
 	"github.com/amimof/kubecfg/pkg/cmdutil/table"
 	"github.com/amimof/kubecfg/pkg/config"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 var kubeconfigsStdout io.Writer = os.Stdout
 
 func newKubeconfigsCmd() *cobra.Command {
-	var workspaceName string
+	var workspaceName, selector string
 
 	cmd := &cobra.Command{
-		Use:          "kubeconfigs",
-		Short:        "List kubeconfigs",
-		Long:         `List kubeconfigs from one workspace or all workspaces.`,
+		Use:   "kubeconfigs",
+		Short: "List kubeconfigs",
+		Long:  `List kubeconfigs from one workspace or all workspaces.`,
+		Example: `  kubecfg kubeconfigs --workspace homelab
+  kubecfg kubeconfigs -l env=prod,team=payments`,
 		Args:         cobra.ExactArgs(0),
 		SilenceUsage: true,
 		RunE: withConfig(func(cmd *cobra.Command, args []string) error {
-			return runKubeconfigsCmd(workspaceName, kubeconfigsStdout)
+			sel, err := parseSelector(selector)
+			if err != nil {
+				return err
+			}
+			return runKubeconfigsCmd(workspaceName, sel, kubeconfigsStdout)
 		}),
 	}
 
 	cmd.PersistentFlags().StringVarP(&workspaceName, "workspace", "w", "", "Workspace")
+	registerWorkspaceCompletion(cmd)
+	addSelectorFlag(cmd, &selector)
 
 	return cmd
 }
 
-func runKubeconfigsCmd(workspaceName string, stdout io.Writer) error {
-	entries, err := collectKubeconfigRows(workspaceName)
+func runKubeconfigsCmd(workspaceName string, selector labels.Selector, stdout io.Writer) error {
+	entries, err := collectKubeconfigRows(workspaceName, selector)
+	if err != nil {
+		return err
+	}
+
+	// Contexts are counted after compiling, which includes the contexts of
+	// kubeconfigs they extend.
+	runtime, err := config.NewCompiler(config.WithoutDecryption()).Compile(&cfg)
 	if err != nil {
 		return err
 	}
@@ -50,8 +66,8 @@ func runKubeconfigsCmd(workspaceName string, stdout io.Writer) error {
 	for _, entry := range entries {
 		aliases := strings.Join(entry.kubeconfig.Aliases, ", ")
 		contexts := 0
-		if entry.kubeconfig.Contexts != nil {
-			contexts = len(entry.kubeconfig.Contexts)
+		if rk, ok := runtime.Kubeconfigs[entry.name]; ok {
+			contexts = len(rk.Contexts)
 		}
 
 		if err := tbl.AddRow(
@@ -78,7 +94,7 @@ type kubeconfigTableRow struct {
 	kubeconfig *config.Kubeconfig
 }
 
-func collectKubeconfigRows(workspaceName string) ([]kubeconfigTableRow, error) {
+func collectKubeconfigRows(workspaceName string, selector labels.Selector) ([]kubeconfigTableRow, error) {
 	workspaceNames := make([]string, 0, len(cfg.Workspaces))
 	if workspaceName != "" {
 		workspace := cfg.Workspace(workspaceName)
@@ -95,12 +111,18 @@ func collectKubeconfigRows(workspaceName string) ([]kubeconfigTableRow, error) {
 
 	rowsByName := make(map[string]*kubeconfigTableRow)
 	for _, workspaceName := range workspaceNames {
-		workspace := cfg.Workspace(workspaceName)
-		for _, kubeconfigName := range workspace.Kubeconfigs {
+		kubeconfigNames, err := cfg.WorkspaceKubeconfigs(workspaceName)
+		if err != nil {
+			return nil, err
+		}
+		for _, kubeconfigName := range kubeconfigNames {
 			kubeconfig := cfg.Kubeconfig(kubeconfigName)
 			if kubeconfig == nil {
 				return nil, fmt.Errorf("workspaces.%s.kubeconfigs references missing kubeconfig %q", workspaceName, kubeconfigName)
 			}
+			if !selector.Matches(labels.Set(kubeconfig.Labels)) {
+				continue
+			}
 
 			row, ok := rowsByName[kubeconfigName]
 			if !ok {
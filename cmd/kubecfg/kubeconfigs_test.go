@@ -7,6 +7,7 @@ import (
 
 	"github.com/amimof/kubecfg/pkg/config"
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 func TestRunKubeconfigsCmdUsesExplicitWorkspace(t *testing.T) {
@@ -18,7 +19,7 @@ func TestRunKubeconfigsCmdUsesExplicitWorkspace(t *testing.T) {
 	cfg = newKubeconfigsCommandTestConfig()
 
 	var stdout bytes.Buffer
-	err := runKubeconfigsCmd("secondary", &stdout)
+	err := runKubeconfigsCmd("secondary", labels.Everything(), &stdout)
 	require.NoError(t, err)
 	require.Equal(t, []string{
 		"NAME   WORKSPACES  PATH         ALIASES  CONTEXTS",
@@ -36,7 +37,7 @@ func TestRunKubeconfigsCmdListsAllWorkspacesWhenFilterIsOmitted(t *testing.T) {
 	cfg = newKubeconfigsCommandTestConfig()
 
 	var stdout bytes.Buffer
-	err := runKubeconfigsCmd("", &stdout)
+	err := runKubeconfigsCmd("", labels.Everything(), &stdout)
 	require.NoError(t, err)
 	require.Equal(t, []string{
 		"NAME   WORKSPACES          PATH         ALIASES  CONTEXTS",
@@ -55,7 +56,7 @@ func TestRunKubeconfigsCmdReturnsErrorForUnknownWorkspace(t *testing.T) {
 	cfg = newKubeconfigsCommandTestConfig()
 
 	var stdout bytes.Buffer
-	err := runKubeconfigsCmd("missing", &stdout)
+	err := runKubeconfigsCmd("missing", labels.Everything(), &stdout)
 	require.EqualError(t, err, "workspace does not exist: missing")
 }
 
@@ -69,7 +70,7 @@ func TestRunKubeconfigsCmdReturnsErrorForMissingReferencedKubeconfig(t *testing.
 	cfg.Workspaces["default"].Kubeconfigs = append(cfg.Workspaces["default"].Kubeconfigs, "missing")
 
 	var stdout bytes.Buffer
-	err := runKubeconfigsCmd("default", &stdout)
+	err := runKubeconfigsCmd("default", labels.Everything(), &stdout)
 	require.EqualError(t, err, "workspaces.default.kubeconfigs references missing kubeconfig \"missing\"")
 }
 
@@ -89,15 +90,27 @@ func newKubeconfigsCommandTestConfig() config.Config {
 			"alpha": {
 				Path:    "/tmp/a.yaml",
 				Aliases: []string{"a1", "a2"},
+				Clusters: map[string]*config.Cluster{
+					"cluster": {Server: "https://a.example.com"},
+				},
+				AuthInfos: map[string]*config.AuthInfo{
+					"user": {Token: "token"},
+				},
 				Contexts: map[string]*config.Context{
-					"admin": {},
+					"admin": {Cluster: "cluster", AuthInfo: "user"},
 				},
 			},
 			"beta": {
 				Path: "/tmp/b.yaml",
+				Clusters: map[string]*config.Cluster{
+					"cluster": {Server: "https://b.example.com"},
+				},
+				AuthInfos: map[string]*config.AuthInfo{
+					"user": {Token: "token"},
+				},
 				Contexts: map[string]*config.Context{
-					"admin": {},
-					"ops":   {},
+					"admin": {Cluster: "cluster", AuthInfo: "user"},
+					"ops":   {Cluster: "cluster", AuthInfo: "user"},
 				},
 			},
 			"gamma": {
@@ -107,3 +120,46 @@ func newKubeconfigsCommandTestConfig() config.Config {
 		},
 	}
 }
+
+func TestRunKubeconfigsCmdFiltersBySelector(t *testing.T) {
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	cfg = newKubeconfigsCommandTestConfig()
+	cfg.Kubeconfigs["alpha"].Labels = map[string]string{"env": "prod", "team": "payments"}
+	cfg.Kubeconfigs["beta"].Labels = map[string]string{"env": "prod"}
+	cfg.Workspaces["prod"] = &config.Workspace{
+		Selector: &config.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+	}
+
+	selector, err := parseSelector("env=prod,team=payments")
+	require.NoError(t, err)
+
+	var stdout bytes.Buffer
+	err = runKubeconfigsCmd("", selector, &stdout)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"NAME   WORKSPACES       PATH         ALIASES  CONTEXTS",
+		"alpha  prod, secondary  /tmp/a.yaml  a1, a2   1",
+	}, strings.Split(strings.TrimSpace(stdout.String()), "\n"))
+}
+
+func TestRunKubeconfigsCmdCountsExtendedContexts(t *testing.T) {
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	cfg = newKubeconfigsCommandTestConfig()
+	cfg.Kubeconfigs["gamma"].Extends = "beta"
+
+	var stdout bytes.Buffer
+	require.NoError(t, runKubeconfigsCmd("default", labels.Everything(), &stdout))
+	require.Equal(t, []string{
+		"NAME   WORKSPACES  PATH         ALIASES  CONTEXTS",
+		"beta   default     /tmp/b.yaml           2",
+		"gamma  default     /tmp/c.yaml  g        2",
+	}, strings.Split(strings.TrimSpace(stdout.String()), "\n"))
+}
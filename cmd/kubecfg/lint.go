@@ -0,0 +1,260 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/amimof/kubecfg/pkg/cmdutil"
+	"github.com/amimof/kubecfg/pkg/cmdutil/table"
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/amimof/kubecfg/pkg/lint"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// validateRule is the rule reported with findings that fail validation. It
+// can't be disabled.
+const validateRule = "validate"
+
+var (
+	errLintFailed = errors.New("lint failed")
+
+	lintStdout io.Writer = os.Stdout
+)
+
+type lintOptions struct {
+	output    string
+	fix       bool
+	strict    bool
+	listRules bool
+}
+
+func newLintCmd() *cobra.Command {
+	var opts lintOptions
+
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Check kubecfg.yaml for likely mistakes",
+		Long: `Run lint rules over kubecfg.yaml and report findings such as plaintext secrets,
+unused kubeconfigs and duplicate output paths. Rules can be disabled with lint.disable in kubecfg.yaml.`,
+		Example: `  kubecfg lint
+  kubecfg lint -o json
+  kubecfg lint --fix
+  kubecfg lint --list-rules`,
+		Args:         cobra.ExactArgs(0),
+		SilenceUsage: true,
+		// Problems that fail validation are reported as findings, so the config
+		// is loaded without validating it instead of through withConfig.
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := loadConfig(false); err != nil {
+				return err
+			}
+			return runLintCmd(lintStdout, viper.ConfigFileUsed(), opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "text", "Output format (text, json)")
+	cmd.Flags().BoolVar(&opts.fix, "fix", false, "Rewrite kubecfg.yaml to fix findings that can be fixed mechanically")
+	cmd.Flags().BoolVar(&opts.strict, "strict", false, "Fail on warnings as well as errors")
+	cmd.Flags().BoolVar(&opts.listRules, "list-rules", false, "List available rules and exit")
+
+	return cmd
+}
+
+func runLintCmd(stdout io.Writer, file string, opts lintOptions) error {
+	if opts.output != "text" && opts.output != "json" {
+		return fmt.Errorf("expected output to be one of [text json]")
+	}
+
+	linter := lint.NewLinter(lint.WithDisabled(fileCfg.Lint.Disable...))
+
+	if opts.listRules {
+		return printLintRules(stdout, linter)
+	}
+
+	for _, id := range linter.Unknown() {
+		logrus.Warnf("lint.disable references unknown rule %q", id)
+	}
+
+	// The config is linted as written, before overlays and interpolation, so
+	// that findings don't depend on the host and every finding is in a file.
+	// Linting must never prompt for a passphrase, so encrypted fields are left
+	// as is. A config that can't be compiled is still linted.
+	runtime, err := config.NewCompiler(config.WithoutDecryption()).Compile(&fileCfg)
+	if err != nil {
+		logrus.Debugf("lint without runtime config: %v", err)
+		runtime = nil
+	}
+
+	findings := linter.Lint(&lint.Input{Config: &fileCfg, Runtime: runtime})
+	_, diags := fileCfg.Expand()
+	for _, diag := range append(fileCfg.Diagnose(), diags...) {
+		findings = append(findings, lint.Finding{Diagnostic: diag, Rule: validateRule})
+	}
+	slices.SortStableFunc(findings, func(a, b lint.Finding) int {
+		return cmp.Or(strings.Compare(a.Path, b.Path), strings.Compare(a.Rule, b.Rule))
+	})
+
+	// Findings are located, and fixed, in the file they were loaded from since
+	// the config may be split across files with include.
+	var files []string
+	for i := range findings {
+		if findings[i].File == "" {
+			findings[i].File = cmp.Or(fileCfg.SourceOf(findings[i].Path), file)
+		}
+		if !slices.Contains(files, findings[i].File) {
+			files = append(files, findings[i].File)
+		}
+	}
+
+	for _, f := range files {
+		if err := lintFile(linter, f, findings, opts.fix); err != nil {
+			return err
+		}
+	}
+
+	if err := printLintFindings(stdout, opts.output, findings); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, f := range findings {
+		if f.Fixed {
+			continue
+		}
+		if f.Severity == config.SeverityError || opts.strict {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d problem(s) found", errLintFailed, failed)
+	}
+
+	return nil
+}
+
+// lintFile locates the findings that belong to file and, if fix is set, fixes
+// them and writes the file back.
+func lintFile(linter *lint.Linter, file string, findings []lint.Finding, fix bool) error {
+	data, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	doc, err := config.ParseDocument(data)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", file, err)
+	}
+
+	var idx []int
+	for i := range findings {
+		if findings[i].File != file {
+			continue
+		}
+		idx = append(idx, i)
+		if line, column, ok := doc.Position(findings[i].Path); ok {
+			findings[i].Line = line
+			findings[i].Column = column
+		}
+	}
+
+	if !fix {
+		return nil
+	}
+
+	subset := make([]lint.Finding, len(idx))
+	for j, i := range idx {
+		subset[j] = findings[i]
+	}
+
+	fixed, err := linter.Fix(doc, subset)
+	if err != nil {
+		return err
+	}
+	if fixed == 0 {
+		return nil
+	}
+
+	for j, i := range idx {
+		findings[i].Fixed = subset[j].Fixed
+	}
+
+	return writeConfigDocument(file, doc)
+}
+
+func printLintFindings(w io.Writer, output string, findings []lint.Finding) error {
+	if output == "json" {
+		if findings == nil {
+			findings = []lint.Finding{}
+		}
+		b, err := json.MarshalIndent(findings, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	}
+
+	for _, f := range findings {
+		location := f.File
+		if f.Line > 0 {
+			location = fmt.Sprintf("%s:%d:%d", f.File, f.Line, f.Column)
+		}
+		cmdutil.Fprintf(w, `{{ .Location | FgHiBlack }} {{ if eq .Severity "error" }}{{ "error" | FgRed }}{{ else }}{{ "warning" | FgYellow }}{{ end }}: {{ .Message }} {{ printf "[%s]" .Rule | FgHiBlack }}{{ if .Fixed }} {{ "(fixed)" | FgGreen }}{{ end }}`, cmdutil.Data{
+			"Location": location + ":",
+			"Severity": string(f.Severity),
+			"Message":  f.Error(),
+			"Rule":     f.Rule,
+			"Fixed":    f.Fixed,
+		})
+	}
+
+	return nil
+}
+
+func printLintRules(w io.Writer, linter *lint.Linter) error {
+	tbl := table.NewTable([]table.Column{
+		{Header: "RULE"},
+		{Header: "ENABLED"},
+		{Header: "FIXABLE"},
+		{Header: "DESCRIPTION"},
+	})
+
+	for _, rule := range linter.Rules() {
+		_, fixable := rule.(lint.Fixer)
+		if err := tbl.AddRow(rule.ID(), fmt.Sprintf("%t", linter.Enabled(rule.ID())), fmt.Sprintf("%t", fixable), rule.Description()); err != nil {
+			return err
+		}
+	}
+
+	_, err := tbl.WriteTo(w)
+	return err
+}
+
+// writeConfigDocument writes doc back to file atomically, see writeFileAtomic,
+// keeping the file mode of the existing file.
+func writeConfigDocument(file string, doc *config.Document) error {
+	data, err := doc.Bytes()
+	if err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0o600)
+	if info, err := os.Stat(file); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	if err := writeFileAtomic(file, data, 0, nil); err != nil {
+		return err
+	}
+	// writeFileAtomic creates the file with 0600, keep the mode of the original.
+	return os.Chmod(file, mode)
+}
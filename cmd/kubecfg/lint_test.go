@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/amimof/kubecfg/pkg/lint"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+const lintTestConfigYAML = `version: v1
+workspaces:
+  work:
+    kubeconfigs:
+      - vgr
+kubeconfigs:
+  vgr:
+    path: /tmp/vgr.yaml
+    login_sources:
+      # no longer used
+      stale:
+        command: login
+    clusters:
+      cluster:
+        server: https://example.com
+    auth_infos:
+      user:
+        token: plain
+    contexts:
+      admin:
+        cluster: cluster
+        authinfo: user
+`
+
+func TestRunLintCmdReportsFindingsAsText(t *testing.T) {
+	configPath := loadLintTestConfig(t, lintTestConfigYAML)
+
+	var stdout bytes.Buffer
+	err := runLintCmd(&stdout, configPath, lintOptions{output: "text"})
+	require.ErrorIs(t, err, errLintFailed)
+	require.Equal(t, []string{
+		configPath + ":18:9: error: kubeconfigs.vgr.auth_infos.user.token stores a plaintext secret; use encryptedToken instead [plaintext-secret]",
+		configPath + ":11:7: warning: kubeconfigs.vgr.login_sources.stale is not imported by any context [unused-login-source]",
+	}, strings.Split(strings.TrimSpace(stdout.String()), "\n"))
+}
+
+func TestRunLintCmdReportsFindingsAsJSON(t *testing.T) {
+	configPath := loadLintTestConfig(t, lintTestConfigYAML)
+	fileCfg.Lint.Disable = []string{"plaintext-secret"}
+
+	var stdout bytes.Buffer
+	err := runLintCmd(&stdout, configPath, lintOptions{output: "json"})
+	require.NoError(t, err)
+
+	var findings []lint.Finding
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &findings))
+	require.Len(t, findings, 1)
+	require.Equal(t, "unused-login-source", findings[0].Rule)
+	require.Equal(t, "kubeconfigs.vgr.login_sources.stale", findings[0].Path)
+	require.Equal(t, config.SeverityWarning, findings[0].Severity)
+	require.Equal(t, 11, findings[0].Line)
+	require.True(t, findings[0].Fixable)
+}
+
+func TestRunLintCmdStrictFailsOnWarnings(t *testing.T) {
+	configPath := loadLintTestConfig(t, lintTestConfigYAML)
+	fileCfg.Lint.Disable = []string{"plaintext-secret"}
+
+	var stdout bytes.Buffer
+	err := runLintCmd(&stdout, configPath, lintOptions{output: "text", strict: true})
+	require.ErrorIs(t, err, errLintFailed)
+}
+
+func TestRunLintCmdFixRewritesConfig(t *testing.T) {
+	configPath := loadLintTestConfig(t, lintTestConfigYAML)
+	fileCfg.Lint.Disable = []string{"plaintext-secret"}
+
+	var stdout bytes.Buffer
+	err := runLintCmd(&stdout, configPath, lintOptions{output: "text", fix: true, strict: true})
+	require.NoError(t, err)
+	require.Contains(t, stdout.String(), "[unused-login-source] (fixed)")
+
+	contents, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	require.NotContains(t, string(contents), "stale")
+	require.Contains(t, string(contents), "token: plain")
+	require.Equal(t, os.FileMode(0o640), filePerms(t, configPath))
+}
+
+func TestRunLintCmdListsRules(t *testing.T) {
+	originalFileCfg := fileCfg
+	t.Cleanup(func() {
+		fileCfg = originalFileCfg
+	})
+	fileCfg = config.Config{Lint: config.LintConfig{Disable: []string{"duplicate-path"}}}
+
+	var stdout bytes.Buffer
+	err := runLintCmd(&stdout, "", lintOptions{output: "text", listRules: true})
+	require.NoError(t, err)
+	require.Contains(t, stdout.String(), "RULE")
+	require.Regexp(t, `duplicate-path\s+false\s+false`, stdout.String())
+	require.Regexp(t, `unused-login-source\s+true\s+true`, stdout.String())
+}
+
+// loadLintTestConfig writes contents to a temporary kubecfg.yaml and loads it
+// into fileCfg the same way the CLI does.
+func loadLintTestConfig(t *testing.T, contents string) string {
+	t.Helper()
+
+	originalFileCfg := fileCfg
+	t.Cleanup(func() {
+		fileCfg = originalFileCfg
+	})
+
+	configPath := filepath.Join(t.TempDir(), "kubecfg.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(contents), 0o640))
+
+	v := viper.New()
+	v.SetConfigFile(configPath)
+	require.NoError(t, v.ReadInConfig())
+
+	fileCfg = config.Config{}
+	require.NoError(t, v.Unmarshal(&fileCfg))
+
+	return configPath
+}
+
+func TestRunLintCmdReportsValidationProblems(t *testing.T) {
+	configPath := loadLintTestConfig(t, `version: v1
+workspaces:
+  work:
+    kubeconfigs:
+      - vgr
+kubeconfigs:
+  vgr:
+    path: ${var:missing}/vgr.yaml
+    clusters:
+      cluster:
+        server: https://example.com
+    auth_infos:
+      user:
+        tokenFile: /tmp/token
+    contexts:
+      admin:
+        cluster: nowhere
+        authinfo: user
+`)
+
+	var stdout bytes.Buffer
+	err := runLintCmd(&stdout, configPath, lintOptions{output: "text"})
+	require.ErrorIs(t, err, errLintFailed)
+	require.Equal(t, []string{
+		configPath + ":17:9: error: kubeconfigs.vgr.contexts.admin.cluster references missing cluster \"nowhere\" [validate]",
+		configPath + ":8:5: error: kubeconfigs.vgr.path references undefined var \"missing\" [validate]",
+	}, strings.Split(strings.TrimSpace(stdout.String()), "\n"))
+}
+
+func TestWriteConfigDocumentReplacesFileAtomically(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles", "kubecfg.yaml")
+	require.NoError(t, os.Mkdir(filepath.Dir(target), 0o700))
+	require.NoError(t, os.WriteFile(target, []byte("version: v1\n"), 0o640))
+	link := filepath.Join(dir, "kubecfg.yaml")
+	require.NoError(t, os.Symlink(target, link))
+
+	doc, err := config.ParseDocument([]byte("# edited\nversion: v1\n"))
+	require.NoError(t, err)
+	require.NoError(t, writeConfigDocument(link, doc))
+
+	// The symlink is kept and the file it points to is replaced.
+	linkedTo, err := os.Readlink(link)
+	require.NoError(t, err)
+	require.Equal(t, target, linkedTo)
+	contents, err := os.ReadFile(target)
+	require.NoError(t, err)
+	require.Equal(t, "# edited\nversion: v1\n", string(contents))
+	require.Equal(t, os.FileMode(0o640), filePerms(t, target))
+
+	entries, err := os.ReadDir(filepath.Dir(target))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestRunLintCmdLintsConfigAsWritten(t *testing.T) {
+	configPath := loadLintTestConfig(t, lintTestConfigYAML)
+	fileCfg.Lint.Disable = []string{"plaintext-secret"}
+
+	// cfg has overlays applied and values interpolated, which lint ignores.
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+	cfg = fileCfg
+	cfg.Kubeconfigs = map[string]*config.Kubeconfig{
+		"injected": {Path: "/tmp/injected.yaml", Clusters: map[string]*config.Cluster{
+			"cluster": {Server: "https://example.com", InsecureSkipTLSVerify: true},
+		}},
+	}
+
+	var stdout bytes.Buffer
+	require.NoError(t, runLintCmd(&stdout, configPath, lintOptions{output: "text"}))
+	require.Equal(t, configPath+":11:7: warning: kubeconfigs.vgr.login_sources.stale is not imported by any context [unused-login-source]", strings.TrimSpace(stdout.String()))
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/amimof/kubecfg/pkg/cmdutil"
+	"github.com/amimof/kubecfg/pkg/filelock"
+)
+
+// lockTimeout is how long to wait for other kubecfg processes to release the
+// files they are writing, set by --lock-timeout.
+var lockTimeout = time.Minute
+
+// lockPath locks the kubeconfig at path against other kubecfg processes
+// rendering or logging in to it. The lock is a hidden file next to it.
+func lockPath(ctx context.Context, path string, waiting func(filelock.Holder)) (*filelock.Lock, error) {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	lockFile := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".lock")
+	return filelock.Acquire(ctx, lockFile, lockTimeout, waiting)
+}
+
+// lockBaseDir locks baseDir against other kubecfg processes changing its
+// config symlink.
+func lockBaseDir(ctx context.Context, baseDir string, waiting func(filelock.Holder)) (*filelock.Lock, error) {
+	return filelock.Acquire(ctx, filepath.Join(baseDir, ".kubecfg.lock"), lockTimeout, waiting)
+}
+
+// waitingMessage returns the message shown while waiting for a lock held by h.
+func waitingMessage(h filelock.Holder) string {
+	return "waiting for lock held by " + h.String()
+}
+
+// printWaiting prints the waiting message for h to stderr, for commands that
+// don't show a dashboard.
+func printWaiting(h filelock.Holder) {
+	cmdutil.Fprintf(os.Stderr, `{{ .Message | FgYellow }}`, cmdutil.Data{"Message": waitingMessage(h)})
+}
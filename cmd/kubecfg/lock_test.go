@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/amimof/kubecfg/pkg/filelock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderSingleKubeconfigWaitsForLock(t *testing.T) {
+	targetPath := filepath.Join(t.TempDir(), "vgr.yaml")
+
+	originalCfg := cfg
+	originalLockTimeout := lockTimeout
+	t.Cleanup(func() {
+		cfg = originalCfg
+		lockTimeout = originalLockTimeout
+	})
+
+	cfg = newRenderCommandTestConfig(targetPath)
+	rc, err := config.NewCompiler().Compile(&cfg)
+	require.NoError(t, err)
+	task := renderTask{displayName: "work/vgr", rk: rc.Kubeconfigs["vgr"]}
+
+	// Another kubecfg holds the lock, which also conflicts within a process.
+	held, err := lockPath(context.Background(), targetPath, nil)
+	require.NoError(t, err)
+
+	lockTimeout = 50 * time.Millisecond
+	err = renderSingleKubeconfig(context.Background(), task, func(string) {}, newLoginGroup(), true, time.Second)
+	require.ErrorIs(t, err, filelock.ErrTimeout)
+	require.ErrorContains(t, err, "is held by pid "+strconv.Itoa(os.Getpid()))
+
+	lockTimeout = 5 * time.Second
+	released := make(chan error)
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		released <- held.Release()
+	}()
+
+	var statuses []string
+	err = renderSingleKubeconfig(context.Background(), task, func(msg string) {
+		statuses = append(statuses, msg)
+	}, newLoginGroup(), true, time.Second)
+	require.NoError(t, err)
+	require.NoError(t, <-released)
+
+	require.Len(t, statuses, 2)
+	require.Regexp(t, `^waiting for lock held by pid \d+ \(.+\)$`, statuses[0])
+	require.Empty(t, statuses[1])
+	_, err = os.Stat(targetPath)
+	require.NoError(t, err)
+}
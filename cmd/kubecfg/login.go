@@ -3,33 +3,50 @@ package main
 import (
 	"bytes"
 	"context"
-	"fmt"
+	"strings"
 	"time"
 
+	"github.com/amimof/kubecfg/pkg/config"
 	"github.com/spf13/cobra"
 )
 
 func newLoginCmd() *cobra.Command {
-	var workspaceName string
+	var (
+		workspaceName string
+		yes           bool
+	)
 	cmd := &cobra.Command{
-		Use:   "login [KUBECONFIG] [CONTEXT]",
+		Use:   "login REF [CONTEXT]",
 		Short: "Refresh credentials for a context",
-		Long:  `Run the login flow for a kubeconfig context and write the updated credentials.`,
+		Long: `Run the login flow for a kubeconfig context and write the updated credentials.
+
+REF is a kubeconfig name or alias, optionally qualified as ws/kc, kc/ctx or
+ws/kc/ctx. A REF that doesn't match exactly is used if it is part of the name
+of only one kubeconfig or context.`,
 		Example: `  kubecfg login mainframe admin
-  kubecfg login mainframe admin --workspace homelab`,
-		Args:         cobra.ExactArgs(2),
-		SilenceUsage: true,
+  kubecfg login mainframe admin --workspace homelab
+  kubecfg login homelab/mainframe/admin
+  kubecfg login prod-eu`,
+		Args:              cobra.RangeArgs(1, 2),
+		ValidArgsFunction: completeWith(2, completeLogin),
+		SilenceUsage:      true,
 		RunE: withConfig(func(cmd *cobra.Command, args []string) error {
-			return runLoginCmd(workspaceName, args[0], args[1])
+			var contextName string
+			if len(args) == 2 {
+				contextName = args[1]
+			}
+			return runLoginCmd(workspaceName, args[0], contextName, yes)
 		}),
 	}
 
 	cmd.PersistentFlags().StringVarP(&workspaceName, "workspace", "w", "", "Workspace")
+	registerWorkspaceCompletion(cmd)
+	addYesFlag(cmd, &yes)
 
 	return cmd
 }
 
-func runLoginCmd(workspaceName, kubeconfigName, contextName string) error {
+func runLoginCmd(workspaceName, kubeconfigName, contextName string, yes bool) error {
 	compiler, err := newCompilerWithOptionalDecryptor(&cfg, cfg.IdentityFiles)
 	if err != nil {
 		return err
@@ -40,22 +57,27 @@ func runLoginCmd(workspaceName, kubeconfigName, contextName string) error {
 		return err
 	}
 
-	if workspaceName == "" {
-		workspaceName = cfg.DefaultWorkspace
-	}
-
-	if !runtime.WorkspaceExists(workspaceName) {
-		return fmt.Errorf("workspace does not exist: %s", workspaceName)
+	var parts []string
+	for _, part := range []string{workspaceName, kubeconfigName, contextName} {
+		if part != "" {
+			parts = append(parts, part)
+		}
 	}
-	if !runtime.KubeconfigExists(workspaceName, kubeconfigName) {
-		return fmt.Errorf("kubeconfig does not exist: %s/%s", workspaceName, kubeconfigName)
+	ref, err := runtime.Resolve(strings.Join(parts, "/"))
+	if err != nil {
+		return err
 	}
-	if !runtime.ContextExists(workspaceName, kubeconfigName, contextName) {
-		return fmt.Errorf("context does not exist: %s/%s/%s", workspaceName, kubeconfigName, contextName)
+
+	rk := ref.Kubeconfig
+	if err := confirmProtected(rk, config.Ref{Workspace: ref.Workspace, Kubeconfig: rk}.String(), yes); err != nil {
+		return err
 	}
 
-	// Find the credential source using workspace and kubeconfig name
-	rk := runtime.Workspace(workspaceName).Kubeconfig(kubeconfigName)
+	lock, err := lockPath(context.Background(), rk.Path, printWaiting)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
 
 	// Run login sources
 	for _, source := range rk.LoginSources {
@@ -22,7 +22,7 @@ func TestRunLoginCmdImportsReferencedContext(t *testing.T) {
 
 	cfg = newImportedLoginCommandTestConfig(targetPath)
 
-	err := runLoginCmd("work", "vgr", "ctx1")
+	err := runLoginCmd("work", "vgr", "ctx1", false)
 	require.NoError(t, err)
 
 	loaded, err := clientcmd.LoadFromFile(targetPath)
@@ -54,7 +54,7 @@ func TestRunLoginCmdDoesNotMutateLoginSourceEnv(t *testing.T) {
 	require.NotNil(t, source)
 	require.NotContains(t, source.Env, "KUBECONFIG")
 
-	err = runLoginCmd("work", "vgr", "ctx1")
+	err = runLoginCmd("work", "vgr", "ctx1", false)
 	require.NoError(t, err)
 	require.NotContains(t, source.Env, "KUBECONFIG")
 }
@@ -70,7 +70,7 @@ func TestRunLoginCmdReturnsHelpfulErrorWhenCommandCannotStart(t *testing.T) {
 	cfg = newImportedLoginCommandTestConfig(targetPath)
 	cfg.Kubeconfigs["vgr"].LoginSources["shared"].Command = filepath.Join(t.TempDir(), "missing-login-binary")
 
-	err := runLoginCmd("work", "vgr", "ctx1")
+	err := runLoginCmd("work", "vgr", "ctx1", false)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "login source \"shared\": run command")
 	require.Contains(t, err.Error(), "missing-login-binary")
@@ -87,7 +87,7 @@ func TestRunLoginCmdReturnsHelpfulErrorWhenGeneratedKubeconfigIsInvalid(t *testi
 	cfg = newImportedLoginCommandTestConfig(targetPath)
 	cfg.Kubeconfigs["vgr"].LoginSources["shared"].Args = []string{"-test.run=TestHelperProcessInvalidLoginCommand", "--"}
 
-	err := runLoginCmd("work", "vgr", "ctx1")
+	err := runLoginCmd("work", "vgr", "ctx1", false)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "login source \"shared\": load generated kubeconfig")
 	require.Contains(t, err.Error(), "cannot unmarshal string")
@@ -103,6 +103,16 @@ func TestHelperProcessLoginCommand(t *testing.T) {
 		os.Exit(2)
 	}
 
+	// Record every run so tests can count them.
+	if countFile := os.Getenv("LOGIN_COUNT_FILE"); countFile != "" {
+		f, err := os.OpenFile(countFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			os.Exit(5)
+		}
+		_, _ = f.WriteString("login\n")
+		_ = f.Close()
+	}
+
 	stdoutPayload := bytes.Repeat([]byte("stdout-data\n"), 10000)
 	stderrPayload := bytes.Repeat([]byte("stderr-data\n"), 10000)
 
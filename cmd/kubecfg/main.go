@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/amimof/kubecfg/pkg/cmdutil"
 	"github.com/amimof/kubecfg/pkg/config"
 )
 
@@ -29,6 +31,10 @@ var (
 
 	cfg config.Config
 
+	// fileCfg is the config as read from kubecfg.yaml and its included files,
+	// before overlays and interpolation.
+	fileCfg config.Config
+
 	logLevel   string
 	configFile string
 
@@ -61,39 +67,83 @@ func withConfig(run func(cmd *cobra.Command, args []string) error) func(cmd *cob
 }
 
 func loadConfig(validate bool) error {
+	file := configFile
 	if err := viper.ReadInConfig(); err != nil {
 		var configFileNotFoundError viper.ConfigFileNotFoundError
-		if errors.As(err, &configFileNotFoundError) || errors.Is(err, os.ErrNotExist) {
-			cfg = config.Config{
-				Version:          "v1",
-				DefaultWorkspace: "",
-				Kubeconfigs:      make(map[string]*config.Kubeconfig),
-				Workspaces:       make(map[string]*config.Workspace),
-			}
-			if validate {
-				if err := cfg.Validate(); err != nil {
-					logrus.Fatalf("config validation error: %v", err)
-					return err
-				}
-			}
-			return nil
+		if !errors.As(err, &configFileNotFoundError) && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error reading config: %w", err)
+		}
+		cfg = config.Config{
+			Version:          "v1",
+			DefaultWorkspace: "",
+			Kubeconfigs:      make(map[string]*config.Kubeconfig),
+			Workspaces:       make(map[string]*config.Workspace),
 		}
-		logrus.Fatalf("error reading config: %v", err)
+	} else {
+		if err := viper.Unmarshal(&cfg, config.DecodeHook()); err != nil {
+			return fmt.Errorf("error decoding config into struct: %w", err)
+		}
+		file = viper.ConfigFileUsed()
+	}
+
+	if err := cfg.ResolveIncludes(file); err != nil {
 		return err
 	}
-	if err := viper.Unmarshal(&cfg); err != nil {
-		logrus.Fatalf("error decoding config into struct: %v", err)
+	fileCfg = cfg
+
+	overlaid, err := cfg.ApplyOverlays(config.CurrentHost())
+	if err != nil {
 		return err
 	}
+
+	expanded, diags := overlaid.Expand()
+	cfg = *expanded
+
 	if validate {
-		if err := cfg.Validate(); err != nil {
-			logrus.Fatalf("config validation error: %v", err)
-			return err
-		}
+		return validateConfig(os.Stderr, file, diags...)
 	}
 	return nil
 }
 
+// validateConfig prints every diagnostic found in cfg, plus extra, to w with
+// line and column taken from the file each value came from. Returns
+// ErrNotValid if any of the diagnostics is an error. Warnings are printed but
+// do not fail the command.
+func validateConfig(w io.Writer, file string, extra ...config.Diagnostic) error {
+	diags := cfg.Diagnose()
+	for _, diag := range extra {
+		diag.File = cfg.SourceOf(diag.Path)
+		diags = append(diags, diag)
+	}
+	if len(diags) == 0 {
+		return nil
+	}
+	diags.Sort()
+
+	config.LocateFiles(diags, file)
+	printDiagnostics(w, diags)
+
+	if errs := diags.Errors(); len(errs) > 0 {
+		return fmt.Errorf("%w: %d error(s) in %s", ErrNotValid, len(errs), file)
+	}
+
+	return nil
+}
+
+func printDiagnostics(w io.Writer, diags config.Diagnostics) {
+	for _, diag := range diags {
+		location := diag.File
+		if diag.Line > 0 {
+			location = fmt.Sprintf("%s:%d:%d", diag.File, diag.Line, diag.Column)
+		}
+		cmdutil.Fprintf(w, `{{ .Location | FgHiBlack }} {{ if eq .Severity "error" }}{{ "error" | FgRed }}{{ else }}{{ "warning" | FgYellow }}{{ end }}: {{ .Message }}`, cmdutil.Data{
+			"Location": location + ":",
+			"Severity": string(diag.Severity),
+			"Message":  diag.Error(),
+		})
+	}
+}
+
 func main() {
 	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
 		lvl, err := logrus.ParseLevel(logLevel)
@@ -114,6 +164,7 @@ func main() {
 	// Setup flags
 	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", defaultConfigPath, "config file")
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "number for the log level verbosity (debug, info, warn, error, fatal, panic)")
+	rootCmd.PersistentFlags().DurationVar(&lockTimeout, "lock-timeout", lockTimeout, "How long to wait for other kubecfg processes to finish writing the same kubeconfigs")
 
 	rootCmd.AddCommand(newVersionCmd())
 	rootCmd.AddCommand(newKubeconfigsCmd())
@@ -124,6 +175,18 @@ func main() {
 	rootCmd.AddCommand(newDescribeCmd())
 	rootCmd.AddCommand(newUseCmd())
 	rootCmd.AddCommand(newWhichCmd())
+	rootCmd.AddCommand(newStatusCmd())
+	rootCmd.AddCommand(newContextCmd())
+	rootCmd.AddCommand(newNsCmd())
+	rootCmd.AddCommand(newShellCmd())
+	rootCmd.AddCommand(newEnvCmd())
+	rootCmd.AddCommand(newPromptCmd())
+	rootCmd.AddCommand(newLintCmd())
+	rootCmd.AddCommand(newSchemaCmd())
+	rootCmd.AddCommand(newMigrateCmd())
+	rootCmd.AddCommand(newImportCmd())
+	rootCmd.AddCommand(newSplitCmd())
+	rootCmd.AddCommand(newConfigCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
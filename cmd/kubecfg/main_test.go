@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	// Keep commands that refresh the prompt cache away from the user's cache.
+	dir, err := os.MkdirTemp("", "kubecfg-test-cache-")
+	if err != nil {
+		panic(err)
+	}
+	promptCacheFile = func() (string, error) {
+		return filepath.Join(dir, "prompt.json"), nil
+	}
+	envSessionDir = func() (string, error) {
+		return filepath.Join(dir, "env"), nil
+	}
+
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestValidateConfigPrintsDiagnosticsWithPositions(t *testing.T) {
+	originalCfg := cfg
+	originalNoColor := color.NoColor
+	t.Cleanup(func() {
+		cfg = originalCfg
+		color.NoColor = originalNoColor
+	})
+	color.NoColor = true
+
+	configPath := filepath.Join(t.TempDir(), "kubecfg.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`version: v1
+workspaces:
+  work:
+    kubeconfigs:
+      - demo
+      - demo
+kubeconfigs:
+  demo:
+    contexts:
+      admin:
+        cluster: missing
+`), 0o600))
+
+	cfg = config.Config{
+		Version: "v1",
+		Workspaces: map[string]*config.Workspace{
+			"work": {Kubeconfigs: []string{"demo", "demo"}},
+		},
+		Kubeconfigs: map[string]*config.Kubeconfig{
+			"demo": {
+				Contexts: map[string]*config.Context{
+					"admin": {Cluster: "missing"},
+				},
+			},
+		},
+	}
+
+	var stderr bytes.Buffer
+	err := validateConfig(&stderr, configPath)
+	require.ErrorIs(t, err, ErrNotValid)
+	require.Equal(t, []string{
+		configPath + ":10:7: error: kubeconfigs.demo.contexts.admin.authinfo is required",
+		configPath + ":11:9: error: kubeconfigs.demo.contexts.admin.cluster references missing cluster \"missing\"",
+		configPath + ":8:3: error: kubeconfigs.demo.path is required",
+		configPath + ":6:9: warning: workspaces.work.kubeconfigs[1] lists kubeconfig \"demo\" more than once",
+	}, strings.Split(strings.TrimSpace(stderr.String()), "\n"))
+}
+
+func TestValidateConfigAllowsWarnings(t *testing.T) {
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	cfg = newRenderCommandTestConfig(filepath.Join(t.TempDir(), "target.yaml"))
+	cfg.Kubeconfigs["vgr"].Clusters["cluster"].Server = ""
+
+	var stderr bytes.Buffer
+	err := validateConfig(&stderr, "kubecfg.yaml")
+	require.NoError(t, err)
+	require.Contains(t, stderr.String(), "warning: kubeconfigs.vgr.clusters.cluster.server is empty")
+}
+
+func TestValidateConfigLocatesDiagnosticsInIncludedFiles(t *testing.T) {
+	originalCfg := cfg
+	originalNoColor := color.NoColor
+	t.Cleanup(func() {
+		cfg = originalCfg
+		color.NoColor = originalNoColor
+	})
+	color.NoColor = true
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "kubecfg.yaml")
+	fragmentPath := filepath.Join(dir, "kubecfg.d", "team.yaml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(fragmentPath), 0o755))
+	require.NoError(t, os.WriteFile(configPath, []byte("version: v1\n"), 0o600))
+	require.NoError(t, os.WriteFile(fragmentPath, []byte(`kubeconfigs:
+  team:
+    path: /tmp/team
+    default_context: missing
+`), 0o600))
+
+	loaded, err := config.LoadFile(configPath)
+	require.NoError(t, err)
+	require.NoError(t, loaded.ResolveIncludes(configPath))
+	cfg = *loaded
+
+	var stderr bytes.Buffer
+	err = validateConfig(&stderr, configPath)
+	require.ErrorIs(t, err, ErrNotValid)
+	require.Equal(t,
+		fragmentPath+":4:5: error: kubeconfigs.team.default_context references missing context \"missing\"",
+		strings.TrimSpace(stderr.String()),
+	)
+}
+
+func TestValidateConfigLocatesOverlaysInIncludedFiles(t *testing.T) {
+	originalCfg := cfg
+	originalNoColor := color.NoColor
+	t.Cleanup(func() {
+		cfg = originalCfg
+		color.NoColor = originalNoColor
+	})
+	color.NoColor = true
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "kubecfg.yaml")
+	fragmentPath := filepath.Join(dir, "kubecfg.d", "team.yaml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(fragmentPath), 0o755))
+	require.NoError(t, os.WriteFile(configPath, []byte(`version: v1
+overlays:
+  - name: laptop
+    match:
+      hostname: laptop
+`), 0o600))
+	require.NoError(t, os.WriteFile(fragmentPath, []byte(`# team overlays
+overlays:
+  - name: ci
+    match: {}
+`), 0o600))
+
+	loaded, err := config.LoadFile(configPath)
+	require.NoError(t, err)
+	require.NoError(t, loaded.ResolveIncludes(configPath))
+	require.Equal(t, fragmentPath, loaded.SourceOf("overlays[1].match"))
+	cfg = *loaded
+
+	var stderr bytes.Buffer
+	err = validateConfig(&stderr, configPath)
+	require.ErrorIs(t, err, ErrNotValid)
+	require.Equal(t,
+		fragmentPath+":4:5: error: overlays[0].match requires hostname, user or env",
+		strings.TrimSpace(stderr.String()),
+	)
+}
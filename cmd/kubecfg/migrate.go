@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/amimof/kubecfg/pkg/cmdutil"
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var migrateStdout io.Writer = os.Stdout
+
+type migrateOptions struct {
+	dryRun bool
+}
+
+func newMigrateCmd() *cobra.Command {
+	var opts migrateOptions
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Upgrade kubecfg.yaml to the current config version",
+		Long: `Upgrade kubecfg.yaml to the current config version, one version at a time. The file is
+rewritten in place with comments kept, and the original is saved next to it with a .bak suffix.`,
+		Example: `  kubecfg migrate
+  kubecfg migrate --dry-run`,
+		Args:         cobra.ExactArgs(0),
+		SilenceUsage: true,
+		// Older configs may not pass validation, so the file is read directly
+		// instead of through withConfig.
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMigrateCmd(migrateStdout, configFile, opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Print the migrated config instead of writing it")
+
+	return cmd
+}
+
+func runMigrateCmd(stdout io.Writer, file string, opts migrateOptions) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("config file %s does not exist", file)
+		}
+		return err
+	}
+
+	doc, err := config.ParseDocument(data)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", file, err)
+	}
+
+	applied, err := config.Migrate(doc)
+	if err != nil {
+		return err
+	}
+
+	if opts.dryRun {
+		out, err := doc.Bytes()
+		if err != nil {
+			return err
+		}
+		_, err = stdout.Write(out)
+		return err
+	}
+
+	if len(applied) == 0 {
+		cmdutil.Fprintf(stdout, `{{ "✔" | FgGreen }} {{ .File }} is already at version {{ .Version | FgCyan }}`, cmdutil.Data{"File": file, "Version": config.CurrentVersion})
+		return nil
+	}
+
+	// An earlier backup may be the only copy of an even older config.
+	backup := file + ".bak"
+	if fileExists(backup) {
+		backup = file + ".bak." + strconv.FormatInt(time.Now().Unix(), 10)
+	}
+	if err := os.WriteFile(backup, data, 0o600); err != nil {
+		return fmt.Errorf("write backup: %w", err)
+	}
+
+	if err := writeConfigDocument(file, doc); err != nil {
+		return err
+	}
+
+	for _, m := range applied {
+		cmdutil.Fprintf(stdout, `{{ "✔" | FgGreen }} {{ .Description }}`, cmdutil.Data{"Description": m.Description})
+	}
+	cmdutil.Fprintf(stdout, `{{ "✔" | FgGreen }} Migrated {{ .File }} to version {{ .Version | FgCyan }} {{ printf "(backup: %s)" .Backup | FgHiBlack }}`, cmdutil.Data{
+		"File":    file,
+		"Version": config.CurrentVersion,
+		"Backup":  backup,
+	})
+
+	return nil
+}
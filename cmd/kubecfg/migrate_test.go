@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const migrateTestConfigYAML = `# clusters at work
+kubeconfigs:
+  demo:
+    path: /tmp/demo
+`
+
+func TestRunMigrateCmdRewritesConfigAndWritesBackup(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "kubecfg.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(migrateTestConfigYAML), 0o640))
+
+	var stdout bytes.Buffer
+	require.NoError(t, runMigrateCmd(&stdout, configPath, migrateOptions{}))
+	require.Contains(t, stdout.String(), "Migrated "+configPath+" to version v1")
+
+	contents, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	require.Equal(t, "# clusters at work\nversion: v1\nkubeconfigs:\n  demo:\n    path: /tmp/demo\n", string(contents))
+	require.Equal(t, os.FileMode(0o640), filePerms(t, configPath))
+
+	backup, err := os.ReadFile(configPath + ".bak")
+	require.NoError(t, err)
+	require.Equal(t, migrateTestConfigYAML, string(backup))
+
+	stdout.Reset()
+	require.NoError(t, runMigrateCmd(&stdout, configPath, migrateOptions{}))
+	require.Contains(t, stdout.String(), "is already at version v1")
+}
+
+func TestRunMigrateCmdDryRunDoesNotWrite(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "kubecfg.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(migrateTestConfigYAML), 0o640))
+
+	var stdout bytes.Buffer
+	require.NoError(t, runMigrateCmd(&stdout, configPath, migrateOptions{dryRun: true}))
+	require.Contains(t, stdout.String(), "version: v1")
+
+	contents, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	require.Equal(t, migrateTestConfigYAML, string(contents))
+	require.NoFileExists(t, configPath+".bak")
+}
+
+func TestRunMigrateCmdKeepsExistingBackup(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "kubecfg.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(migrateTestConfigYAML), 0o600))
+	require.NoError(t, os.WriteFile(configPath+".bak", []byte("# older\n"), 0o600))
+
+	var stdout bytes.Buffer
+	require.NoError(t, runMigrateCmd(&stdout, configPath, migrateOptions{}))
+
+	older, err := os.ReadFile(configPath + ".bak")
+	require.NoError(t, err)
+	require.Equal(t, "# older\n", string(older))
+
+	backups, err := filepath.Glob(configPath + ".bak.*")
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	require.Contains(t, stdout.String(), "(backup: "+backups[0]+")")
+	backup, err := os.ReadFile(backups[0])
+	require.NoError(t, err)
+	require.Equal(t, migrateTestConfigYAML, string(backup))
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/amimof/kubecfg/pkg/cmdutil"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func newNsCmd() *cobra.Command {
+	var save bool
+
+	cmd := &cobra.Command{
+		Use:     "ns [NAMESPACE|-]",
+		Aliases: []string{"namespace"},
+		Short:   "Switch namespace of the current context",
+		Long: `Change the namespace of the current context in the kubeconfig that
+~/.kube/config points to, without logging in or rendering it again.
+
+Without NAMESPACE, the namespace is picked in a fuzzy finder from the namespaces
+known to the kubeconfig. "-" switches back to the previous namespace. With
+--save, the namespace is also written to kubecfg.yaml so the next render keeps
+it, as the namespace of the context if kubecfg.yaml sets one and as
+default_namespace otherwise.`,
+		Example: `  kubecfg ns
+  kubecfg ns payments
+  kubecfg ns -
+  kubecfg ns payments --save`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: withConfig(func(cmd *cobra.Command, args []string) error {
+			var name string
+			if len(args) == 1 {
+				name = args[0]
+			}
+			return runNsCmd(name, save)
+		}),
+	}
+
+	cmd.Flags().BoolVar(&save, "save", false, "Write the namespace to kubecfg.yaml")
+
+	return cmd
+}
+
+func runNsCmd(name string, save bool) error {
+	active, err := loadActiveKubeconfig()
+	if err != nil {
+		return err
+	}
+	kc := active.Config
+
+	ctx, ok := kc.Contexts[kc.CurrentContext]
+	if !ok {
+		return fmt.Errorf("%s has no current context", active.Path)
+	}
+	current := ctx.Namespace
+	if current == "" {
+		current = "default"
+	}
+
+	switch name {
+	case "":
+		name, err = pickFrom(knownNamespaces(active), current)
+		if err != nil {
+			if errors.Is(err, errNoSelection) {
+				return nil
+			}
+			return err
+		}
+	case togglePrevious:
+		name = active.Previous.Namespace
+		if name == "" {
+			return fmt.Errorf("no previous namespace for %s", active.Path)
+		}
+	}
+
+	contextName := kc.CurrentContext
+	err = active.update(previousState{Context: active.Previous.Context, Namespace: current}, func(kc *api.Config) error {
+		ctx, ok := kc.Contexts[contextName]
+		if !ok {
+			return fmt.Errorf("context does not exist: %s", contextName)
+		}
+		ctx.Namespace = name
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if save {
+		if err := saveNamespace(active, contextName, name); err != nil {
+			return err
+		}
+	}
+
+	cmdutil.Printf(`{{ "✔" | FgGreen }} Switched to namespace {{ .Name | FgCyan }} in context {{ .Context | FgYellow }}`, cmdutil.Data{"Name": name, "Context": contextName})
+	return nil
+}
+
+// saveNamespace writes namespace to kubecfg.yaml where the next render picks
+// it up for contextName.
+func saveNamespace(active *activeKubeconfig, contextName, namespace string) error {
+	if active.Runtime == nil {
+		return fmt.Errorf("%s is not rendered by kubecfg, can't save namespace", active.Path)
+	}
+
+	if rc := active.Runtime.Context(contextName); rc != nil && rc.Template != "" && rc.Namespace != "" {
+		return fmt.Errorf("context %s is generated by context template %s, which sets its namespace", contextName, rc.Template)
+	}
+
+	if kc := fileCfg.Kubeconfigs[active.Runtime.Name]; kc != nil {
+		if ctx := kc.Contexts[contextName]; ctx != nil && ctx.Namespace != "" {
+			return active.save(fmt.Sprintf("contexts.%s.namespace", contextName), namespace)
+		}
+	}
+
+	return active.save("default_namespace", namespace)
+}
+
+// knownNamespaces returns the namespaces of the contexts of the active
+// kubeconfig and of the kubeconfig it was rendered from.
+func knownNamespaces(active *activeKubeconfig) []string {
+	namespaces := []string{"default"}
+	for _, ctx := range active.Config.Contexts {
+		namespaces = append(namespaces, ctx.Namespace)
+	}
+	if rk := active.Runtime; rk != nil {
+		namespaces = append(namespaces, rk.DefaultNamespace)
+		for _, ctx := range rk.Contexts {
+			namespaces = append(namespaces, ctx.Namespace)
+		}
+	}
+
+	namespaces = slices.DeleteFunc(namespaces, func(ns string) bool { return ns == "" })
+	slices.Sort(namespaces)
+	return slices.Compact(namespaces)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/amimof/kubecfg/pkg/cmdutil"
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/fatih/color"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const defaultPromptFormat = `{{ if .Workspace }}{{ .Workspace }}/{{ end }}{{ .Kubeconfig | Fg .Color }}:{{ .Context }}/{{ .Namespace }}{{ if .Protected }} {{ "⚠" | FgRed }}{{ end }}`
+
+var (
+	promptStdout io.Writer = os.Stdout
+
+	// promptCacheFile returns the file kubecfg prompt reads kubeconfig
+	// details from, so that it doesn't have to compile the config.
+	promptCacheFile = func() (string, error) {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "kubecfg", "prompt.json"), nil
+	}
+)
+
+type promptOptions struct {
+	format  string
+	noColor bool
+}
+
+// promptCache is what kubecfg prompt knows about rendered kubeconfigs. It is
+// refreshed by the commands that render or activate kubeconfigs.
+type promptCache struct {
+	BaseDir string `json:"base_dir"`
+	// Kubeconfigs are keyed by the path they are rendered to.
+	Kubeconfigs map[string]promptEntry `json:"kubeconfigs"`
+}
+
+type promptEntry struct {
+	Workspace  string `json:"workspace,omitempty"`
+	Kubeconfig string `json:"kubeconfig"`
+	Protected  bool   `json:"protected,omitempty"`
+	Color      string `json:"color,omitempty"`
+}
+
+func newPromptCmd() *cobra.Command {
+	var opts promptOptions
+
+	cmd := &cobra.Command{
+		Use:   "prompt",
+		Short: "Print the active kubeconfig for a shell prompt",
+		Long: `Print the workspace, kubeconfig, context and namespace in use, for a shell
+prompt. It only reads the active kubeconfig and a cache kept up to date by
+render, use, shell and env, so it is fast enough to run on every prompt. Nothing
+is printed when no kubeconfig is active.
+
+The kubeconfig is $KUBECONFIG if set, as in kubecfg shell, and the file
+~/.kube/config points to otherwise. --format is a template with .Workspace,
+.Kubeconfig, .Context, .Namespace, .Protected and .Color, the color setting of
+the kubeconfig, and the same color functions as the rest of kubecfg.`,
+		Example: `  PS1='$(kubecfg prompt) \$ '
+  kubecfg prompt --format '{{ .Kubeconfig | Fg .Color }}{{ if .Protected }} {{ "!" | FgRed }}{{ end }}'`,
+		Args:         cobra.ExactArgs(0),
+		SilenceUsage: true,
+		// The config isn't loaded, the prompt has to be fast.
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPromptCmd(opts, promptStdout)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.format, "format", defaultPromptFormat, "Template to print")
+	cmd.Flags().BoolVar(&opts.noColor, "no-color", false, "Don't print colors")
+
+	return cmd
+}
+
+func runPromptCmd(opts promptOptions, stdout io.Writer) error {
+	// Prompts are captured by the shell, so stdout is never a terminal.
+	color.NoColor = opts.noColor
+
+	cache := readPromptCache()
+
+	path := activePromptKubeconfig(cache.BaseDir)
+	if path == "" {
+		return nil
+	}
+	kc, err := clientcmd.LoadFromFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// The first file may only select the context, as with kubecfg env, so
+	// the files in $KUBECONFIG are merged like kubectl does.
+	if env := os.Getenv("KUBECONFIG"); env != "" && env != path {
+		kc, err = (&clientcmd.ClientConfigLoadingRules{Precedence: filepath.SplitList(env)}).Load()
+		if err != nil {
+			return err
+		}
+	}
+
+	entry, ok := cache.Kubeconfigs[path]
+	if !ok {
+		entry.Kubeconfig = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	// Sessions started by kubecfg shell or env use a copy of the kubeconfig.
+	if name := os.Getenv(envKubeconfig); name != "" {
+		entry = promptEntry{Kubeconfig: name}
+		for _, e := range cache.Kubeconfigs {
+			if e.Kubeconfig == name {
+				entry = e
+				break
+			}
+		}
+		if ws := os.Getenv(envWorkspace); ws != "" {
+			entry.Workspace = ws
+		}
+	}
+
+	namespace := "default"
+	if ctx, ok := kc.Contexts[kc.CurrentContext]; ok && ctx.Namespace != "" {
+		namespace = ctx.Namespace
+	}
+
+	cmdutil.Fprintf(stdout, opts.format, cmdutil.Data{
+		"Workspace":  entry.Workspace,
+		"Kubeconfig": entry.Kubeconfig,
+		"Context":    kc.CurrentContext,
+		"Namespace":  namespace,
+		"Protected":  entry.Protected,
+		"Color":      entry.Color,
+	})
+	return nil
+}
+
+// activePromptKubeconfig returns the kubeconfig in use, which is the first
+// file in $KUBECONFIG or the file the config symlink in baseDir points to.
+func activePromptKubeconfig(baseDir string) string {
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		path, _, _ := strings.Cut(env, string(filepath.ListSeparator))
+		return path
+	}
+
+	if baseDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		baseDir = filepath.Join(home, ".kube")
+	}
+
+	path, err := activeKubeconfigPath(baseDir)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// readPromptCache returns the prompt cache, or an empty one if it can't be
+// read.
+func readPromptCache() promptCache {
+	var cache promptCache
+
+	file, err := promptCacheFile()
+	if err != nil {
+		return cache
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		logrus.Debugf("ignoring prompt cache %s: %v", file, err)
+	}
+	return cache
+}
+
+// refreshPromptCache writes the prompt cache for rc. Failing to write it only
+// makes the prompt less informative, so errors are logged and ignored.
+func refreshPromptCache(rc *config.RuntimeConfig) {
+	cache := promptCache{BaseDir: rc.BaseDir, Kubeconfigs: make(map[string]promptEntry)}
+	for name, rk := range rc.Kubeconfigs {
+		entry := promptEntry{Kubeconfig: name, Protected: rk.Protected, Color: rk.Color}
+		if ref, err := rc.Resolve(name); err == nil && ref.Workspace != nil {
+			entry.Workspace = ref.Workspace.Name
+		}
+		cache.Kubeconfigs[rk.Path] = entry
+	}
+	// Context names of merged workspaces already include the kubeconfig.
+	for name, rw := range rc.Workspaces {
+		if !rw.Merged {
+			continue
+		}
+		entry := promptEntry{Kubeconfig: name}
+		for _, rk := range rw.Kubeconfigs {
+			entry.Protected = entry.Protected || rk.Protected
+		}
+		cache.Kubeconfigs[rw.Path] = entry
+	}
+
+	if err := writePromptCache(cache); err != nil {
+		logrus.Debugf("writing prompt cache: %v", err)
+	}
+}
+
+func writePromptCache(cache promptCache) error {
+	file, err := promptCacheFile()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return err
+	}
+	// Prompts read the cache while it is refreshed.
+	return writeFileAtomic(file, data, 0, nil)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestRunPromptCmd(t *testing.T) {
+	t.Setenv(envKubeconfig, "")
+	t.Setenv(envWorkspace, "")
+	originalNoColor := color.NoColor
+	t.Cleanup(func() {
+		color.NoColor = originalNoColor
+	})
+
+	targetPath, _ := setupActiveKubeconfig(t, "")
+	cfg.Kubeconfigs["vgr"].Protected = true
+	cfg.Kubeconfigs["vgr"].Color = "red"
+	t.Setenv("KUBECONFIG", "")
+
+	// Activating refreshes the cache the prompt reads.
+	require.NoError(t, runUseRefCmd("vgr", true))
+
+	var stdout bytes.Buffer
+	require.NoError(t, runPromptCmd(promptOptions{format: defaultPromptFormat, noColor: true}, &stdout))
+	require.Equal(t, "work/vgr:admin/payments ⚠\n", stdout.String())
+
+	stdout.Reset()
+	require.NoError(t, runPromptCmd(promptOptions{format: `{{ .Kubeconfig | Fg .Color }}`}, &stdout))
+	require.Equal(t, "\x1b[31mvgr\x1b[0m\n", stdout.String())
+
+	// Sessions use KUBECONFIG and the names exported with it.
+	kc, err := clientcmd.LoadFromFile(targetPath)
+	require.NoError(t, err)
+	kc.CurrentContext = "view"
+	sessionPath := filepath.Join(t.TempDir(), "session.yaml")
+	require.NoError(t, writeKubeconfig(sessionPath, kc))
+	t.Setenv("KUBECONFIG", sessionPath)
+	t.Setenv(envKubeconfig, "vgr")
+	t.Setenv(envWorkspace, "work")
+
+	stdout.Reset()
+	require.NoError(t, runPromptCmd(promptOptions{format: defaultPromptFormat, noColor: true}, &stdout))
+	require.Equal(t, "work/vgr:view/default ⚠\n", stdout.String())
+
+	// kubecfg env selects the context in a file listed before the kubeconfig.
+	selector, err := writeEnvSession("admin")
+	require.NoError(t, err)
+	t.Setenv("KUBECONFIG", selector+string(filepath.ListSeparator)+targetPath)
+
+	stdout.Reset()
+	require.NoError(t, runPromptCmd(promptOptions{format: defaultPromptFormat, noColor: true}, &stdout))
+	require.Equal(t, "work/vgr:admin/payments ⚠\n", stdout.String())
+}
+
+func TestRunPromptCmdWithoutActiveKubeconfig(t *testing.T) {
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "missing.yaml"))
+
+	var stdout bytes.Buffer
+	require.NoError(t, runPromptCmd(promptOptions{format: defaultPromptFormat, noColor: true}, &stdout))
+	require.Empty(t, stdout.String())
+}
+
+func TestRunPromptCmdUsesFileNameWithoutCache(t *testing.T) {
+	t.Setenv(envKubeconfig, "")
+	targetPath := filepath.Join(t.TempDir(), "adhoc.yaml")
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+	cfg = newRenderCommandTestConfig(targetPath)
+	require.NoError(t, runRenderCmd(context.Background(), "work", "vgr", true, false, time.Second))
+
+	other := filepath.Join(t.TempDir(), "other.yaml")
+	data, err := os.ReadFile(targetPath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(other, data, 0o600))
+	t.Setenv("KUBECONFIG", other)
+
+	var stdout bytes.Buffer
+	require.NoError(t, runPromptCmd(promptOptions{format: `{{ .Workspace }}|{{ .Kubeconfig }}`, noColor: true}, &stdout))
+	require.Equal(t, "|other\n", stdout.String())
+}
+
+func TestWritePromptCacheReplacesFileAtomically(t *testing.T) {
+	originalPromptCacheFile := promptCacheFile
+	t.Cleanup(func() {
+		promptCacheFile = originalPromptCacheFile
+	})
+	dir := filepath.Join(t.TempDir(), "kubecfg")
+	promptCacheFile = func() (string, error) {
+		return filepath.Join(dir, "prompt.json"), nil
+	}
+
+	require.NoError(t, writePromptCache(promptCache{BaseDir: "first"}))
+	require.NoError(t, writePromptCache(promptCache{BaseDir: "second"}))
+	require.Equal(t, "second", readPromptCache().BaseDir)
+
+	// Only the cache is left, without backups or temporary files.
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, os.FileMode(0o600), filePerms(t, filepath.Join(dir, "prompt.json")))
+}
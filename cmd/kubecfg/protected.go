@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/amimof/kubecfg/pkg/cmdutil"
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var (
+	errNotConfirmed = errors.New("not confirmed")
+
+	confirmStdin  io.Reader = os.Stdin
+	confirmStdout io.Writer = os.Stderr
+
+	// stdinIsTerminal reports whether the user can be asked for confirmation.
+	stdinIsTerminal = func() bool {
+		return term.IsTerminal(int(os.Stdin.Fd()))
+	}
+)
+
+// protectedMarker is appended to protected kubeconfigs wherever they are
+// listed or activated.
+const protectedMarker = "⚠ protected"
+
+// addYesFlag adds the --yes flag used to skip confirmation of protected
+// kubeconfigs.
+func addYesFlag(cmd *cobra.Command, yes *bool) {
+	cmd.PersistentFlags().BoolVarP(yes, "yes", "y", false, "Don't ask for confirmation before activating protected kubeconfigs")
+}
+
+// confirmProtected asks the user to confirm before a protected kubeconfig is
+// activated. name is the name shown to the user. Without a terminal to ask on,
+// protected kubeconfigs can only be activated with --yes.
+func confirmProtected(rk *config.RuntimeKubeconfig, name string, yes bool) error {
+	if rk == nil || !rk.Protected || yes {
+		return nil
+	}
+
+	if !stdinIsTerminal() {
+		return fmt.Errorf("%w: kubeconfig %s is protected, use --yes to activate it", errNotConfirmed, name)
+	}
+
+	cmdutil.Fprintf(confirmStdout, `{{ "⚠" | FgYellow }} Kubeconfig {{ .Name | FgCyan }} is {{ "protected" | FgRed }}`, cmdutil.Data{"Name": name})
+
+	switch rk.ProtectedConfirm {
+	case config.ProtectedConfirmTypeName:
+		fmt.Fprintf(confirmStdout, "Type %s to continue: ", rk.Name)
+		answer, err := readAnswer(confirmStdin)
+		if err != nil {
+			return err
+		}
+		if answer != rk.Name {
+			return fmt.Errorf("%w: %q does not match %s", errNotConfirmed, answer, rk.Name)
+		}
+	default:
+		fmt.Fprint(confirmStdout, "Continue? [y/N]: ")
+		answer, err := readAnswer(confirmStdin)
+		if err != nil {
+			return err
+		}
+		if a := strings.ToLower(answer); a != "y" && a != "yes" {
+			return fmt.Errorf("%w: kubeconfig %s was not activated", errNotConfirmed, name)
+		}
+	}
+
+	return nil
+}
+
+func readAnswer(r io.Reader) (string, error) {
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+// kubeconfigForFile returns the kubeconfig rendered to file, if any. Relative
+// files are resolved against baseDir, like the ~/.kube/config symlink.
+func kubeconfigForFile(rc *config.RuntimeConfig, file string) *config.RuntimeKubeconfig {
+	file = resolveRenderedFile(rc, file)
+	for _, name := range sortedNames(rc.Kubeconfigs) {
+		if rk := rc.Kubeconfigs[name]; filepath.Clean(rk.Path) == file {
+			return rk
+		}
+	}
+	return nil
+}
+
+// refsForFile returns the kubeconfigs in file, which is either the file of a
+// single kubeconfig or the file a merged workspace is rendered to. Refs to the
+// members of a merged workspace include the workspace.
+func refsForFile(rc *config.RuntimeConfig, file string) []config.Ref {
+	if rk := kubeconfigForFile(rc, file); rk != nil {
+		return []config.Ref{{Kubeconfig: rk}}
+	}
+
+	file = resolveRenderedFile(rc, file)
+	for _, name := range sortedNames(rc.Workspaces) {
+		rw := rc.Workspaces[name]
+		if !rw.Merged || filepath.Clean(rw.Path) != file {
+			continue
+		}
+		var refs []config.Ref
+		for _, rk := range selectKubeconfigs(rw.Kubeconfigs, labels.Everything()) {
+			refs = append(refs, config.Ref{Workspace: rw, Kubeconfig: rk})
+		}
+		return refs
+	}
+	return nil
+}
+
+// resolveRenderedFile returns the clean absolute path of file, resolving
+// relative files against the base dir of rc.
+func resolveRenderedFile(rc *config.RuntimeConfig, file string) string {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(rc.BaseDir, file)
+	}
+	return filepath.Clean(file)
+}
+
+// printUsingKubeconfig prints the message shown after a kubeconfig has been
+// activated. Without name, a merged workspace has been activated.
+func printUsingKubeconfig(workspace, name string, protected bool) {
+	data := cmdutil.Data{"Workspace": workspace, "Kubeconfig": name, "Protected": protected, "Marker": protectedMarker}
+	if name == "" {
+		cmdutil.Printf(`{{ "✔" | FgGreen }} Using workspace {{ .Workspace | FgYellow }}{{ if .Protected }} {{ .Marker | FgRed }}{{ end }}`, data)
+		return
+	}
+	cmdutil.Printf(`{{ "✔" | FgGreen }} Using kubeconfig {{ if .Workspace }}{{ .Workspace | FgYellow }}/{{ end }}{{ .Kubeconfig | FgCyan }}{{ if .Protected }} {{ .Marker | FgRed }}{{ end }}`, data)
+}
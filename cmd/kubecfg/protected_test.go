@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amimof/kubecfg/pkg/config"
+	fzf "github.com/junegunn/fzf/src"
+	"github.com/stretchr/testify/require"
+)
+
+// stubConfirm answers confirmation prompts with answer, as if typed on a
+// terminal when interactive is true, and returns what was printed.
+func stubConfirm(t *testing.T, interactive bool, answer string) *bytes.Buffer {
+	t.Helper()
+
+	originalStdin, originalStdout, originalIsTerminal := confirmStdin, confirmStdout, stdinIsTerminal
+	t.Cleanup(func() {
+		confirmStdin, confirmStdout, stdinIsTerminal = originalStdin, originalStdout, originalIsTerminal
+	})
+
+	var stdout bytes.Buffer
+	confirmStdin = strings.NewReader(answer)
+	confirmStdout = &stdout
+	stdinIsTerminal = func() bool { return interactive }
+	return &stdout
+}
+
+func TestConfirmProtected(t *testing.T) {
+	protected := &config.RuntimeKubeconfig{Name: "payments", Protected: true, ProtectedConfirm: config.ProtectedConfirmYes}
+	typeName := &config.RuntimeKubeconfig{Name: "payments", Protected: true, ProtectedConfirm: config.ProtectedConfirmTypeName}
+
+	tests := []struct {
+		name        string
+		rk          *config.RuntimeKubeconfig
+		yes         bool
+		interactive bool
+		answer      string
+		err         string
+	}{
+		{name: "not protected", rk: &config.RuntimeKubeconfig{Name: "dev"}},
+		{name: "yes flag", rk: protected, yes: true},
+		{name: "not a terminal", rk: protected, err: "not confirmed: kubeconfig prod/payments is protected, use --yes to activate it"},
+		{name: "confirmed", rk: protected, interactive: true, answer: "y\n"},
+		{name: "confirmed in full", rk: protected, interactive: true, answer: "YES\n"},
+		{name: "declined", rk: protected, interactive: true, answer: "\n", err: "not confirmed: kubeconfig prod/payments was not activated"},
+		{name: "name typed", rk: typeName, interactive: true, answer: "payments\n"},
+		{name: "wrong name typed", rk: typeName, interactive: true, answer: "y\n", err: `not confirmed: "y" does not match payments`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubConfirm(t, tt.interactive, tt.answer)
+
+			err := confirmProtected(tt.rk, "prod/payments", tt.yes)
+			if tt.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, errNotConfirmed)
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestConfirmProtectedPromptsForName(t *testing.T) {
+	stdout := stubConfirm(t, true, "payments\n")
+
+	rk := &config.RuntimeKubeconfig{Name: "payments", Protected: true, ProtectedConfirm: config.ProtectedConfirmTypeName}
+	require.NoError(t, confirmProtected(rk, "prod/payments", false))
+	require.Contains(t, stdout.String(), "Kubeconfig prod/payments is protected")
+	require.Contains(t, stdout.String(), "Type payments to continue: ")
+}
+
+func TestRunRenderCmdRequiresConfirmationForProtectedKubeconfig(t *testing.T) {
+	targetPath := filepath.Join(t.TempDir(), "target-kubeconfig.yaml")
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	cfg = newRenderCommandTestConfig(targetPath)
+	cfg.Kubeconfigs["vgr"].Protected = true
+
+	stubConfirm(t, true, "n\n")
+	err := runRenderCmd(context.Background(), "work", "vgr", true, false, time.Second)
+	require.ErrorIs(t, err, errNotConfirmed)
+	_, err = os.Stat(targetPath)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	stubConfirm(t, false, "")
+	err = runRenderCmd(context.Background(), "work", "vgr", true, true, time.Second)
+	require.NoError(t, err)
+
+	linkedTo, err := os.Readlink(filepath.Join(filepath.Dir(targetPath), "config"))
+	require.NoError(t, err)
+	require.Equal(t, targetPath, linkedTo)
+}
+
+func TestRunLoginCmdRequiresConfirmationForProtectedKubeconfig(t *testing.T) {
+	targetPath := filepath.Join(t.TempDir(), "target-kubeconfig.yaml")
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	cfg = newRenderCommandTestConfig(targetPath)
+	cfg.Kubeconfigs["vgr"].Protected = true
+
+	stubConfirm(t, false, "")
+	err := runLoginCmd("work", "vgr", "context", false)
+	require.ErrorIs(t, err, errNotConfirmed)
+	_, err = os.Stat(targetPath)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	err = runLoginCmd("work", "vgr", "context", true)
+	require.NoError(t, err)
+}
+
+func TestPickContextMarksProtectedKubeconfigs(t *testing.T) {
+	t.Setenv("FZF_DEFAULT_OPTS", "")
+	t.Setenv("FZF_DEFAULT_OPTS_FILE", "")
+
+	originalFzfRun := fzfRun
+	t.Cleanup(func() {
+		fzfRun = originalFzfRun
+	})
+
+	fzfRun = func(options *fzf.Options) (int, error) {
+		var inputs []string
+		for input := range options.Input {
+			inputs = append(inputs, input)
+		}
+
+		require.ElementsMatch(t, []string{"prod/api\t" + protectedMarker, "prod/web"}, inputs)
+		options.Output <- "prod/api\t" + protectedMarker
+		return fzf.ExitOk, nil
+	}
+
+	runtimeConfig := &config.RuntimeConfig{
+		Workspaces: map[string]*config.RuntimeWorkspace{
+			"prod": {
+				Name: "prod",
+				Kubeconfigs: map[string]*config.RuntimeKubeconfig{
+					"api": {Name: "api", Protected: true},
+					"web": {Name: "web"},
+				},
+			},
+		},
+	}
+
+	workspace, selected, err := pickContext(runtimeConfig)
+	require.NoError(t, err)
+	require.Equal(t, "prod", workspace)
+	require.Equal(t, "api", selected)
+}
+
+func TestKubeconfigForFileResolvesAgainstBaseDir(t *testing.T) {
+	rk := &config.RuntimeKubeconfig{Name: "api", Path: "/home/me/.kube/api.yaml"}
+	rc := &config.RuntimeConfig{
+		BaseDir:     "/home/me/.kube",
+		Kubeconfigs: map[string]*config.RuntimeKubeconfig{"api": rk},
+	}
+
+	require.Same(t, rk, kubeconfigForFile(rc, "api.yaml"))
+	require.Same(t, rk, kubeconfigForFile(rc, "/home/me/.kube/api.yaml"))
+	require.Nil(t, kubeconfigForFile(rc, "web.yaml"))
+}
+
+func TestRunUseCmdConfirmsProtectedMembersOfMergedFile(t *testing.T) {
+	t.Setenv("FZF_DEFAULT_OPTS", "")
+	t.Setenv("FZF_DEFAULT_OPTS_FILE", "")
+	tmpDir := t.TempDir()
+
+	originalCfg, originalFzfRun := cfg, fzfRun
+	t.Cleanup(func() {
+		cfg, fzfRun = originalCfg, originalFzfRun
+	})
+
+	cfg = newRenderCommandTestConfig(filepath.Join(tmpDir, "vgr.yaml"))
+	other := *cfg.Kubeconfigs["vgr"]
+	other.Path = filepath.Join(tmpDir, "other.yaml")
+	other.Protected = true
+	cfg.Kubeconfigs["other"] = &other
+	cfg.Workspaces["work"].Kubeconfigs = append(cfg.Workspaces["work"].Kubeconfigs, "other")
+	cfg.Workspaces["work"].Render = config.WorkspaceRenderMerged
+	require.NoError(t, runRenderCmd(context.Background(), "work", "", true, true, time.Second))
+
+	fzfRun = func(options *fzf.Options) (int, error) {
+		var inputs []string
+		for input := range options.Input {
+			inputs = append(inputs, input)
+		}
+
+		require.Equal(t, []string{"work.yaml\t" + protectedMarker}, inputs)
+		options.Output <- inputs[0]
+		return fzf.ExitOk, nil
+	}
+	glob := []string{filepath.Join(tmpDir, "*.yaml")}
+
+	stubConfirm(t, false, "")
+	err := runUseCmd(glob, false)
+	require.ErrorIs(t, err, errNotConfirmed)
+	require.EqualError(t, err, "not confirmed: kubeconfig work/other is protected, use --yes to activate it")
+
+	require.NoError(t, runUseCmd(glob, true))
+	linkedTo, err := os.Readlink(filepath.Join(tmpDir, "config"))
+	require.NoError(t, err)
+	require.Equal(t, "work.yaml", linkedTo)
+}
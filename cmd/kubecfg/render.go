@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"strings"
@@ -14,8 +15,10 @@ import (
 	"github.com/amimof/kubecfg/pkg/cmdutil"
 	"github.com/amimof/kubecfg/pkg/command"
 	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/amimof/kubecfg/pkg/filelock"
 	"github.com/amimof/kubecfg/pkg/service"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/clientcmd/api"
 
@@ -23,8 +26,11 @@ import (
 )
 
 var (
-	errNoSelection = errors.New("no selection")
-	fzfRun         = fzf.Run
+	errNoSelection   = errors.New("no selection")
+	errRenderChanged = errors.New("rendered kubeconfigs are out of date")
+	fzfRun           = fzf.Run
+
+	renderStdout io.Writer = os.Stdout
 )
 
 func newRenderCmd() *cobra.Command {
@@ -32,11 +38,14 @@ func newRenderCmd() *cobra.Command {
 		noLogin     bool
 		noUse       bool
 		all         bool
+		yes         bool
+		selector    string
 		waitTimeout time.Duration
+		dryRun      renderDryRunOptions
 	)
 
 	cmd := &cobra.Command{
-		Use:   "render [WORKSPACE] [KUBECONFIG]",
+		Use:   "render [WORKSPACE|REF] [KUBECONFIG]",
 		Short: "Select and render kubeconfigs",
 		Long:  `Select a kubeconfig, render and write it to the base directory.`,
 		Example: `
@@ -46,21 +55,52 @@ kubecfg render
 # Render kubeconfig mainframe in workspace homelab
 kubecfg render homelab mainframe
 
+# Render a kubeconfig by alias and make context admin the current context
+kubecfg render prod-eu/admin
+
 # Render all kubeconfigs across all workspaces
-kubecfg render --all`,
-		Args:         cobra.MaximumNArgs(2),
-		SilenceUsage: true,
+kubecfg render --all
+
+# Render all production kubeconfigs
+kubecfg render --all -l env=prod
+
+# Check in CI that the rendered kubeconfigs match the config
+kubecfg render --all --dry-run --diff --no-login --exit-code`,
+		Args:              cobra.MaximumNArgs(2),
+		ValidArgsFunction: completeWith(2, completeRender),
+		SilenceUsage:      true,
 		RunE: withConfig(func(cmd *cobra.Command, args []string) error {
+			if (dryRun.diff || dryRun.exitCode) && !dryRun.enabled {
+				return fmt.Errorf("--diff and --exit-code require --dry-run")
+			}
+			if selector != "" && !all {
+				return fmt.Errorf("--selector requires --all")
+			}
+			if dryRun.enabled {
+				sel, err := parseSelector(selector)
+				if err != nil {
+					return err
+				}
+				dryRun.all = all
+				dryRun.selector = sel
+				dryRun.noLogin = noLogin
+				dryRun.waitTimeout = waitTimeout
+				return runRenderDryRun(cmd.Context(), args, dryRun, renderStdout)
+			}
 			if all {
-				return runRenderAll(cmd.Context(), noLogin, waitTimeout)
+				sel, err := parseSelector(selector)
+				if err != nil {
+					return err
+				}
+				return runRenderAll(cmd.Context(), sel, noLogin, waitTimeout)
 			}
 			switch len(args) {
 			case 0:
-				return runRenderCmdFzf(cmd.Context(), noLogin, waitTimeout)
+				return runRenderCmdFzf(cmd.Context(), noLogin, yes, waitTimeout)
 			case 1:
-				return runRenderCmd(cmd.Context(), args[0], "", noLogin, waitTimeout)
+				return runRenderCmd(cmd.Context(), args[0], "", noLogin, yes, waitTimeout)
 			default:
-				return runRenderCmd(cmd.Context(), args[0], args[1], noLogin, waitTimeout)
+				return runRenderCmd(cmd.Context(), args[0], args[1], noLogin, yes, waitTimeout)
 			}
 		}),
 	}
@@ -68,52 +108,131 @@ kubecfg render --all`,
 	cmd.PersistentFlags().BoolVar(&noLogin, "no-login", false, "Skip execution of login flow prior to kubeconfig rendering")
 	cmd.PersistentFlags().BoolVar(&noUse, "no-use", false, "Skip activation of rendered kubeconfig after successful render")
 	cmd.PersistentFlags().BoolVarP(&all, "all", "a", false, "Render all kubeconfigs across all workspaces")
+	addSelectorFlag(cmd, &selector)
+	addYesFlag(cmd, &yes)
 	cmd.PersistentFlags().DurationVar(&waitTimeout, "timeout", time.Second*30, "How long in seconds to wait for login opearation to finish before giving up")
+	cmd.Flags().BoolVar(&dryRun.enabled, "dry-run", false, "Render without writing or activating anything, and list the files that would change")
+	cmd.Flags().BoolVar(&dryRun.diff, "diff", false, "With --dry-run, print a unified diff of each file that would change, with secrets redacted")
+	cmd.Flags().BoolVar(&dryRun.exitCode, "exit-code", false, "With --dry-run, exit non-zero if any file would change")
 
 	return cmd
 }
 
+// writeKubeconfig writes kubeconfig to path atomically, see writeFileAtomic.
+// The written file is loaded back and validated before it replaces the
+// existing one, which is kept as a backup. Certificate and key files that
+// don't exist aren't an error, see withoutMissingFiles.
 func writeKubeconfig(path string, kubeconfig *api.Config) error {
 	data, err := clientcmd.Write(*kubeconfig)
 	if err != nil {
 		return err
 	}
-	if err := os.WriteFile(path, data, 0o600); err != nil {
-		return err
+	return writeFileAtomic(path, data, cfg.BackupCount(), func(name string) error {
+		written, err := clientcmd.LoadFromFile(name)
+		if err != nil {
+			return err
+		}
+		if err := clientcmd.Validate(*withoutMissingFiles(written)); err != nil {
+			return fmt.Errorf("rendered kubeconfig %s is invalid: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// withoutMissingFiles returns a copy of kubeconfig without the certificate and
+// key files it references that don't exist, which clientcmd.Validate rejects.
+// They may only be created later, such as by a login source, or only exist
+// where the kubeconfig is used.
+func withoutMissingFiles(kubeconfig *api.Config) *api.Config {
+	kc := kubeconfig.DeepCopy()
+	for _, cluster := range kc.Clusters {
+		cluster.CertificateAuthority = existingFile(cluster.CertificateAuthority)
 	}
-	return os.Chmod(path, 0o600)
+	for _, authInfo := range kc.AuthInfos {
+		authInfo.ClientCertificate = existingFile(authInfo.ClientCertificate)
+		authInfo.ClientKey = existingFile(authInfo.ClientKey)
+	}
+	return kc
+}
+
+// existingFile returns path, or an empty string if nothing exists at path.
+func existingFile(path string) string {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return ""
+	}
+	return path
 }
 
-// setConfig creates a new symlink to a kubeconfigfile overwriting any existing one
+// setConfig points the config symlink in baseDir to name, replacing any
+// existing symlink or file atomically. It waits for other kubecfg processes
+// changing the symlink.
 func setConfig(baseDir, name string) error {
-	dst := path.Join(baseDir, "config")
-	// Remove existing symlink to config so we don't run into an error
-	if _, err := os.Stat(dst); !os.IsNotExist(err) {
-		err := os.Remove(dst)
-		if err != nil {
-			return err
-		}
+	lock, err := lockBaseDir(context.Background(), baseDir, printWaiting)
+	if err != nil {
+		return err
 	}
-	// Create the symlink to config
-	return os.Symlink(name, path.Join(baseDir, "config"))
+	defer lock.Release()
+
+	return symlinkAtomic(name, path.Join(baseDir, "config"))
 }
 
 // renderTask describes a single kubeconfig to render, along with its display name.
 type renderTask struct {
 	displayName string // "workspace/kubeconfig"
 	rk          *config.RuntimeKubeconfig
+
+	// merged is true if the kubeconfig is only rendered into rk.Config, to
+	// be written as part of a merged workspace.
+	merged bool
+
+	// dryRun is true if the kubeconfig is only rendered into rk.Config, to be
+	// compared with the file it would be written to.
+	dryRun bool
+
+	// rendered is the kubeconfig as it is currently written, if known.
+	// Contexts imported from login sources that didn't run are taken from it.
+	rendered *api.Config
+}
+
+// workspaceRenderTasks returns the tasks that render the kubeconfigs of rw.
+func workspaceRenderTasks(rw *config.RuntimeWorkspace, kubeconfigs []*config.RuntimeKubeconfig) []renderTask {
+	tasks := make([]renderTask, 0, len(kubeconfigs))
+	for _, rk := range kubeconfigs {
+		tasks = append(tasks, renderTask{
+			displayName: fmt.Sprintf("%s/%s", rw.Name, rk.Name),
+			rk:          rk,
+			merged:      rw.Merged,
+		})
+	}
+	return tasks
+}
+
+// writeMergedWorkspace writes the merged kubeconfig of rw. Its kubeconfigs
+// must have been rendered first.
+func writeMergedWorkspace(out io.Writer, rw *config.RuntimeWorkspace) error {
+	lock, err := lockPath(context.Background(), rw.Path, printWaiting)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	if err := writeKubeconfig(rw.Path, rw.Merge()); err != nil {
+		return err
+	}
+	cmdutil.Fprintf(out, `{{ "✔" | FgGreen }} Merged workspace {{ .Workspace | FgYellow }} into {{ .Path }}`, cmdutil.Data{"Workspace": rw.Name, "Path": rw.Path})
+	return nil
 }
 
 // renderKubeconfigs renders a list of kubeconfigs concurrently, showing a dashboard
-// with a spinner per kubeconfig. Errors on individual kubeconfigs do not block
-// others. Returns a joined error if any kubeconfigs failed, nil otherwise.
-func renderKubeconfigs(ctx context.Context, tasks []renderTask, skipLogin bool, waitTimeout time.Duration) error {
+// with a spinner per kubeconfig on out. Errors on individual kubeconfigs do not
+// block others. Returns a joined error if any kubeconfigs failed, nil otherwise.
+func renderKubeconfigs(ctx context.Context, out io.Writer, tasks []renderTask, skipLogin bool, waitTimeout time.Duration) error {
 	names := make([]string, len(tasks))
 	for i, t := range tasks {
 		names[i] = t.displayName
 	}
 
-	dash, err := cmdutil.NewDashboard(names, cmdutil.WithLayout(&cmdutil.Layout{Padding: [4]int{0, 2, 0, 0}}), cmdutil.WithFields(cmdutil.FieldError))
+	dash, err := cmdutil.NewDashboard(names, cmdutil.WithWriter(out), cmdutil.WithLayout(&cmdutil.Layout{Padding: [4]int{0, 2, 0, 0}}), cmdutil.WithFields(cmdutil.FieldError))
 	if err != nil {
 		return err
 	}
@@ -123,6 +242,8 @@ func renderKubeconfigs(ctx context.Context, tasks []renderTask, skipLogin bool,
 
 	go dash.Loop(ctx)
 
+	logins := newLoginGroup()
+
 	var (
 		outerWg      sync.WaitGroup
 		mu           sync.Mutex
@@ -134,7 +255,8 @@ func renderKubeconfigs(ctx context.Context, tasks []renderTask, skipLogin bool,
 		go func(idx int, t renderTask) {
 			defer outerWg.Done()
 
-			if err := renderSingleKubeconfig(ctx, t.rk, skipLogin, waitTimeout); err != nil {
+			status := func(msg string) { dash.SetStatus(idx, msg) }
+			if err := renderSingleKubeconfig(ctx, t, status, logins, skipLogin, waitTimeout); err != nil {
 				dash.FailMsg(idx, err.Error())
 				mu.Lock()
 				renderErrors = append(renderErrors, fmt.Errorf("%s: %w", t.displayName, err))
@@ -155,7 +277,7 @@ func renderKubeconfigs(ctx context.Context, tasks []renderTask, skipLogin bool,
 	if len(renderErrors) > 0 {
 
 		errHeader := fmt.Sprintf("\n%d of %d kubeconfigs failed to render\n", len(renderErrors), len(tasks))
-		cmdutil.Printf("{{ .Error }}", cmdutil.Data{"Error": errHeader})
+		cmdutil.Fprintf(out, "{{ .Error }}", cmdutil.Data{"Error": errHeader})
 		return fmt.Errorf("%w", errors.Join(renderErrors...))
 	}
 
@@ -163,9 +285,24 @@ func renderKubeconfigs(ctx context.Context, tasks []renderTask, skipLogin bool,
 }
 
 // renderSingleKubeconfig runs login sources, applies imports, and writes the
-// kubeconfig file for a single RuntimeKubeconfig. All login sources within the
-// kubeconfig are executed concurrently.
-func renderSingleKubeconfig(ctx context.Context, rk *config.RuntimeKubeconfig, skipLogin bool, waitTimeout time.Duration) error {
+// kubeconfig file for the RuntimeKubeconfig of a task, unless it is merged or
+// a dry run. All login sources within the kubeconfig are executed
+// concurrently. Login sources shared with other kubeconfigs only run once per
+// login group. Kubeconfigs that are written are locked for the whole render,
+// and status reports when another process holds the lock.
+func renderSingleKubeconfig(ctx context.Context, t renderTask, status func(string), logins *loginGroup, skipLogin bool, waitTimeout time.Duration) error {
+	rk := t.rk
+
+	if !t.merged && !t.dryRun {
+		lock, err := lockPath(ctx, rk.Path, func(h filelock.Holder) {
+			status(waitingMessage(h))
+		})
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+		status("")
+	}
 	if !skipLogin {
 		var (
 			loginWg  sync.WaitGroup
@@ -177,9 +314,11 @@ func renderSingleKubeconfig(ctx context.Context, rk *config.RuntimeKubeconfig, s
 			loginWg.Add(1)
 			go func(s *config.RuntimeLoginSource) {
 				defer loginWg.Done()
-				stdout := &bytes.Buffer{}
-				stderr := &bytes.Buffer{}
-				if err := runLogin(ctx, s, waitTimeout, stdout, stderr); err != nil {
+				if err := logins.Do(s, func() error {
+					stdout := &bytes.Buffer{}
+					stderr := &bytes.Buffer{}
+					return runLogin(ctx, s, waitTimeout, stdout, stderr)
+				}); err != nil {
 					loginMu.Lock()
 					loginErr = err
 					loginMu.Unlock()
@@ -194,10 +333,13 @@ func renderSingleKubeconfig(ctx context.Context, rk *config.RuntimeKubeconfig, s
 		}
 	}
 
-	if err := applyImportedContexts(rk); err != nil {
+	if err := applyImportedContextsFrom(rk, t.rendered); err != nil {
 		return err
 	}
 
+	if t.merged || t.dryRun {
+		return nil
+	}
 	if err := writeKubeconfig(rk.Path, rk.Config); err != nil {
 		return err
 	}
@@ -205,7 +347,11 @@ func renderSingleKubeconfig(ctx context.Context, rk *config.RuntimeKubeconfig, s
 	return nil
 }
 
-func runRenderCmd(ctx context.Context, workspaceName, kubeconfigName string, skipLogin bool, waitTimeout time.Duration) error {
+// runRenderCmd renders a whole workspace, or the single kubeconfig that
+// workspaceName and kubeconfigName resolve to, and activates it. A single
+// argument that isn't a workspace is resolved as a reference like prod-eu or
+// prod-eu/admin, in which case the context becomes the current context.
+func runRenderCmd(ctx context.Context, workspaceName, kubeconfigName string, skipLogin, yes bool, waitTimeout time.Duration) error {
 	if workspaceName == "" {
 		return fmt.Errorf("workspace cannot be empty")
 	}
@@ -219,53 +365,91 @@ func runRenderCmd(ctx context.Context, workspaceName, kubeconfigName string, ski
 	if err != nil {
 		return err
 	}
+	refreshPromptCache(runtime)
 
-	if !runtime.WorkspaceExists(workspaceName) {
-		return fmt.Errorf("workspace does not exist: %s", workspaceName)
-	}
+	if kubeconfigName == "" && runtime.WorkspaceExists(workspaceName) {
+		rw := runtime.Workspace(workspaceName)
+		kubeconfigs := selectKubeconfigs(rw.Kubeconfigs, labels.Everything())
+
+		// A single kubeconfig is activated after rendering, confirm before logging in.
+		if len(kubeconfigs) == 1 && !rw.Merged {
+			if err := confirmProtected(kubeconfigs[0], fmt.Sprintf("%s/%s", rw.Name, kubeconfigs[0].Name), yes); err != nil {
+				return err
+			}
+		}
 
-	var kubeconfigs []*config.RuntimeKubeconfig
+		tasks := workspaceRenderTasks(rw, kubeconfigs)
 
-	if kubeconfigName != "" {
-		if !runtime.KubeconfigExists(workspaceName, kubeconfigName) {
-			return fmt.Errorf("kubeconfig does not exist: %s/%s", workspaceName, kubeconfigName)
+		cmdutil.Println("Rendering workspace\n")
+
+		if err := renderKubeconfigs(ctx, os.Stdout, tasks, skipLogin, waitTimeout); err != nil {
+			return err
 		}
-		rk := runtime.Workspace(workspaceName).Kubeconfig(kubeconfigName)
-		kubeconfigs = append(kubeconfigs, rk)
-	} else {
-		for _, rtkc := range runtime.Workspace(workspaceName).Kubeconfigs {
-			kubeconfigs = append(kubeconfigs, rtkc)
+		if rw.Merged {
+			fmt.Print("\n")
+			return writeMergedWorkspace(os.Stdout, rw)
 		}
-	}
 
-	tasks := make([]renderTask, len(kubeconfigs))
-	for i, rk := range kubeconfigs {
-		tasks[i] = renderTask{
-			displayName: fmt.Sprintf("%s/%s", workspaceName, rk.Name),
-			rk:          rk,
+		// Automatically run "use" when only 1 kubeconfig
+		if len(kubeconfigs) == 1 {
+			rk := kubeconfigs[0]
+			if err := setConfig(runtime.BaseDir, rk.Path); err != nil {
+				return err
+			}
+			fmt.Print("\n")
+			printUsingKubeconfig(rw.Name, rk.Name, rk.Protected)
 		}
+		return nil
+	}
+
+	name := workspaceName
+	if kubeconfigName != "" {
+		name = workspaceName + "/" + kubeconfigName
+	}
+	ref, err := runtime.Resolve(name)
+	if err != nil {
+		return err
 	}
 
-	cmdutil.Println("Rendering workspace\n")
+	rk := ref.Kubeconfig
+	displayName := config.Ref{Workspace: ref.Workspace, Kubeconfig: rk}.String()
 
-	if err := renderKubeconfigs(ctx, tasks, skipLogin, waitTimeout); err != nil {
+	// The kubeconfig is activated after rendering, confirm before logging in.
+	if err := confirmProtected(rk, displayName, yes); err != nil {
 		return err
 	}
 
-	// Automatically run "use" when only 1 kubeconfig
-	if len(kubeconfigs) == 1 {
-		rk := kubeconfigs[0]
-		if err := setConfig(runtime.BaseDir, rk.Path); err != nil {
-			return err
-		}
-		fmt.Print("\n")
-		cmdutil.Printf(`{{ "✔" | FgGreen }} Using kubeconfig {{ .Workspace | FgYellow }}/{{ .Kubeconfig | FgCyan }}`, cmdutil.Data{"Workspace": workspaceName, "Kubeconfig": rk.Name})
+	if ref.Context != nil {
+		rk.Config.CurrentContext = ref.Context.Name
+	}
+
+	tasks := []renderTask{{displayName: displayName, rk: rk}}
+
+	cmdutil.Println("Rendering kubeconfig\n")
+
+	if err := renderKubeconfigs(ctx, os.Stdout, tasks, skipLogin, waitTimeout); err != nil {
+		return err
+	}
+
+	if err := setConfig(runtime.BaseDir, rk.Path); err != nil {
+		return err
 	}
+	fmt.Print("\n")
+	printUsingKubeconfig(workspaceDisplayName(ref.Workspace), rk.Name, rk.Protected)
 
 	return nil
 }
 
-func runRenderAll(ctx context.Context, skipLogin bool, waitTimeout time.Duration) error {
+// workspaceDisplayName returns the name of rw, or an empty string for
+// kubeconfigs that aren't in a workspace.
+func workspaceDisplayName(rw *config.RuntimeWorkspace) string {
+	if rw == nil {
+		return ""
+	}
+	return rw.Name
+}
+
+func runRenderAll(ctx context.Context, selector labels.Selector, skipLogin bool, waitTimeout time.Duration) error {
 	compiler, err := newCompilerWithOptionalDecryptor(&cfg, cfg.IdentityFiles)
 	if err != nil {
 		return err
@@ -275,27 +459,61 @@ func runRenderAll(ctx context.Context, skipLogin bool, waitTimeout time.Duration
 	if err != nil {
 		return err
 	}
+	refreshPromptCache(runtime)
 
-	var tasks []renderTask
-	for _, ws := range runtime.Workspaces {
-		for _, rk := range ws.Kubeconfigs {
-			tasks = append(tasks, renderTask{
-				displayName: fmt.Sprintf("%s/%s", ws.Name, rk.Name),
-				rk:          rk,
-			})
-		}
-	}
-
+	tasks, merged := allRenderTasks(runtime, selector)
 	if len(tasks) == 0 {
 		return fmt.Errorf("no kubeconfigs found")
 	}
 
 	cmdutil.Println("Rendering all workspaces\n")
 
-	return renderKubeconfigs(ctx, tasks, skipLogin, waitTimeout)
+	if err := renderKubeconfigs(ctx, os.Stdout, tasks, skipLogin, waitTimeout); err != nil {
+		return err
+	}
+	if len(merged) > 0 {
+		fmt.Print("\n")
+	}
+	for _, ws := range merged {
+		if err := writeMergedWorkspace(os.Stdout, ws); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allRenderTasks returns the tasks that render the kubeconfigs matching
+// selector across all workspaces, and the merged workspaces among them.
+func allRenderTasks(rc *config.RuntimeConfig, selector labels.Selector) (tasks []renderTask, merged []*config.RuntimeWorkspace) {
+	seen := make(map[*config.RuntimeKubeconfig]int)
+	for _, name := range sortedNames(rc.Workspaces) {
+		ws := rc.Workspace(name)
+		kubeconfigs := selectKubeconfigs(ws.Kubeconfigs, selector)
+		if len(kubeconfigs) == 0 {
+			continue
+		}
+		// A merged workspace is always rendered as a whole.
+		if ws.Merged {
+			kubeconfigs = selectKubeconfigs(ws.Kubeconfigs, labels.Everything())
+			merged = append(merged, ws)
+		}
+
+		// Kubeconfigs in several workspaces are rendered once, and written if
+		// any of the workspaces renders them separately.
+		for _, task := range workspaceRenderTasks(ws, kubeconfigs) {
+			if i, ok := seen[task.rk]; ok {
+				tasks[i].merged = tasks[i].merged && task.merged
+				continue
+			}
+			seen[task.rk] = len(tasks)
+			tasks = append(tasks, task)
+		}
+	}
+
+	return tasks, merged
 }
 
-func runRenderCmdFzf(ctx context.Context, skipLogin bool, waitTimeout time.Duration) error {
+func runRenderCmdFzf(ctx context.Context, skipLogin, yes bool, waitTimeout time.Duration) error {
 	compiler, err := newCompilerWithOptionalDecryptor(&cfg, cfg.IdentityFiles)
 	if err != nil {
 		return err
@@ -305,6 +523,7 @@ func runRenderCmdFzf(ctx context.Context, skipLogin bool, waitTimeout time.Durat
 	if err != nil {
 		return err
 	}
+	refreshPromptCache(runtime)
 
 	workspace, selected, err := pickContext(runtime)
 	if err != nil {
@@ -316,10 +535,12 @@ func runRenderCmdFzf(ctx context.Context, skipLogin bool, waitTimeout time.Durat
 
 	rk := runtime.Workspace(workspace).Kubeconfig(selected)
 
-	if rk.Config.CurrentContext == "" {
-		rk.Config.CurrentContext = rk.Name
+	if err := confirmProtected(rk, fmt.Sprintf("%s/%s", workspace, selected), yes); err != nil {
+		return err
 	}
 
+	defaultCurrentContext(rk)
+
 	tasks := []renderTask{{
 		displayName: fmt.Sprintf("%s/%s", workspace, selected),
 		rk:          rk,
@@ -327,18 +548,62 @@ func runRenderCmdFzf(ctx context.Context, skipLogin bool, waitTimeout time.Durat
 
 	cmdutil.Println("Rendering kubeconfig\n")
 
-	if err := renderKubeconfigs(ctx, tasks, skipLogin, waitTimeout); err != nil {
+	if err := renderKubeconfigs(ctx, os.Stdout, tasks, skipLogin, waitTimeout); err != nil {
 		return err
 	}
 
 	if err := setConfig(runtime.BaseDir, rk.Path); err != nil {
 		return err
 	}
-	cmdutil.Printf(`{{ "✔" | FgGreen }} Using kubeconfig {{ .Workspace | FgYellow }}/{{ .Kubeconfig | FgCyan }}`, cmdutil.Data{"Workspace": workspace, "Kubeconfig": selected})
+	printUsingKubeconfig(workspace, selected, rk.Protected)
 
 	return nil
 }
 
+// defaultCurrentContext makes the context named after rk its current context,
+// if it has no current context and such a context exists.
+func defaultCurrentContext(rk *config.RuntimeKubeconfig) {
+	if rk.Config.CurrentContext != "" {
+		return
+	}
+	if _, ok := rk.Config.Contexts[rk.Name]; ok {
+		rk.Config.CurrentContext = rk.Name
+	}
+}
+
+// loginGroup runs each login source at most once. Kubeconfigs that share a
+// login source wait for the first run and reuse its ImportedConfig and error.
+type loginGroup struct {
+	mu    sync.Mutex
+	calls map[*config.RuntimeLoginSource]*loginCall
+}
+
+type loginCall struct {
+	once sync.Once
+	err  error
+}
+
+func newLoginGroup() *loginGroup {
+	return &loginGroup{calls: make(map[*config.RuntimeLoginSource]*loginCall)}
+}
+
+// Do calls fn unless it has already been called for source, and returns the
+// error of the first call.
+func (g *loginGroup) Do(source *config.RuntimeLoginSource, fn func() error) error {
+	g.mu.Lock()
+	call, ok := g.calls[source]
+	if !ok {
+		call = &loginCall{}
+		g.calls[source] = call
+	}
+	g.mu.Unlock()
+
+	call.once.Do(func() {
+		call.err = fn()
+	})
+	return call.err
+}
+
 func runLogin(ctx context.Context, source *config.RuntimeLoginSource, waitTimeout time.Duration, stdout, stderr *bytes.Buffer) error {
 	cmdCtx, cancel := context.WithTimeout(context.Background(), waitTimeout)
 	defer cancel()
@@ -354,12 +619,18 @@ func runLogin(ctx context.Context, source *config.RuntimeLoginSource, waitTimeou
 	return nil
 }
 
+// pickContext lets the user pick a kubeconfig as workspace/kubeconfig.
+// Protected kubeconfigs are marked after a tab, which is not part of the
+// selection returned.
 func pickContext(rc *config.RuntimeConfig) (string, string, error) {
 	inputChan := make(chan string)
 	go func() {
 		for _, w := range rc.Workspaces {
 			for _, k := range w.Kubeconfigs {
 				input := fmt.Sprintf("%s/%s", w.Name, k.Name)
+				if k.Protected {
+					input += "\t" + protectedMarker
+				}
 				inputChan <- input
 
 			}
@@ -371,6 +642,7 @@ func pickContext(rc *config.RuntimeConfig) (string, string, error) {
 	if err != nil {
 		return "", "", err
 	}
+	selected, _, _ = strings.Cut(selected, "\t")
 
 	ss := strings.Split(selected, "/")
 	if len(ss) == 2 {
@@ -380,6 +652,25 @@ func pickContext(rc *config.RuntimeConfig) (string, string, error) {
 	return "", selected, nil
 }
 
+// resolveOrPick resolves ref, or lets the user pick a kubeconfig like
+// kubecfg render does when ref is empty. Returns errNoSelection if nothing was
+// picked.
+func resolveOrPick(rc *config.RuntimeConfig, ref string) (config.Ref, error) {
+	if ref != "" {
+		return rc.Resolve(ref)
+	}
+
+	workspace, selected, err := pickContext(rc)
+	if err != nil {
+		return config.Ref{}, err
+	}
+	rw := rc.Workspace(workspace)
+	if rw == nil || rw.Kubeconfig(selected) == nil {
+		return config.Ref{}, fmt.Errorf("kubeconfig does not exist: %s/%s", workspace, selected)
+	}
+	return config.Ref{Workspace: rw, Kubeconfig: rw.Kubeconfig(selected)}, nil
+}
+
 func pick(input chan string) (string, error) {
 	outputChan := make(chan string, 1)
 
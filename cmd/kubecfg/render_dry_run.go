@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/amimof/kubecfg/pkg/cmdutil"
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+type renderDryRunOptions struct {
+	enabled     bool
+	diff        bool
+	exitCode    bool
+	all         bool
+	selector    labels.Selector
+	noLogin     bool
+	waitTimeout time.Duration
+}
+
+// runRenderDryRun renders the kubeconfigs that args select, like kubecfg
+// render, and reports the files that writing them would change instead of
+// writing them. Nothing is activated and protected kubeconfigs aren't
+// confirmed. With noLogin, imported contexts are taken from the files as they
+// are, so that no credentials are needed.
+func runRenderDryRun(ctx context.Context, args []string, opts renderDryRunOptions, stdout io.Writer) error {
+	compiler, err := newCompilerWithOptionalDecryptor(&cfg, cfg.IdentityFiles)
+	if err != nil {
+		return err
+	}
+
+	runtime, err := compiler.Compile(&cfg)
+	if err != nil {
+		return err
+	}
+
+	tasks, merged, err := dryRunTasks(runtime, args, opts)
+	if err != nil {
+		return err
+	}
+
+	// Kubeconfigs only rendered into a merged workspace are compared as part
+	// of the first merged workspace they are in.
+	mergedInto := make(map[*config.RuntimeKubeconfig]*config.RuntimeWorkspace)
+	for _, rw := range merged {
+		for _, rk := range rw.Kubeconfigs {
+			if _, ok := mergedInto[rk]; !ok {
+				mergedInto[rk] = rw
+			}
+		}
+	}
+
+	for i := range tasks {
+		t := &tasks[i]
+		t.dryRun = true
+		if !opts.noLogin {
+			continue
+		}
+		if !t.merged {
+			if t.rendered, err = loadRenderedKubeconfig(t.rk.Path); err != nil {
+				return fmt.Errorf("%s: %w", t.displayName, err)
+			}
+			continue
+		}
+		rendered, err := loadRenderedKubeconfig(mergedInto[t.rk].Path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.displayName, err)
+		}
+		if rendered != nil {
+			t.rendered = config.Unmerge(rendered, t.rk.Name)
+		}
+	}
+
+	if err := renderKubeconfigs(ctx, os.Stderr, tasks, opts.noLogin, opts.waitTimeout); err != nil {
+		return err
+	}
+
+	var diffs []kubeconfigDiff
+	for _, t := range tasks {
+		if t.merged {
+			continue
+		}
+		d, err := diffKubeconfig(t.rk.Path, t.rk.Config)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.displayName, err)
+		}
+		diffs = append(diffs, d)
+	}
+	for _, rw := range merged {
+		d, err := diffKubeconfig(rw.Path, rw.Merge())
+		if err != nil {
+			return fmt.Errorf("%s: %w", rw.Name, err)
+		}
+		diffs = append(diffs, d)
+	}
+
+	changed := printKubeconfigDiffs(stdout, diffs, opts.diff)
+	if changed == 0 {
+		cmdutil.Fprintf(stdout, `{{ "✔" | FgGreen }} Rendered kubeconfigs are up to date`, nil)
+		return nil
+	}
+	if opts.exitCode {
+		return fmt.Errorf("%w: %d of %d kubeconfigs would change", errRenderChanged, changed, len(diffs))
+	}
+	return nil
+}
+
+// dryRunTasks returns the tasks that kubecfg render renders for args, and the
+// merged workspaces it writes.
+func dryRunTasks(rc *config.RuntimeConfig, args []string, opts renderDryRunOptions) ([]renderTask, []*config.RuntimeWorkspace, error) {
+	if opts.all {
+		tasks, merged := allRenderTasks(rc, opts.selector)
+		if len(tasks) == 0 {
+			return nil, nil, fmt.Errorf("no kubeconfigs found")
+		}
+		return tasks, merged, nil
+	}
+
+	if len(args) == 0 {
+		workspace, selected, err := pickContext(rc)
+		if err != nil {
+			return nil, nil, err
+		}
+		rk := rc.Workspace(workspace).Kubeconfig(selected)
+		defaultCurrentContext(rk)
+		return []renderTask{{displayName: fmt.Sprintf("%s/%s", workspace, selected), rk: rk}}, nil, nil
+	}
+
+	if len(args) == 1 && rc.WorkspaceExists(args[0]) {
+		rw := rc.Workspace(args[0])
+		tasks := workspaceRenderTasks(rw, selectKubeconfigs(rw.Kubeconfigs, labels.Everything()))
+		if rw.Merged {
+			return tasks, []*config.RuntimeWorkspace{rw}, nil
+		}
+		return tasks, nil, nil
+	}
+
+	name := args[0]
+	if len(args) == 2 {
+		name = args[0] + "/" + args[1]
+	}
+	ref, err := rc.Resolve(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ref.Context != nil {
+		ref.Kubeconfig.Config.CurrentContext = ref.Context.Name
+	}
+	displayName := config.Ref{Workspace: ref.Workspace, Kubeconfig: ref.Kubeconfig}.String()
+	return []renderTask{{displayName: displayName, rk: ref.Kubeconfig}}, nil, nil
+}
+
+// printKubeconfigDiffs prints the files in diffs that would change, or their
+// diffs if diff is true, and returns how many would change.
+func printKubeconfigDiffs(w io.Writer, diffs []kubeconfigDiff, diff bool) int {
+	changed := 0
+	for _, d := range diffs {
+		if !d.Changed {
+			continue
+		}
+		changed++
+
+		switch {
+		case diff && d.Diff != "":
+			fmt.Fprint(w, d.Diff)
+		case diff:
+			cmdutil.Fprintf(w, `{{ "~" | FgYellow }} Would update {{ .Path }} {{ "(only redacted values differ)" | FgHiBlack }}`, cmdutil.Data{"Path": d.Path})
+		case d.Created:
+			cmdutil.Fprintf(w, `{{ "+" | FgGreen }} Would create {{ .Path }}`, cmdutil.Data{"Path": d.Path})
+		default:
+			cmdutil.Fprintf(w, `{{ "~" | FgYellow }} Would update {{ .Path }}`, cmdutil.Data{"Path": d.Path})
+		}
+	}
+	return changed
+}
+
+// kubeconfigDiff is the change that writing a kubeconfig would make to its file.
+type kubeconfigDiff struct {
+	Path    string
+	Created bool
+	Changed bool
+
+	// Diff is a unified diff between the file and the kubeconfig, with
+	// secrets redacted. It is empty if only redacted values changed.
+	Diff string
+}
+
+// diffKubeconfig compares the file at path with what writeKubeconfig would
+// write for kubeconfig.
+func diffKubeconfig(path string, kubeconfig *api.Config) (kubeconfigDiff, error) {
+	d := kubeconfigDiff{Path: path}
+
+	want, err := clientcmd.Write(*kubeconfig)
+	if err != nil {
+		return d, err
+	}
+
+	current, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		d.Created = true
+	} else if err != nil {
+		return d, err
+	}
+	if !d.Created && bytes.Equal(current, want) {
+		return d, nil
+	}
+	d.Changed = true
+
+	fromFile := "/dev/null"
+	var from []byte
+	if !d.Created {
+		currentConfig, err := clientcmd.Load(current)
+		if err != nil {
+			return d, fmt.Errorf("load %s: %w", path, err)
+		}
+		if from, err = redactKubeconfig(currentConfig); err != nil {
+			return d, err
+		}
+		fromFile = path
+	}
+
+	to, err := redactKubeconfig(kubeconfig)
+	if err != nil {
+		return d, err
+	}
+
+	d.Diff, err = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(from)),
+		B:        difflib.SplitLines(string(to)),
+		FromFile: fromFile,
+		ToFile:   path,
+		Context:  3,
+	})
+	return d, err
+}
+
+// redactKubeconfig returns kubeconfig as YAML with tokens, passwords, key
+// data, auth provider config and exec env values replaced by REDACTED.
+func redactKubeconfig(kubeconfig *api.Config) ([]byte, error) {
+	redacted := kubeconfig.DeepCopy()
+	if err := api.RedactSecrets(redacted); err != nil {
+		return nil, err
+	}
+
+	// RedactSecrets leaves these alone, but they usually hold id tokens,
+	// refresh tokens, client secrets and API keys.
+	for _, authInfo := range redacted.AuthInfos {
+		if authInfo.AuthProvider != nil {
+			for key := range authInfo.AuthProvider.Config {
+				authInfo.AuthProvider.Config[key] = "REDACTED"
+			}
+		}
+		if authInfo.Exec != nil {
+			for i := range authInfo.Exec.Env {
+				authInfo.Exec.Env[i].Value = "REDACTED"
+			}
+		}
+	}
+
+	return clientcmd.Write(*redacted)
+}
+
+// loadRenderedKubeconfig loads the kubeconfig at path, or returns nil if it
+// doesn't exist.
+func loadRenderedKubeconfig(path string) (*api.Config, error) {
+	kubeconfig, err := clientcmd.LoadFromFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return kubeconfig, err
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestRunRenderDryRunDiff(t *testing.T) {
+	targetPath := filepath.Join(t.TempDir(), "vgr.yaml")
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	cfg = newRenderCommandTestConfig(targetPath)
+	cfg.Kubeconfigs["vgr"].AuthInfos["user"] = &config.AuthInfo{Token: "secret-token"}
+	opts := renderDryRunOptions{enabled: true, exitCode: true, selector: labels.Everything(), waitTimeout: time.Second}
+
+	var stdout bytes.Buffer
+	err := runRenderDryRun(context.Background(), []string{"work", "vgr"}, opts, &stdout)
+	require.ErrorIs(t, err, errRenderChanged)
+	require.Contains(t, stdout.String(), "Would create "+targetPath)
+	_, err = os.Stat(targetPath)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	require.NoError(t, runRenderCmd(context.Background(), "work", "vgr", true, false, time.Second))
+
+	stdout.Reset()
+	require.NoError(t, runRenderDryRun(context.Background(), []string{"work", "vgr"}, opts, &stdout))
+	require.Contains(t, stdout.String(), "Rendered kubeconfigs are up to date")
+
+	cfg.Kubeconfigs["vgr"].Clusters["cluster"].Server = "https://changed.example.com"
+	opts.diff = true
+	stdout.Reset()
+	err = runRenderDryRun(context.Background(), []string{"work", "vgr"}, opts, &stdout)
+	require.EqualError(t, err, "rendered kubeconfigs are out of date: 1 of 1 kubeconfigs would change")
+	require.Contains(t, stdout.String(), "--- "+targetPath)
+	require.Contains(t, stdout.String(), "-    server: https://example.com")
+	require.Contains(t, stdout.String(), "+    server: https://changed.example.com")
+	require.NotContains(t, stdout.String(), "secret-token")
+
+	loaded, err := loadRenderedKubeconfig(targetPath)
+	require.NoError(t, err)
+	redacted, err := redactKubeconfig(loaded)
+	require.NoError(t, err)
+	require.Contains(t, string(redacted), "token: REDACTED")
+	require.Equal(t, "secret-token", loaded.AuthInfos["user"].Token)
+
+	// Only the token changed, which the diff can't show.
+	cfg.Kubeconfigs["vgr"].Clusters["cluster"].Server = "https://example.com"
+	cfg.Kubeconfigs["vgr"].AuthInfos["user"].Token = "rotated-token"
+	stdout.Reset()
+	err = runRenderDryRun(context.Background(), []string{"work", "vgr"}, opts, &stdout)
+	require.ErrorIs(t, err, errRenderChanged)
+	require.Contains(t, stdout.String(), "Would update "+targetPath)
+	require.Contains(t, stdout.String(), "only redacted values differ")
+	require.NotContains(t, stdout.String(), "token")
+}
+
+func TestRunRenderDryRunDiffRedactsAuthProviderAndExecEnv(t *testing.T) {
+	targetPath := filepath.Join(t.TempDir(), "vgr.yaml")
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	cfg = newRenderCommandTestConfig(targetPath)
+	cfg.Kubeconfigs["vgr"].AuthInfos["user"] = &config.AuthInfo{
+		AuthProvider: &config.AuthProviderConfig{
+			Name: "oidc",
+			Config: map[string]string{
+				"client-secret": "secret-client",
+				"id-token":      "secret-id-token",
+				"refresh-token": "secret-refresh-token",
+			},
+		},
+		Exec: &config.ExecConfig{
+			Command:    "get-token",
+			APIVersion: "client.authentication.k8s.io/v1",
+			Env:        []config.ExecEnvVar{{Name: "API_KEY", Value: "secret-api-key"}},
+		},
+	}
+	opts := renderDryRunOptions{enabled: true, diff: true, selector: labels.Everything(), waitTimeout: time.Second}
+
+	var stdout bytes.Buffer
+	require.NoError(t, runRenderDryRun(context.Background(), []string{"work", "vgr"}, opts, &stdout))
+	require.Contains(t, stdout.String(), "client-secret: REDACTED")
+	require.Contains(t, stdout.String(), "name: API_KEY")
+	require.NotContains(t, stdout.String(), "secret-")
+}
+
+func TestRunRenderDryRunNoLoginUsesRenderedImports(t *testing.T) {
+	targetPath := filepath.Join(t.TempDir(), "vgr.yaml")
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	cfg = newImportedRenderCommandTestConfig(targetPath)
+	opts := renderDryRunOptions{enabled: true, all: true, noLogin: true, exitCode: true, selector: labels.Everything(), waitTimeout: time.Second}
+
+	var stdout bytes.Buffer
+	err := runRenderDryRun(context.Background(), nil, opts, &stdout)
+	require.ErrorContains(t, err, "has no imported config; run login first")
+
+	require.NoError(t, runRenderCmd(context.Background(), "work", "vgr", false, false, 5*time.Second))
+
+	require.NoError(t, runRenderDryRun(context.Background(), nil, opts, &stdout))
+	require.Contains(t, stdout.String(), "up to date")
+
+	cfg.Kubeconfigs["vgr"].Contexts["ctx1"].Namespace = "apps"
+	stdout.Reset()
+	opts.diff = true
+	err = runRenderDryRun(context.Background(), nil, opts, &stdout)
+	require.ErrorIs(t, err, errRenderChanged)
+	require.Contains(t, stdout.String(), "+    namespace: apps")
+}
+
+func TestRunRenderDryRunMergedWorkspace(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	cfg = newImportedRenderCommandTestConfig(filepath.Join(tmpDir, "vgr.yaml"))
+	cfg.Workspaces["work"].Render = config.WorkspaceRenderMerged
+	opts := renderDryRunOptions{enabled: true, noLogin: true, exitCode: true, selector: labels.Everything(), waitTimeout: time.Second}
+
+	require.NoError(t, runRenderCmd(context.Background(), "work", "", false, false, 5*time.Second))
+
+	var stdout bytes.Buffer
+	require.NoError(t, runRenderDryRun(context.Background(), []string{"work"}, opts, &stdout))
+	require.Contains(t, stdout.String(), "Rendered kubeconfigs are up to date")
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -13,7 +14,9 @@ import (
 	"github.com/amimof/kubecfg/pkg/config"
 	decryptpkg "github.com/amimof/kubecfg/pkg/decrypt"
 	fzf "github.com/junegunn/fzf/src"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/clientcmd/api"
 )
@@ -124,7 +127,7 @@ func TestRunRenderCmdFzfNoSelectionIsNoOp(t *testing.T) {
 	}
 
 	maxWait := time.Second * 30
-	err := runRenderCmdFzf(context.Background(), false, maxWait)
+	err := runRenderCmdFzf(context.Background(), false, false, maxWait)
 	require.NoError(t, err)
 
 	_, err = os.Stat(targetPath)
@@ -153,7 +156,7 @@ func TestRunRenderCmdFzfUpdatesActiveConfigSymlink(t *testing.T) {
 		return fzf.ExitOk, nil
 	}
 
-	err := runRenderCmdFzf(context.Background(), false, time.Second)
+	err := runRenderCmdFzf(context.Background(), false, false, time.Second)
 	require.NoError(t, err)
 
 	linkPath := filepath.Join(tmpDir, "config")
@@ -176,7 +179,7 @@ func TestRunRenderCmdDecryptsEncryptedTokenWithConfiguredIdentityFiles(t *testin
 	cfg = newEncryptedRenderCommandTestConfig(targetPath, encryptedToken)
 	cfg.IdentityFiles = []string{identityFile}
 
-	err := runRenderCmd(context.Background(), "work", "vgr", true, time.Second)
+	err := runRenderCmd(context.Background(), "work", "vgr", true, false, time.Second)
 	require.NoError(t, err)
 
 	contents, err := os.ReadFile(targetPath)
@@ -185,6 +188,161 @@ func TestRunRenderCmdDecryptsEncryptedTokenWithConfiguredIdentityFiles(t *testin
 	require.NotContains(t, string(contents), encryptedToken)
 }
 
+func TestRunRenderCmdResolvesAliasAndContext(t *testing.T) {
+	tmpDir := t.TempDir()
+	targetPath := filepath.Join(tmpDir, "target-kubeconfig.yaml")
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	cfg = newRenderCommandTestConfig(targetPath)
+	cfg.Kubeconfigs["vgr"].Aliases = []string{"prod-eu"}
+
+	err := runRenderCmd(context.Background(), "prod-eu/context", "", true, false, time.Second)
+	require.NoError(t, err)
+
+	loaded, err := clientcmd.LoadFromFile(targetPath)
+	require.NoError(t, err)
+	require.Equal(t, "context", loaded.CurrentContext)
+
+	linkedTo, err := os.Readlink(filepath.Join(tmpDir, "config"))
+	require.NoError(t, err)
+	require.Equal(t, targetPath, linkedTo)
+}
+
+func TestRunRenderCmdActivatesOnlyKubeconfigOfWorkspace(t *testing.T) {
+	tmpDir := t.TempDir()
+	targetPath := filepath.Join(tmpDir, "vgr.yaml")
+	configPath := filepath.Join(tmpDir, "config")
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	cfg = newRenderCommandTestConfig(targetPath)
+	cfg.Kubeconfigs["vgr"].Protected = true
+
+	stubConfirm(t, true, "n\n")
+	err := runRenderCmd(context.Background(), "work", "", true, false, time.Second)
+	require.ErrorIs(t, err, errNotConfirmed)
+	_, err = os.Stat(targetPath)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	stubConfirm(t, false, "")
+	require.NoError(t, runRenderCmd(context.Background(), "work", "", true, true, time.Second))
+	linkedTo, err := os.Readlink(configPath)
+	require.NoError(t, err)
+	require.Equal(t, targetPath, linkedTo)
+
+	// A workspace with several kubeconfigs leaves ~/.kube/config alone.
+	require.NoError(t, os.Remove(configPath))
+	other := *cfg.Kubeconfigs["vgr"]
+	other.Path = filepath.Join(tmpDir, "other.yaml")
+	cfg.Kubeconfigs["other"] = &other
+	cfg.Workspaces["work"].Kubeconfigs = append(cfg.Workspaces["work"].Kubeconfigs, "other")
+
+	require.NoError(t, runRenderCmd(context.Background(), "work", "", true, false, time.Second))
+	_, err = os.Lstat(configPath)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRunRenderCmdReportsAmbiguousRef(t *testing.T) {
+	targetPath := filepath.Join(t.TempDir(), "target-kubeconfig.yaml")
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	cfg = newRenderCommandTestConfig(targetPath)
+	other := *cfg.Kubeconfigs["vgr"]
+	other.Path = targetPath + ".other"
+	cfg.Kubeconfigs["vgr-other"] = &other
+	cfg.Workspaces["work"].Kubeconfigs = append(cfg.Workspaces["work"].Kubeconfigs, "vgr-other")
+
+	err := runRenderCmd(context.Background(), "vg", "", true, false, time.Second)
+	require.EqualError(t, err, `"vg" is ambiguous, it matches work/vgr, work/vgr-other`)
+	_, err = os.Stat(targetPath)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRunRenderCmdMergedWorkspace(t *testing.T) {
+	tmpDir := t.TempDir()
+	targetPath := filepath.Join(tmpDir, "vgr.yaml")
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	cfg = newRenderCommandTestConfig(targetPath)
+	other := *cfg.Kubeconfigs["vgr"]
+	other.Path = filepath.Join(tmpDir, "other.yaml")
+	other.Clusters = map[string]*config.Cluster{"cluster": {Server: "https://other.example.com"}}
+	cfg.Kubeconfigs["other"] = &other
+	cfg.Workspaces["work"].Kubeconfigs = append(cfg.Workspaces["work"].Kubeconfigs, "other")
+	cfg.Workspaces["work"].Render = config.WorkspaceRenderMerged
+
+	err := runUseRefCmd("work", false)
+	require.EqualError(t, err, "workspace work is not rendered, run kubecfg render work")
+
+	require.NoError(t, runRenderCmd(context.Background(), "work", "", true, false, time.Second))
+
+	// Only the merged file is written.
+	_, err = os.Stat(targetPath)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	mergedPath := filepath.Join(tmpDir, "work.yaml")
+	merged, err := clientcmd.LoadFromFile(mergedPath)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com", merged.Clusters["vgr/cluster"].Server)
+	require.Equal(t, "https://other.example.com", merged.Clusters["other/cluster"].Server)
+	require.Equal(t, "other/cluster", merged.Contexts["other/context"].Cluster)
+
+	require.NoError(t, runUseRefCmd("work", false))
+	linkedTo, err := os.Readlink(filepath.Join(tmpDir, "config"))
+	require.NoError(t, err)
+	require.Equal(t, mergedPath, linkedTo)
+}
+
+func TestRunUseRefCmdRejectsSeparateWorkspace(t *testing.T) {
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+	cfg = newRenderCommandTestConfig(filepath.Join(t.TempDir(), "vgr.yaml"))
+	cfg.Kubeconfigs["other"] = cfg.Kubeconfigs["vgr"]
+	cfg.Workspaces["work"].Kubeconfigs = append(cfg.Workspaces["work"].Kubeconfigs, "other")
+
+	err := runUseRefCmd("work", false)
+	require.EqualError(t, err, "workspace work is rendered as separate kubeconfigs, use one of them or set render: merged on the workspace")
+}
+
+func TestRunUseRefCmdDoesNotDecrypt(t *testing.T) {
+	tmpDir := t.TempDir()
+	targetPath := filepath.Join(tmpDir, "vgr.yaml")
+	identityFile, encryptedToken := writeAgeIdentityAndEncryptedToken(t, "use-token")
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	cfg = newEncryptedRenderCommandTestConfig(targetPath, encryptedToken)
+	cfg.IdentityFiles = []string{identityFile}
+	require.NoError(t, runRenderCmd(context.Background(), "work", "vgr", true, false, time.Second))
+	require.NoError(t, os.Remove(filepath.Join(tmpDir, "config")))
+
+	// use only switches the symlink, so it works without the identity.
+	cfg.IdentityFiles = nil
+	require.NoError(t, runUseRefCmd("vgr", false))
+	linkedTo, err := os.Readlink(filepath.Join(tmpDir, "config"))
+	require.NoError(t, err)
+	require.Equal(t, targetPath, linkedTo)
+}
+
 func TestRunRenderCmdFzfDecryptsEncryptedTokenWithConfiguredIdentityFiles(t *testing.T) {
 	t.Setenv("FZF_DEFAULT_OPTS", "")
 	t.Setenv("FZF_DEFAULT_OPTS_FILE", "")
@@ -209,7 +367,7 @@ func TestRunRenderCmdFzfDecryptsEncryptedTokenWithConfiguredIdentityFiles(t *tes
 		return fzf.ExitOk, nil
 	}
 
-	err := runRenderCmdFzf(context.Background(), true, time.Second)
+	err := runRenderCmdFzf(context.Background(), true, false, time.Second)
 	require.NoError(t, err)
 
 	contents, err := os.ReadFile(targetPath)
@@ -227,7 +385,7 @@ func TestRunRenderCmdImportsReferencedContext(t *testing.T) {
 
 	cfg = newImportedRenderCommandTestConfig(targetPath)
 
-	err := runRenderCmd(context.Background(), "work", "vgr", false, 5*time.Second)
+	err := runRenderCmd(context.Background(), "work", "vgr", false, false, 5*time.Second)
 	require.NoError(t, err)
 
 	loaded, err := clientcmd.LoadFromFile(targetPath)
@@ -239,6 +397,53 @@ func TestRunRenderCmdImportsReferencedContext(t *testing.T) {
 	require.Equal(t, "default", loaded.Contexts["ctx1"].Namespace)
 }
 
+func TestRunRenderAllRunsSharedLoginSourceOnce(t *testing.T) {
+	dir := t.TempDir()
+	countFile := filepath.Join(dir, "logins")
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	cfg = config.Config{
+		Version: "v1",
+		BaseDir: dir,
+		Workspaces: map[string]*config.Workspace{
+			"work": {Kubeconfigs: []string{"dev", "prod"}},
+		},
+		LoginSources: map[string]*config.LoginSource{
+			"sso": {
+				Command: os.Args[0],
+				Args:    []string{"-test.run=TestHelperProcessLoginCommand", "--"},
+				Env:     []string{"GO_WANT_HELPER_PROCESS=1", "LOGIN_COUNT_FILE=" + countFile},
+			},
+		},
+		Kubeconfigs: map[string]*config.Kubeconfig{},
+	}
+	for _, name := range []string{"dev", "prod"} {
+		cfg.Kubeconfigs[name] = &config.Kubeconfig{
+			Path: "@/" + name + ".yaml",
+			Contexts: map[string]*config.Context{
+				name: {ImportRef: config.ImportRef{LoginSourceName: "shared:sso", ContextName: "utbildning-dev"}},
+			},
+		}
+	}
+
+	err := runRenderAll(context.Background(), labels.Everything(), false, 5*time.Second)
+	require.NoError(t, err)
+
+	for _, name := range []string{"dev", "prod"} {
+		loaded, err := clientcmd.LoadFromFile(filepath.Join(dir, name+".yaml"))
+		require.NoError(t, err)
+		require.Equal(t, "imported-token", loaded.AuthInfos[loaded.Contexts[name].AuthInfo].Token)
+	}
+
+	runs, err := os.ReadFile(countFile)
+	require.NoError(t, err)
+	require.Equal(t, "login\n", string(runs))
+}
+
 func TestRunRenderCmdImportsImplicitClusterAndAuthInfo(t *testing.T) {
 	targetPath := filepath.Join(t.TempDir(), "target-kubeconfig.yaml")
 
@@ -250,7 +455,7 @@ func TestRunRenderCmdImportsImplicitClusterAndAuthInfo(t *testing.T) {
 	cfg = newImportedRenderCommandTestConfig(targetPath)
 	cfg.Kubeconfigs["vgr"].Contexts["ctx1"].ImportRef.AuthInfoName = ""
 
-	err := runRenderCmd(context.Background(), "work", "vgr", false, 5*time.Second)
+	err := runRenderCmd(context.Background(), "work", "vgr", false, false, 5*time.Second)
 	require.NoError(t, err)
 
 	loaded, err := clientcmd.LoadFromFile(targetPath)
@@ -270,7 +475,7 @@ func TestRunRenderCmdFailsWhenImportedContextIsMissing(t *testing.T) {
 	cfg = newImportedRenderCommandTestConfig(targetPath)
 	cfg.Kubeconfigs["vgr"].Contexts["ctx1"].ImportRef.ContextName = "missing"
 
-	err := runRenderCmd(context.Background(), "work", "vgr", false, 5*time.Second)
+	err := runRenderCmd(context.Background(), "work", "vgr", false, false, 5*time.Second)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), `kubeconfig "vgr" context "ctx1" imports missing context "missing" from login source "shared"`)
 }
@@ -286,7 +491,7 @@ func TestRunRenderCmdReturnsHelpfulErrorWhenLoginCommandCannotStart(t *testing.T
 	cfg = newImportedRenderCommandTestConfig(targetPath)
 	cfg.Kubeconfigs["vgr"].LoginSources["shared"].Command = filepath.Join(t.TempDir(), "missing-login-binary")
 
-	err := runRenderCmd(context.Background(), "work", "vgr", false, 5*time.Second)
+	err := runRenderCmd(context.Background(), "work", "vgr", false, false, 5*time.Second)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "login source \"shared\": run command")
 	require.Contains(t, err.Error(), "missing-login-binary")
@@ -303,7 +508,7 @@ func TestRunRenderCmdReturnsHelpfulErrorWhenGeneratedKubeconfigIsInvalid(t *test
 	cfg = newImportedRenderCommandTestConfig(targetPath)
 	cfg.Kubeconfigs["vgr"].LoginSources["shared"].Args = []string{"-test.run=TestHelperProcessInvalidLoginCommand", "--"}
 
-	err := runRenderCmd(context.Background(), "work", "vgr", false, 5*time.Second)
+	err := runRenderCmd(context.Background(), "work", "vgr", false, false, 5*time.Second)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "login source \"shared\": load generated kubeconfig")
 	require.Contains(t, err.Error(), "cannot unmarshal string")
@@ -336,6 +541,129 @@ func TestWriteKubeconfigSetsSecurePermissions(t *testing.T) {
 	})
 }
 
+func TestWriteKubeconfigKeepsBackups(t *testing.T) {
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	backups := 2
+	cfg = config.Config{Backups: &backups}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	kubeconfig := newWriteKubeconfigTestConfig()
+	write := func(server string) {
+		t.Helper()
+		kubeconfig.Clusters["cluster"].Server = server
+		require.NoError(t, writeKubeconfig(path, &kubeconfig))
+	}
+
+	write("https://a.example.com")
+	write("https://b.example.com")
+	// Writing the same kubeconfig again doesn't push out older backups.
+	write("https://b.example.com")
+	write("https://c.example.com")
+	write("https://d.example.com")
+
+	server := func(name string) string {
+		t.Helper()
+		loaded, err := clientcmd.LoadFromFile(name)
+		require.NoError(t, err)
+		return loaded.Clusters["cluster"].Server
+	}
+	require.Equal(t, "https://d.example.com", server(path))
+	require.Equal(t, "https://c.example.com", server(path+".bak.1"))
+	require.Equal(t, "https://b.example.com", server(path+".bak.2"))
+	_, err := os.Stat(path + ".bak.3")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestWriteKubeconfigRejectsInvalidKubeconfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+
+	kubeconfig := newWriteKubeconfigTestConfig()
+	require.NoError(t, writeKubeconfig(path, &kubeconfig))
+	before, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	kubeconfig.CurrentContext = "missing"
+	err = writeKubeconfig(path, &kubeconfig)
+	require.ErrorContains(t, err, "rendered kubeconfig "+path+" is invalid")
+
+	// The existing file is left alone and the temporary file is removed.
+	after, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestRunRenderCmdWritesFileReferencesAndExecUsers(t *testing.T) {
+	targetPath := filepath.Join(t.TempDir(), "vgr.yaml")
+	caPath := filepath.Join(t.TempDir(), "missing", "ca.pem")
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	cfg = newRenderCommandTestConfig(targetPath)
+	cfg.Kubeconfigs["vgr"].Clusters["cluster"].CertificateAuthority = caPath
+	cfg.Kubeconfigs["vgr"].AuthInfos["user"] = &config.AuthInfo{
+		ClientCertificate: filepath.Join(t.TempDir(), "missing", "client.pem"),
+		ClientKey:         filepath.Join(t.TempDir(), "missing", "client-key.pem"),
+		Exec:              &config.ExecConfig{Command: "kubelogin", APIVersion: "client.authentication.k8s.io/v1"},
+	}
+
+	require.NoError(t, runRenderCmd(context.Background(), "work", "vgr", true, false, time.Second))
+
+	// Missing files are kept, they may only be created later.
+	written, err := clientcmd.LoadFromFile(targetPath)
+	require.NoError(t, err)
+	require.Equal(t, caPath, written.Clusters["cluster"].CertificateAuthority)
+	require.Equal(t, api.IfAvailableExecInteractiveMode, written.AuthInfos["user"].Exec.InteractiveMode)
+}
+
+func TestWriteKubeconfigReplacesSymlinkTarget(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.yaml")
+	link := filepath.Join(dir, "link.yaml")
+	require.NoError(t, os.WriteFile(target, []byte("stale"), 0o600))
+	require.NoError(t, os.Symlink(target, link))
+
+	kubeconfig := newWriteKubeconfigTestConfig()
+	require.NoError(t, writeKubeconfig(link, &kubeconfig))
+
+	linkedTo, err := os.Readlink(link)
+	require.NoError(t, err)
+	require.Equal(t, target, linkedTo)
+	loaded, err := clientcmd.LoadFromFile(target)
+	require.NoError(t, err)
+	require.Equal(t, "context", loaded.CurrentContext)
+}
+
+func TestSetConfigReplacesExistingConfig(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config")
+	require.NoError(t, os.WriteFile(configPath, []byte("hand written"), 0o600))
+
+	for _, name := range []string{"a.yaml", "b.yaml"} {
+		target := filepath.Join(dir, name)
+		require.NoError(t, setConfig(dir, target))
+
+		linkedTo, err := os.Readlink(configPath)
+		require.NoError(t, err)
+		require.Equal(t, target, linkedTo)
+	}
+
+	// The temporary symlink is renamed into place.
+	matches, err := filepath.Glob(filepath.Join(dir, "config.tmp-*"))
+	require.NoError(t, err)
+	require.Empty(t, matches)
+}
+
 func filePerms(t *testing.T, path string) os.FileMode {
 	t.Helper()
 
@@ -450,3 +778,24 @@ func writeAgeIdentityAndEncryptedToken(t *testing.T, plaintext string) (string,
 
 	return identityFile, encrypted
 }
+
+func TestRenderCmdRejectsSelectorWithoutAll(t *testing.T) {
+	viper.SetConfigFile(filepath.Join(t.TempDir(), "kubecfg.yaml"))
+	t.Cleanup(viper.Reset)
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	for _, args := range [][]string{
+		{"work", "-l", "env=prod"},
+		{"work", "-l", "env=prod", "--dry-run"},
+	} {
+		cmd := newRenderCmd()
+		cmd.SetArgs(args)
+		cmd.SetOut(io.Discard)
+		cmd.SetErr(io.Discard)
+		require.EqualError(t, cmd.Execute(), "--selector requires --all")
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var schemaStdout io.Writer = os.Stdout
+
+func newSchemaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema for kubecfg.yaml",
+		Long: `Print a JSON Schema describing kubecfg.yaml. Point yaml-language-server at it to get
+completion and validation in editors.`,
+		Example:      `  kubecfg schema > ~/.config/kubecfg.schema.json`,
+		Args:         cobra.ExactArgs(0),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSchemaCmd(schemaStdout)
+		},
+	}
+
+	return cmd
+}
+
+func runSchemaCmd(w io.Writer) error {
+	schema, err := config.Schema()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(schema)
+	return err
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunSchemaCmdPrintsSchema(t *testing.T) {
+	var stdout bytes.Buffer
+	require.NoError(t, runSchemaCmd(&stdout))
+
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &schema))
+	require.Equal(t, config.SchemaID, schema["$id"])
+	require.Contains(t, schema["properties"], "kubeconfigs")
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// addSelectorFlag adds the -l flag used to filter kubeconfigs by their labels.
+func addSelectorFlag(cmd *cobra.Command, selector *string) {
+	cmd.PersistentFlags().StringVarP(selector, "selector", "l", "", "Only include kubeconfigs matching the label selector, for example env=prod,team=payments")
+}
+
+// parseSelector parses a label selector given with -l. An empty selector
+// matches everything.
+func parseSelector(s string) (labels.Selector, error) {
+	selector, err := labels.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %q: %w", s, err)
+	}
+	return selector, nil
+}
+
+// selectKubeconfigs returns the kubeconfigs whose labels match selector,
+// ordered by name.
+func selectKubeconfigs(kubeconfigs map[string]*config.RuntimeKubeconfig, selector labels.Selector) []*config.RuntimeKubeconfig {
+	var res []*config.RuntimeKubeconfig
+	for _, name := range slices.Sorted(maps.Keys(kubeconfigs)) {
+		if rk := kubeconfigs[name]; selector.Matches(labels.Set(rk.Labels)) {
+			res = append(res, rk)
+		}
+	}
+	return res
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/amimof/kubecfg/pkg/cmdutil"
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// envWorkspace and envKubeconfig are set in kubecfg shells and by
+	// kubecfg env to the workspace and kubeconfig in use.
+	envWorkspace  = "KUBECFG_WORKSPACE"
+	envKubeconfig = "KUBECFG_KUBECONFIG"
+)
+
+// shellWaitDelay is how long a shell gets to exit after the signal that
+// terminated kubecfg was forwarded to it, before it is killed.
+const shellWaitDelay = 5 * time.Second
+
+var (
+	errNestedShell = errors.New("nested kubecfg shell")
+
+	// runShell runs shell interactively with env and waits for it to exit.
+	// When ctx is done, the signal that terminated kubecfg is forwarded to
+	// the shell.
+	runShell = func(ctx context.Context, shell string, env []string) error {
+		cmd := exec.CommandContext(ctx, shell)
+		cmd.Env = env
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		cmd.Cancel = func() error {
+			var terminated *terminatedError
+			if errors.As(context.Cause(ctx), &terminated) {
+				return cmd.Process.Signal(terminated.signal)
+			}
+			return cmd.Process.Kill()
+		}
+		cmd.WaitDelay = shellWaitDelay
+
+		// The shell handles Ctrl-C itself, kubecfg waits for it to exit.
+		signal.Ignore(os.Interrupt)
+		defer signal.Reset(os.Interrupt)
+
+		err := cmd.Run()
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// The exit status is that of the last command run in the shell.
+			return nil
+		}
+		return err
+	}
+)
+
+// terminatedError is the cause of a shell session being cancelled because
+// kubecfg received signal.
+type terminatedError struct {
+	signal os.Signal
+}
+
+func (e *terminatedError) Error() string {
+	return "shell session ended: " + e.signal.String()
+}
+
+// cancelOnSignal returns a context that is cancelled with a terminatedError
+// when kubecfg receives SIGHUP, such as when the terminal is closed, or
+// SIGTERM. Call stop to restore the default handling.
+func cancelOnSignal(ctx context.Context) (_ context.Context, stop func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			cancel(&terminatedError{signal: sig})
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel(nil)
+	}
+}
+
+func newShellCmd() *cobra.Command {
+	var (
+		noLogin     bool
+		ephemeral   bool
+		yes         bool
+		waitTimeout time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "shell [REF]",
+		Short: "Start a shell with its own kubeconfig",
+		Long: `Render a kubeconfig and start $SHELL with KUBECONFIG pointing at a copy that
+only this shell uses. Other terminals and ~/.kube/config are left alone.
+
+KUBECFG_WORKSPACE and KUBECFG_KUBECONFIG are set in the shell. The copy is
+deleted when the shell exits. With --ephemeral, the kubeconfig is only rendered
+to a private temporary directory and never written to its path.
+
+Without REF, the kubeconfig is picked in a fuzzy finder.`,
+		Example: `  kubecfg shell
+  kubecfg shell prod-eu
+  kubecfg shell prod-eu/admin --ephemeral`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeWith(1, completeRefs),
+		SilenceUsage:      true,
+		RunE: withConfig(func(cmd *cobra.Command, args []string) error {
+			var ref string
+			if len(args) == 1 {
+				ref = args[0]
+			}
+			return runShellCmd(cmd.Context(), ref, noLogin, ephemeral, yes, waitTimeout)
+		}),
+	}
+
+	cmd.PersistentFlags().BoolVar(&noLogin, "no-login", false, "Skip execution of login flow prior to kubeconfig rendering")
+	cmd.PersistentFlags().BoolVar(&ephemeral, "ephemeral", false, "Render to a private temporary directory that is deleted when the shell exits")
+	addYesFlag(cmd, &yes)
+	cmd.PersistentFlags().DurationVar(&waitTimeout, "timeout", time.Second*30, "How long in seconds to wait for login opearation to finish before giving up")
+
+	return cmd
+}
+
+func runShellCmd(ctx context.Context, ref string, skipLogin, ephemeral, yes bool, waitTimeout time.Duration) error {
+	if current := os.Getenv(envKubeconfig); current != "" {
+		return fmt.Errorf("%w: %s is already set to %s, exit the kubecfg shell or run eval \"$(kubecfg env --unset)\" first", errNestedShell, envKubeconfig, current)
+	}
+
+	compiler, err := newCompilerWithOptionalDecryptor(&cfg, cfg.IdentityFiles)
+	if err != nil {
+		return err
+	}
+
+	runtime, err := compiler.Compile(&cfg)
+	if err != nil {
+		return err
+	}
+	refreshPromptCache(runtime)
+
+	resolved, err := resolveOrPick(runtime, ref)
+	if err != nil {
+		if errors.Is(err, errNoSelection) {
+			return nil
+		}
+		return err
+	}
+
+	rk := resolved.Kubeconfig
+	name := config.Ref{Workspace: resolved.Workspace, Kubeconfig: rk}.String()
+	if err := confirmProtected(rk, name, yes); err != nil {
+		return err
+	}
+	var contextName string
+	if resolved.Context != nil {
+		contextName = resolved.Context.Name
+	}
+
+	dir, err := os.MkdirTemp("", "kubecfg-shell-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := secureRemoveAll(dir); err != nil {
+			cmdutil.Printf(`{{ "✗" | FgRed }} Couldn't remove {{ .Dir }}: {{ .Error }}`, cmdutil.Data{"Dir": dir, "Error": err.Error()})
+		}
+	}()
+	if err := os.Chmod(dir, 0o700); err != nil {
+		return err
+	}
+
+	// The directory holds credentials, so it is removed when kubecfg is
+	// terminated as well.
+	ctx, stop := cancelOnSignal(ctx)
+	defer stop()
+
+	session := filepath.Join(dir, filepath.Base(rk.Path))
+	rendered := rk.Path
+	if ephemeral {
+		// Nothing else uses the kubeconfig, so the context is selected when
+		// rendering.
+		rk.Path = session
+		if contextName != "" {
+			rk.Config.CurrentContext = contextName
+		}
+	}
+
+	cmdutil.Println("Rendering kubeconfig\n")
+
+	if err := renderKubeconfigs(ctx, os.Stdout, []renderTask{{displayName: name, rk: rk}}, skipLogin, waitTimeout); err != nil {
+		return err
+	}
+
+	// Other terminals may use the rendered kubeconfig, so the context is
+	// only selected in the copy.
+	if !ephemeral {
+		if err := copyKubeconfig(rendered, session, contextName); err != nil {
+			return err
+		}
+	}
+
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+
+	env := append(os.Environ(),
+		"KUBECONFIG="+session,
+		envWorkspace+"="+workspaceDisplayName(resolved.Workspace),
+		envKubeconfig+"="+rk.Name,
+	)
+
+	cmdutil.Printf(`{{ "✔" | FgGreen }} Starting {{ .Shell }} with kubeconfig {{ .Name | FgCyan }}, exit to return`, cmdutil.Data{"Shell": shell, "Name": name})
+
+	return runShell(ctx, shell, env)
+}
+
+// copyKubeconfig copies the rendered kubeconfig at src to dst, readable by the
+// user only. Unless contextName is empty, it becomes the current context of
+// the copy.
+func copyKubeconfig(src, dst, contextName string) error {
+	if contextName == "" {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(dst, data, 0o600)
+	}
+
+	kc, err := clientcmd.LoadFromFile(src)
+	if err != nil {
+		return err
+	}
+	kc.CurrentContext = contextName
+	return clientcmd.WriteToFile(*kc, dst)
+}
+
+// secureRemoveAll overwrites every file in dir with zeros before removing it,
+// since they hold credentials.
+func secureRemoveAll(dir string) error {
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		return zeroFile(path)
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+func zeroFile(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(make([]byte, info.Size())); err != nil {
+		return err
+	}
+	return f.Sync()
+}
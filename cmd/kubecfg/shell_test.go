@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// stubShell replaces runShell with one that records the environment and the
+// kubeconfig the shell would see.
+func stubShell(t *testing.T) *map[string]string {
+	t.Helper()
+
+	originalRunShell := runShell
+	t.Cleanup(func() {
+		runShell = originalRunShell
+	})
+
+	env := map[string]string{}
+	runShell = func(ctx context.Context, shell string, environ []string) error {
+		for _, kv := range environ {
+			k, v, _ := strings.Cut(kv, "=")
+			env[k] = v
+		}
+		loaded, err := clientcmd.LoadFromFile(env["KUBECONFIG"])
+		require.NoError(t, err)
+		env["current-context"] = loaded.CurrentContext
+
+		info, err := os.Stat(filepath.Dir(env["KUBECONFIG"]))
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+		return nil
+	}
+	return &env
+}
+
+func TestRunShellCmd(t *testing.T) {
+	t.Setenv(envKubeconfig, "")
+	targetPath := filepath.Join(t.TempDir(), "vgr.yaml")
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+	cfg = newRenderCommandTestConfig(targetPath)
+	env := stubShell(t)
+
+	err := runShellCmd(context.Background(), "vgr/context", true, false, false, time.Second)
+	require.NoError(t, err)
+
+	require.Equal(t, "work", (*env)[envWorkspace])
+	require.Equal(t, "vgr", (*env)[envKubeconfig])
+	require.Equal(t, "context", (*env)["current-context"])
+	require.NotEqual(t, targetPath, (*env)["KUBECONFIG"])
+
+	// The session copy is removed, the rendered kubeconfig is kept.
+	_, err = os.Stat(filepath.Dir((*env)["KUBECONFIG"]))
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat(targetPath)
+	require.NoError(t, err)
+
+	// The global symlink is left alone.
+	_, err = os.Lstat(filepath.Join(filepath.Dir(targetPath), "config"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRunShellCmdSelectsContextInSessionCopy(t *testing.T) {
+	t.Setenv(envKubeconfig, "")
+	targetPath := filepath.Join(t.TempDir(), "vgr.yaml")
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+	cfg = newRenderCommandTestConfig(targetPath)
+	cfg.Kubeconfigs["vgr"].Contexts["other"] = &config.Context{Cluster: "cluster", AuthInfo: "user"}
+	require.NoError(t, runRenderCmd(context.Background(), "work", "vgr", true, false, time.Second))
+	rendered, err := os.ReadFile(targetPath)
+	require.NoError(t, err)
+	env := stubShell(t)
+
+	require.NoError(t, runShellCmd(context.Background(), "vgr/other", true, false, false, time.Second))
+	require.Equal(t, "other", (*env)["current-context"])
+
+	// Other terminals using the rendered kubeconfig are not affected.
+	again, err := os.ReadFile(targetPath)
+	require.NoError(t, err)
+	require.Equal(t, string(rendered), string(again))
+}
+
+func TestRunShellCmdEphemeral(t *testing.T) {
+	t.Setenv(envKubeconfig, "")
+	targetPath := filepath.Join(t.TempDir(), "vgr.yaml")
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+	cfg = newRenderCommandTestConfig(targetPath)
+	env := stubShell(t)
+
+	err := runShellCmd(context.Background(), "vgr", true, true, false, time.Second)
+	require.NoError(t, err)
+
+	_, err = os.Stat((*env)["KUBECONFIG"])
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat(targetPath)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRunShellCmdDetectsNestedShell(t *testing.T) {
+	t.Setenv(envKubeconfig, "vgr")
+
+	err := runShellCmd(context.Background(), "vgr", true, false, false, time.Second)
+	require.ErrorIs(t, err, errNestedShell)
+	require.EqualError(t, err, `nested kubecfg shell: KUBECFG_KUBECONFIG is already set to vgr, exit the kubecfg shell or run eval "$(kubecfg env --unset)" first`)
+}
+
+func TestSecureRemoveAll(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config"), []byte("token: secret"), 0o600))
+
+	require.NoError(t, secureRemoveAll(dir))
+	_, err := os.Stat(dir)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,55 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunShellCmdRemovesSessionOnSIGTERM(t *testing.T) {
+	t.Setenv(envKubeconfig, "")
+	tmpDir := t.TempDir()
+	targetPath := filepath.Join(tmpDir, "vgr.yaml")
+
+	// The shell records its KUBECONFIG and keeps running until it is
+	// signalled.
+	marker := filepath.Join(tmpDir, "started")
+	shell := filepath.Join(tmpDir, "shell.sh")
+	require.NoError(t, os.WriteFile(shell, []byte("#!/bin/sh\necho \"$KUBECONFIG\" > "+marker+".tmp\nmv "+marker+".tmp "+marker+"\nexec sleep 30\n"), 0o700))
+	t.Setenv("SHELL", shell)
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+	cfg = newRenderCommandTestConfig(targetPath)
+
+	go func() {
+		for {
+			if _, err := os.Stat(marker); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		_ = syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	}()
+
+	start := time.Now()
+	err := runShellCmd(context.Background(), "vgr", true, true, false, time.Second)
+	var terminated *terminatedError
+	require.ErrorAs(t, err, &terminated)
+	require.Equal(t, syscall.SIGTERM, terminated.signal)
+	require.Less(t, time.Since(start), shellWaitDelay)
+
+	session, err := os.ReadFile(marker)
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Dir(string(session[:len(session)-1])))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,458 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amimof/kubecfg/pkg/cmdutil"
+	"github.com/amimof/kubecfg/pkg/cmdutil/table"
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+var splitStdout io.Writer = os.Stdout
+
+type splitOptions struct {
+	regex      *regexp.Regexp
+	dryRun     bool
+	configFile string
+}
+
+// splitGroup is a set of contexts of the split file that become one
+// kubeconfig.
+type splitGroup struct {
+	Name      string
+	Server    string
+	Contexts  []string
+	Workspace string
+
+	// Path is where the kubeconfig of the group is rendered.
+	Path string
+
+	// Existing is true if the kubeconfig is already defined by an earlier
+	// split.
+	Existing bool
+}
+
+func newSplitCmd() *cobra.Command {
+	var (
+		opts  splitOptions
+		regex string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "split [FILE]",
+		Short: "Split a merged kubeconfig into kubeconfigs managed by kubecfg",
+		Long: `Split a kubeconfig with many contexts, such as the ~/.kube/config built up by
+cloud CLIs, into kubeconfigs in kubecfg.yaml. Contexts are grouped by the server
+of their cluster, or with --regex by the first capture group of a regular
+expression matched against the context name. Each group is written to
+kubecfg.yaml and rendered to @/<name>.yaml.
+
+FILE defaults to the config file in base_dir. That file is then replaced with the
+symlink render and use manage, pointing at the kubeconfig of the current context,
+and the original is kept with a .bak suffix. Workspaces are suggested for
+kubeconfigs that look like they come from a cloud provider or a local cluster.
+
+Running split again is safe: a file that is already a symlink is left alone, and
+groups that were split before are not added twice.`,
+		Example: `  kubecfg split --dry-run
+  kubecfg split
+  kubecfg split --regex '^(prod|dev)-'
+  kubecfg split ~/Downloads/merged.yaml`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: withConfig(func(cmd *cobra.Command, args []string) error {
+			if regex != "" {
+				re, err := regexp.Compile(regex)
+				if err != nil {
+					return fmt.Errorf("--regex: %w", err)
+				}
+				opts.regex = re
+			}
+			opts.configFile = configFile
+
+			var file string
+			if len(args) == 1 {
+				file = args[0]
+			}
+			return runSplitCmd(file, opts, splitStdout)
+		}),
+	}
+
+	cmd.Flags().StringVar(&regex, "regex", "", "Group contexts by the first capture group, or the match, of this regular expression")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Print where contexts would go and the YAML that would be added without writing anything")
+
+	return cmd
+}
+
+func runSplitCmd(file string, opts splitOptions, stdout io.Writer) error {
+	runtime, err := config.NewCompiler(config.WithoutDecryption()).Compile(&cfg)
+	if err != nil {
+		return err
+	}
+
+	managed := filepath.Join(runtime.BaseDir, "config")
+	if file == "" {
+		file = managed
+	}
+
+	info, err := os.Lstat(file)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		cmdutil.Fprintf(stdout, `{{ "✔" | FgGreen }} {{ .File }} is already a symlink managed by kubecfg, nothing to split`, cmdutil.Data{"File": file})
+		return nil
+	}
+
+	kc, err := clientcmd.LoadFromFile(file)
+	if err != nil {
+		return fmt.Errorf("load %s: %w", file, err)
+	}
+	if len(kc.Contexts) == 0 {
+		return fmt.Errorf("%s has no contexts", file)
+	}
+
+	doc, err := readConfigDocument(opts.configFile)
+	if err != nil {
+		return err
+	}
+
+	groups := groupContexts(kc, opts.regex)
+	taken := takenKubeconfigNames(doc)
+	entries := make(map[string]*config.Kubeconfig, len(groups))
+	for _, g := range groups {
+		// Names of groups may have been changed to avoid clashes, so groups
+		// split before are recognized by their contexts.
+		if name := splitKubeconfigName(cfg.Kubeconfigs, kc, g); name != "" {
+			g.Name, g.Path, g.Existing = name, config.ResolvePath(runtime.BaseDir, cfg.Kubeconfigs[name].Path), true
+			continue
+		}
+
+		base := g.Name
+		for taken[g.Name] || fileExists(config.ResolvePath(runtime.BaseDir, "@/"+g.Name+".yaml")) {
+			taken[g.Name] = true
+			g.Name = uniqueName(base, taken)
+		}
+		taken[g.Name] = true
+
+		entry := config.FromAPIConfig(subsetConfig(kc, g.Contexts))
+		entry.Path = "@/" + g.Name + ".yaml"
+		entries[g.Name] = entry
+		g.Path = config.ResolvePath(runtime.BaseDir, entry.Path)
+	}
+
+	if err := writeSplitReport(stdout, groups); err != nil {
+		return err
+	}
+
+	suggested := config.Config{Workspaces: suggestedWorkspaces(groups)}
+	if len(suggested.Workspaces) > 0 {
+		out, err := suggested.Marshal()
+		if err != nil {
+			return err
+		}
+		cmdutil.Fprintf(stdout, "\nSuggested workspaces, add them to {{ .File }} to use them:", cmdutil.Data{"File": opts.configFile})
+		if _, err := stdout.Write(out); err != nil {
+			return err
+		}
+	}
+
+	// The managed symlink points at the kubeconfig of the current context.
+	var active string
+	for _, g := range groups {
+		if active == "" || slices.Contains(g.Contexts, kc.CurrentContext) {
+			active = g.Path
+		}
+	}
+
+	if opts.dryRun {
+		if len(entries) > 0 {
+			out, err := (&config.Config{Kubeconfigs: entries}).Marshal()
+			if err != nil {
+				return err
+			}
+			cmdutil.Fprintf(stdout, "\nWould add to {{ .File }}:", cmdutil.Data{"File": opts.configFile})
+			if _, err := stdout.Write(out); err != nil {
+				return err
+			}
+		}
+		if file == managed {
+			cmdutil.Fprintf(stdout, "\nWould replace {{ .File }} with a symlink to {{ .Active }}", cmdutil.Data{"File": file, "Active": active})
+		}
+		return nil
+	}
+
+	fmt.Fprintln(stdout)
+
+	for _, g := range groups {
+		if g.Existing {
+			continue
+		}
+		node, err := config.Encode(entries[g.Name])
+		if err != nil {
+			return err
+		}
+		if err := doc.SetKey("kubeconfigs", g.Name, node); err != nil {
+			return err
+		}
+	}
+	if len(entries) > 0 {
+		if err := writeConfigDocument(opts.configFile, doc); err != nil {
+			return err
+		}
+		cmdutil.Fprintf(stdout, `{{ "✔" | FgGreen }} Added {{ .Count }} kubeconfigs to {{ .File }}`, cmdutil.Data{"Count": len(entries), "File": opts.configFile})
+	}
+
+	for _, g := range groups {
+		if err := writeSplitKubeconfig(g.Path, subsetConfig(kc, g.Contexts), g.Existing); err != nil {
+			return err
+		}
+	}
+
+	if file != managed {
+		return nil
+	}
+
+	backup := file + ".bak"
+	if fileExists(backup) {
+		backup = file + ".bak." + strconv.FormatInt(time.Now().Unix(), 10)
+	}
+	if err := os.Rename(file, backup); err != nil {
+		return fmt.Errorf("back up %s: %w", file, err)
+	}
+	if err := setConfig(runtime.BaseDir, active); err != nil {
+		return err
+	}
+	cmdutil.Fprintf(stdout, `{{ "✔" | FgGreen }} Replaced {{ .File }} with a symlink to {{ .Active | FgCyan }} {{ printf "(backup: %s)" .Backup | FgHiBlack }}`, cmdutil.Data{
+		"File":   file,
+		"Active": active,
+		"Backup": backup,
+	})
+
+	return nil
+}
+
+// groupContexts groups the contexts of kc by the server of their cluster, or
+// by re if it matches the context name. Groups are sorted by name.
+func groupContexts(kc *api.Config, re *regexp.Regexp) []*splitGroup {
+	byKey := make(map[string]*splitGroup)
+	for _, name := range sortedNames(kc.Contexts) {
+		ctx := kc.Contexts[name]
+		server := contextServer(kc, name)
+
+		key, groupName := "server:"+server, serverGroupName(server, ctx.Cluster)
+		if re != nil {
+			if m := re.FindStringSubmatch(name); m != nil {
+				match := m[0]
+				if len(m) > 1 && m[1] != "" {
+					match = m[1]
+				}
+				key, groupName = "regex:"+match, sanitizeName(match)
+			}
+		}
+
+		g, ok := byKey[key]
+		if !ok {
+			g = &splitGroup{Name: groupName, Server: server, Workspace: suggestWorkspace(name, server)}
+			byKey[key] = g
+		}
+		g.Contexts = append(g.Contexts, name)
+	}
+
+	groups := make([]*splitGroup, 0, len(byKey))
+	for _, key := range sortedNames(byKey) {
+		groups = append(groups, byKey[key])
+	}
+	slices.SortStableFunc(groups, func(a, b *splitGroup) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	// Different servers can share a cluster name.
+	seen := make(map[string]bool)
+	for _, g := range groups {
+		g.Name = uniqueName(g.Name, seen)
+		seen[g.Name] = true
+	}
+	return groups
+}
+
+// contextServer returns the server of the cluster of the context called name
+// in kc, or an empty string if the cluster isn't defined.
+func contextServer(kc *api.Config, name string) string {
+	if cluster, ok := kc.Clusters[kc.Contexts[name].Cluster]; ok {
+		return cluster.Server
+	}
+	return ""
+}
+
+// splitKubeconfigName returns the name of the kubeconfig among kubeconfigs
+// that has exactly the contexts of g, with the same servers as in kc. Returns
+// an empty string if there is none.
+func splitKubeconfigName(kubeconfigs map[string]*config.Kubeconfig, kc *api.Config, g *splitGroup) string {
+	for _, name := range sortedNames(kubeconfigs) {
+		k := kubeconfigs[name]
+		if k == nil || len(k.Contexts) != len(g.Contexts) {
+			continue
+		}
+		matches := true
+		for _, ctxName := range g.Contexts {
+			ctx, ok := k.Contexts[ctxName]
+			if !ok {
+				matches = false
+				break
+			}
+			cluster, ok := k.Clusters[ctx.Cluster]
+			if !ok || cluster == nil || cluster.Server != contextServer(kc, ctxName) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return name
+		}
+	}
+	return ""
+}
+
+// writeSplitKubeconfig writes kubeconfig to path, unless the group it was split
+// into already existed and has been rendered since. The file is locked against
+// other kubecfg processes rendering it.
+func writeSplitKubeconfig(path string, kubeconfig *api.Config, existing bool) error {
+	lock, err := lockPath(context.Background(), path, printWaiting)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	if existing && fileExists(path) {
+		return nil
+	}
+	return writeKubeconfig(path, kubeconfig)
+}
+
+// subsetConfig returns the contexts of kc named in contexts, along with the
+// clusters and users they reference.
+func subsetConfig(kc *api.Config, contexts []string) *api.Config {
+	out := api.NewConfig()
+	for _, name := range contexts {
+		ctx := kc.Contexts[name]
+		out.Contexts[name] = ctx
+		if cluster, ok := kc.Clusters[ctx.Cluster]; ok {
+			out.Clusters[ctx.Cluster] = cluster
+		}
+		if authInfo, ok := kc.AuthInfos[ctx.AuthInfo]; ok {
+			out.AuthInfos[ctx.AuthInfo] = authInfo
+		}
+	}
+
+	out.CurrentContext = contexts[0]
+	if slices.Contains(contexts, kc.CurrentContext) {
+		out.CurrentContext = kc.CurrentContext
+	}
+	return out
+}
+
+// serverGroupName names the group of a server after the cluster, since names
+// written by cloud CLIs tend to end with the name of the cluster, and falls
+// back to the host of the server.
+func serverGroupName(server, cluster string) string {
+	if i := strings.LastIndexAny(cluster, "/:"); i >= 0 {
+		cluster = cluster[i+1:]
+	}
+	if name := sanitizeName(cluster); name != "" {
+		return name
+	}
+	if u, err := url.Parse(server); err == nil && u.Hostname() != "" {
+		return sanitizeName(u.Hostname())
+	}
+	return "default"
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// sanitizeName turns s into a kubeconfig name that is also a safe file name.
+func sanitizeName(s string) string {
+	return strings.Trim(unsafeNameChars.ReplaceAllString(strings.ToLower(s), "-"), "-_")
+}
+
+// suggestWorkspace suggests a workspace for a context based on the naming
+// conventions of cloud CLIs and local cluster tools. Returns an empty string
+// if there is no suggestion.
+func suggestWorkspace(context, server string) string {
+	var host string
+	if u, err := url.Parse(server); err == nil {
+		host = u.Hostname()
+	}
+
+	switch {
+	case strings.HasPrefix(context, "arn:aws:eks:") || strings.HasSuffix(host, ".eks.amazonaws.com"):
+		return "aws"
+	case strings.HasPrefix(context, "gke_"):
+		return "gcp"
+	case strings.HasSuffix(host, ".azmk8s.io"):
+		return "azure"
+	case host == "localhost" || host == "127.0.0.1" || host == "::1" ||
+		strings.HasPrefix(context, "kind-") || context == "minikube" ||
+		context == "docker-desktop" || context == "rancher-desktop":
+		return "local"
+	}
+	return ""
+}
+
+// suggestedWorkspaces returns the workspaces suggested for groups.
+func suggestedWorkspaces(groups []*splitGroup) map[string]*config.Workspace {
+	workspaces := make(map[string]*config.Workspace)
+	for _, g := range groups {
+		if g.Workspace == "" {
+			continue
+		}
+		ws, ok := workspaces[g.Workspace]
+		if !ok {
+			ws = &config.Workspace{}
+			workspaces[g.Workspace] = ws
+		}
+		ws.Kubeconfigs = append(ws.Kubeconfigs, g.Name)
+	}
+	return workspaces
+}
+
+func writeSplitReport(w io.Writer, groups []*splitGroup) error {
+	tbl := table.NewTable([]table.Column{
+		{Header: "CONTEXT"},
+		{Header: "KUBECONFIG"},
+		{Header: "SERVER"},
+		{Header: "WORKSPACE"},
+	})
+	for _, g := range groups {
+		name := g.Name
+		if g.Existing {
+			name += " (exists)"
+		}
+		for _, ctx := range g.Contexts {
+			if err := tbl.AddRow(ctx, name, g.Server, g.Workspace); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := tbl.WriteTo(w)
+	return err
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return !errors.Is(err, os.ErrNotExist)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const splitTestKubeconfigYAML = `apiVersion: v1
+kind: Config
+current-context: kind-dev
+clusters:
+  - name: arn:aws:eks:eu-west-1:123456789012:cluster/prod
+    cluster:
+      server: https://ABC.gr7.eu-west-1.eks.amazonaws.com
+  - name: kind-dev
+    cluster:
+      server: https://127.0.0.1:6443
+users:
+  - name: aws-admin
+    user:
+      token: aws
+  - name: kind-dev
+    user:
+      token: kind
+contexts:
+  - name: arn:aws:eks:eu-west-1:123456789012:cluster/prod
+    context:
+      cluster: arn:aws:eks:eu-west-1:123456789012:cluster/prod
+      user: aws-admin
+  - name: prod-readonly
+    context:
+      cluster: arn:aws:eks:eu-west-1:123456789012:cluster/prod
+      user: aws-admin
+      namespace: apps
+  - name: kind-dev
+    context:
+      cluster: kind-dev
+      user: kind-dev
+`
+
+func setupSplitTest(t *testing.T) (string, string) {
+	t.Helper()
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	dir := t.TempDir()
+	baseDir := filepath.Join(dir, "kube")
+	require.NoError(t, os.Mkdir(baseDir, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(baseDir, "config"), []byte(splitTestKubeconfigYAML), 0o600))
+
+	cfg = config.Config{Version: "v1", BaseDir: baseDir, Kubeconfigs: map[string]*config.Kubeconfig{}}
+	configPath := filepath.Join(dir, "kubecfg.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte("# my config\nversion: v1\nbase_dir: "+baseDir+"\n"), 0o600))
+	return configPath, baseDir
+}
+
+func TestRunSplitCmd(t *testing.T) {
+	configPath, baseDir := setupSplitTest(t)
+
+	var stdout bytes.Buffer
+	require.NoError(t, runSplitCmd("", splitOptions{configFile: configPath}, &stdout))
+
+	out := stdout.String()
+	require.Regexp(t, `prod-readonly\s+prod\s+https://ABC.gr7.eu-west-1.eks.amazonaws.com\s+aws`, out)
+	require.Regexp(t, `kind-dev\s+kind-dev\s+https://127.0.0.1:6443\s+local`, out)
+	require.Contains(t, out, "workspaces:\n  aws:\n    kubeconfigs:\n      - prod\n  local:\n    kubeconfigs:\n      - kind-dev\n")
+	require.Contains(t, out, "Added 2 kubeconfigs to "+configPath)
+
+	contents, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	require.Contains(t, string(contents), "# my config\n")
+	require.Contains(t, string(contents), "  prod:\n    path: '@/prod.yaml'\n")
+
+	prod, err := clientcmd.LoadFromFile(filepath.Join(baseDir, "prod.yaml"))
+	require.NoError(t, err)
+	require.Len(t, prod.Contexts, 2)
+	require.Len(t, prod.AuthInfos, 1)
+
+	target, err := os.Readlink(filepath.Join(baseDir, "config"))
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(baseDir, "kind-dev.yaml"), target)
+	backup, err := os.ReadFile(filepath.Join(baseDir, "config.bak"))
+	require.NoError(t, err)
+	require.Equal(t, splitTestKubeconfigYAML, string(backup))
+
+	// Splitting again is a no-op.
+	stdout.Reset()
+	require.NoError(t, runSplitCmd("", splitOptions{configFile: configPath}, &stdout))
+	require.Contains(t, stdout.String(), "is already a symlink managed by kubecfg")
+	again, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	require.Equal(t, string(contents), string(again))
+}
+
+func TestRunSplitCmdSkipsExistingKubeconfigs(t *testing.T) {
+	configPath, baseDir := setupSplitTest(t)
+	file := filepath.Join(baseDir, "config")
+	kc, err := clientcmd.LoadFromFile(file)
+	require.NoError(t, err)
+	cfg.Kubeconfigs["prod"] = config.FromAPIConfig(subsetConfig(kc, []string{"arn:aws:eks:eu-west-1:123456789012:cluster/prod", "prod-readonly"}))
+	cfg.Kubeconfigs["prod"].Path = "@/prod.yaml"
+	cfg.Kubeconfigs["kind-dev"] = &config.Kubeconfig{Path: "/elsewhere/kind.yaml"}
+
+	var stdout bytes.Buffer
+	require.NoError(t, runSplitCmd(file, splitOptions{configFile: configPath, dryRun: true}, &stdout))
+
+	out := stdout.String()
+	require.Regexp(t, `prod-readonly\s+prod \(exists\)`, out)
+	require.Regexp(t, `kind-dev\s+kind-dev-2\s`, out)
+	require.Contains(t, out, "Would add to "+configPath+":\nkubeconfigs:\n  kind-dev-2:\n")
+	require.NotContains(t, out, "  prod:\n")
+	require.Contains(t, out, "Would replace "+file+" with a symlink to "+filepath.Join(baseDir, "kind-dev-2.yaml"))
+
+	_, err = os.Lstat(filepath.Join(baseDir, "config.bak"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRunSplitCmdTwiceWithNameClash(t *testing.T) {
+	configPath, baseDir := setupSplitTest(t)
+	file := filepath.Join(t.TempDir(), "merged.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(splitTestKubeconfigYAML), 0o600))
+	require.NoError(t, os.WriteFile(configPath, []byte("version: v1\nbase_dir: "+baseDir+"\nkubeconfigs:\n  prod:\n    path: /elsewhere/prod.yaml\n"), 0o600))
+
+	split := func() string {
+		t.Helper()
+		loaded, err := config.LoadFile(configPath)
+		require.NoError(t, err)
+		cfg = *loaded
+
+		var stdout bytes.Buffer
+		require.NoError(t, runSplitCmd(file, splitOptions{configFile: configPath}, &stdout))
+		return stdout.String()
+	}
+
+	out := split()
+	require.Regexp(t, `prod-readonly\s+prod-2\s`, out)
+	require.Contains(t, out, "Added 2 kubeconfigs to "+configPath)
+	contents, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+
+	// The renamed group is recognized and not added again.
+	out = split()
+	require.Regexp(t, `prod-readonly\s+prod-2 \(exists\)`, out)
+	require.Regexp(t, `kind-dev\s+kind-dev \(exists\)`, out)
+	require.NotContains(t, out, "Added")
+	again, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	require.Equal(t, string(contents), string(again))
+	require.NoFileExists(t, filepath.Join(baseDir, "prod-3.yaml"))
+}
+
+func TestGroupContextsByRegex(t *testing.T) {
+	kc, err := clientcmd.Load([]byte(splitTestKubeconfigYAML))
+	require.NoError(t, err)
+
+	groups := groupContexts(kc, regexp.MustCompile(`^(prod)-`))
+	require.Len(t, groups, 3)
+	require.Equal(t, "kind-dev", groups[0].Name)
+	require.Equal(t, "prod", groups[1].Name)
+	require.Equal(t, []string{"prod-readonly"}, groups[1].Contexts)
+	require.Equal(t, "prod-2", groups[2].Name)
+	require.Equal(t, []string{"arn:aws:eks:eu-west-1:123456789012:cluster/prod"}, groups[2].Contexts)
+}
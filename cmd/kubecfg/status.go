@@ -0,0 +1,329 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/amimof/kubecfg/pkg/cmdutil/table"
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+var (
+	statusStdout io.Writer = os.Stdout
+	statusNow              = time.Now
+)
+
+// Values of kubeconfigStatus.Status.
+const (
+	statusOK      = "ok"
+	statusMissing = "missing"
+	statusDrifted = "drifted"
+)
+
+// kubeconfigStatus is how a rendered kubeconfig file compares to the config.
+type kubeconfigStatus struct {
+	Name     string   `json:"name"`
+	Path     string   `json:"path"`
+	Active   bool     `json:"active"`
+	Status   string   `json:"status"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+type statusOptions struct {
+	output   string
+	selector string
+}
+
+func newStatusCmd() *cobra.Command {
+	var opts statusOptions
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Compare rendered kubeconfigs with the config",
+		Long: `Compare the kubeconfig files that kubecfg render writes with the config and
+report files that are missing, have been edited by hand, hold expired imported
+credentials or have the wrong permissions. The kubeconfig that ~/.kube/config
+points to is marked as active.
+
+Namespaces and current contexts are not compared, since kubecfg ns and kubecfg
+context change them. Run kubecfg render to bring drifted files back in sync.`,
+		Example: `  kubecfg status
+  kubecfg status -l env=prod
+  kubecfg status -o json`,
+		Args:         cobra.ExactArgs(0),
+		SilenceUsage: true,
+		RunE: withConfig(func(cmd *cobra.Command, args []string) error {
+			return runStatusCmd(statusStdout, opts)
+		}),
+	}
+
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "text", "Output format (text, json)")
+	addSelectorFlag(cmd, &opts.selector)
+
+	return cmd
+}
+
+func runStatusCmd(stdout io.Writer, opts statusOptions) error {
+	if opts.output != "text" && opts.output != "json" {
+		return fmt.Errorf("expected output to be one of [text json]")
+	}
+	selector, err := parseSelector(opts.selector)
+	if err != nil {
+		return err
+	}
+
+	rc, err := config.NewCompiler(config.WithoutDecryption()).Compile(&cfg)
+	if err != nil {
+		return err
+	}
+
+	statuses, err := collectStatuses(rc, selector)
+	if err != nil {
+		return err
+	}
+
+	if opts.output == "json" {
+		b, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(stdout, string(b))
+		return err
+	}
+
+	tbl := table.NewTable([]table.Column{
+		{Header: "NAME"},
+		{Header: "ACTIVE"},
+		{Header: "STATUS"},
+		{Header: "PATH"},
+		{Header: "PROBLEMS"},
+	})
+	for _, s := range statuses {
+		active := ""
+		if s.Active {
+			active = "*"
+		}
+		if err := tbl.AddRow(s.Name, active, s.Status, s.Path, strings.Join(s.Problems, "; ")); err != nil {
+			return err
+		}
+	}
+	_, err = tbl.WriteTo(stdout)
+	return err
+}
+
+// collectStatuses returns the status of every file that kubecfg render --all
+// writes for the kubeconfigs matching selector, in the same order.
+func collectStatuses(rc *config.RuntimeConfig, selector labels.Selector) ([]kubeconfigStatus, error) {
+	active, err := activeKubeconfigPath(rc.BaseDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	tasks, merged := allRenderTasks(rc, selector)
+
+	var statuses []kubeconfigStatus
+	for _, t := range tasks {
+		if t.merged {
+			continue
+		}
+		statuses = append(statuses, kubeconfigFileStatus(statusName(rc, t), t.rk.Path, active, func(rendered *api.Config) (*api.Config, []string) {
+			return expectedKubeconfig(t.rk, rendered, "")
+		}))
+	}
+	for _, rw := range merged {
+		statuses = append(statuses, kubeconfigFileStatus(rw.Name, rw.Path, active, func(rendered *api.Config) (*api.Config, []string) {
+			var problems []string
+			for _, name := range sortedNames(rw.Kubeconfigs) {
+				_, p := expectedKubeconfig(rw.Kubeconfigs[name], config.Unmerge(rendered, name), name)
+				problems = append(problems, p...)
+			}
+			return rw.Merge(), problems
+		}))
+	}
+	return statuses, nil
+}
+
+// statusName returns the name t is listed with. A kubeconfig in several
+// workspaces is named by the workspace it resolves to, like in kubecfg prompt
+// and env.
+func statusName(rc *config.RuntimeConfig, t renderTask) string {
+	ref, err := rc.Resolve(t.rk.Name)
+	if err != nil || ref.Kubeconfig != t.rk {
+		return t.displayName
+	}
+	return config.Ref{Workspace: ref.Workspace, Kubeconfig: ref.Kubeconfig}.String()
+}
+
+// kubeconfigFileStatus compares the file at path with the kubeconfig that
+// expected returns for it. active is the file ~/.kube/config points to.
+func kubeconfigFileStatus(name, path, active string, expected func(rendered *api.Config) (*api.Config, []string)) kubeconfigStatus {
+	s := kubeconfigStatus{Name: name, Path: path, Status: statusOK}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		s.Active = resolved == active
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.Status = statusMissing
+		s.Problems = []string{"not rendered"}
+		return s
+	}
+	if err != nil {
+		s.Status = statusDrifted
+		s.Problems = []string{err.Error()}
+		return s
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		s.Problems = append(s.Problems, fmt.Sprintf("permissions are %04o, expected 0600", info.Mode().Perm()))
+	}
+
+	rendered, err := loadKubeconfigFile(path)
+	if err != nil {
+		s.Status = statusDrifted
+		s.Problems = append(s.Problems, err.Error())
+		return s
+	}
+
+	want, problems := expected(rendered)
+	s.Problems = append(s.Problems, problems...)
+	if want != nil {
+		normalized, err := normalizeKubeconfig(want)
+		if err != nil {
+			s.Problems = append(s.Problems, err.Error())
+		} else {
+			s.Problems = append(s.Problems, diffEntries("cluster", normalized.Clusters, rendered.Clusters, nil)...)
+			s.Problems = append(s.Problems, diffEntries("context", normalized.Contexts, rendered.Contexts, func(ctx api.Context) api.Context {
+				ctx.Namespace = ""
+				return ctx
+			})...)
+		}
+	}
+
+	if len(s.Problems) > 0 {
+		s.Status = statusDrifted
+	}
+	return s
+}
+
+// expectedKubeconfig applies the imports of rk from rendered, the file it was
+// rendered to, and returns its config along with the imported credentials in
+// rendered that have expired. Contexts of a merged workspace are reported as
+// config.MergedName(prefix, name) if prefix isn't empty.
+func expectedKubeconfig(rk *config.RuntimeKubeconfig, rendered *api.Config, prefix string) (*api.Config, []string) {
+	if err := applyImportedContextsFrom(rk, rendered); err != nil {
+		return nil, []string{err.Error()}
+	}
+
+	var problems []string
+	for _, name := range sortedNames(rk.Contexts) {
+		if rk.Contexts[name].Import == nil {
+			continue
+		}
+		ctx, ok := rendered.Contexts[name]
+		if !ok {
+			continue
+		}
+		authInfo, ok := rendered.AuthInfos[ctx.AuthInfo]
+		if !ok {
+			continue
+		}
+		if expiry, ok := credentialExpiry(authInfo); ok && expiry.Before(statusNow()) {
+			display := name
+			if prefix != "" {
+				display = config.MergedName(prefix, name)
+			}
+			problems = append(problems, fmt.Sprintf("credentials of context %s expired %s", display, expiry.UTC().Format(time.RFC3339)))
+		}
+	}
+	return rk.Config, problems
+}
+
+// credentialExpiry returns when the token or client certificate of authInfo
+// expires, if it can tell. Only JWT tokens carry an expiry.
+func credentialExpiry(authInfo *api.AuthInfo) (time.Time, bool) {
+	if parts := strings.Split(authInfo.Token, "."); len(parts) == 3 {
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err == nil {
+			var claims struct {
+				Exp int64 `json:"exp"`
+			}
+			if json.Unmarshal(payload, &claims) == nil && claims.Exp > 0 {
+				return time.Unix(claims.Exp, 0), true
+			}
+		}
+	}
+
+	if block, _ := pem.Decode(authInfo.ClientCertificateData); block != nil {
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			return cert.NotAfter, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// diffEntries describes the entries of kind that were added to, removed from
+// or changed in got compared to want. normalize, if not nil, clears fields
+// that aren't compared.
+func diffEntries[V any](kind string, want, got map[string]*V, normalize func(V) V) []string {
+	var problems []string
+	for _, name := range sortedNames(want) {
+		entry, ok := got[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s %s was removed", kind, name))
+			continue
+		}
+		w, g := *want[name], *entry
+		if normalize != nil {
+			w, g = normalize(w), normalize(g)
+		}
+		if !reflect.DeepEqual(w, g) {
+			problems = append(problems, fmt.Sprintf("%s %s was changed", kind, name))
+		}
+	}
+	for _, name := range sortedNames(got) {
+		if _, ok := want[name]; !ok {
+			problems = append(problems, fmt.Sprintf("%s %s was added", kind, name))
+		}
+	}
+	return problems
+}
+
+// normalizeKubeconfig returns kubeconfig as it reads back after being written,
+// so that it can be compared with a file loaded with loadKubeconfigFile.
+func normalizeKubeconfig(kubeconfig *api.Config) (*api.Config, error) {
+	data, err := clientcmd.Write(*kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	return clientcmd.Load(data)
+}
+
+// loadKubeconfigFile loads the kubeconfig at path without recording where its
+// entries came from, unlike clientcmd.LoadFromFile.
+func loadKubeconfigFile(path string) (*api.Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	kubeconfig, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, fmt.Errorf("load %s: %w", path, err)
+	}
+	return kubeconfig, nil
+}
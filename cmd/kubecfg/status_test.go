@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestRunStatusCmd(t *testing.T) {
+	targetPath := filepath.Join(t.TempDir(), "vgr.yaml")
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	cfg = newRenderCommandTestConfig(targetPath)
+	opts := statusOptions{output: "json"}
+
+	statuses := runStatusJSON(t, opts)
+	require.Equal(t, []kubeconfigStatus{{
+		Name:     "work/vgr",
+		Path:     targetPath,
+		Status:   statusMissing,
+		Problems: []string{"not rendered"},
+	}}, statuses)
+
+	require.NoError(t, runRenderCmd(context.Background(), "work", "vgr", true, false, time.Second))
+	require.NoError(t, setConfig(cfg.BaseDir, targetPath))
+
+	statuses = runStatusJSON(t, opts)
+	require.Equal(t, []kubeconfigStatus{{
+		Name:   "work/vgr",
+		Path:   targetPath,
+		Active: true,
+		Status: statusOK,
+	}}, statuses)
+
+	// kubecfg ns and kubecfg context change these, so they aren't drift.
+	rendered, err := clientcmd.LoadFromFile(targetPath)
+	require.NoError(t, err)
+	rendered.Contexts["context"].Namespace = "apps"
+	rendered.CurrentContext = ""
+	require.NoError(t, clientcmd.WriteToFile(*rendered, targetPath))
+	require.Equal(t, statusOK, runStatusJSON(t, opts)[0].Status)
+
+	rendered.Clusters["cluster"].Server = "https://changed.example.com"
+	rendered.Clusters["extra"] = rendered.Clusters["cluster"].DeepCopy()
+	delete(rendered.Contexts, "context")
+	require.NoError(t, clientcmd.WriteToFile(*rendered, targetPath))
+
+	statuses = runStatusJSON(t, opts)
+	require.Equal(t, statusDrifted, statuses[0].Status)
+	require.Equal(t, []string{
+		"cluster cluster was changed",
+		"cluster extra was added",
+		"context context was removed",
+	}, statuses[0].Problems)
+
+	var stdout bytes.Buffer
+	require.NoError(t, runStatusCmd(&stdout, statusOptions{output: "text"}))
+	require.Contains(t, stdout.String(), "NAME")
+	require.Contains(t, stdout.String(), "work/vgr")
+	require.Contains(t, stdout.String(), "cluster cluster was changed; cluster extra was added")
+
+	require.EqualError(t, runStatusCmd(&stdout, statusOptions{output: "yaml"}), "expected output to be one of [text json]")
+}
+
+func TestRunStatusCmdPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions aren't checked on windows")
+	}
+
+	targetPath := filepath.Join(t.TempDir(), "vgr.yaml")
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	cfg = newRenderCommandTestConfig(targetPath)
+	require.NoError(t, runRenderCmd(context.Background(), "work", "vgr", true, false, time.Second))
+	require.NoError(t, os.Chmod(targetPath, 0o644))
+
+	statuses := runStatusJSON(t, statusOptions{output: "json"})
+	require.Equal(t, statusDrifted, statuses[0].Status)
+	require.Equal(t, []string{"permissions are 0644, expected 0600"}, statuses[0].Problems)
+}
+
+func TestRunStatusCmdExpiredImport(t *testing.T) {
+	targetPath := filepath.Join(t.TempDir(), "vgr.yaml")
+
+	originalCfg := cfg
+	originalNow := statusNow
+	t.Cleanup(func() {
+		cfg = originalCfg
+		statusNow = originalNow
+	})
+
+	cfg = newImportedRenderCommandTestConfig(targetPath)
+	require.NoError(t, runRenderCmd(context.Background(), "work", "vgr", false, false, 5*time.Second))
+
+	// Imported contexts are checked against the rendered file without
+	// running the login source.
+	opts := statusOptions{output: "json"}
+	require.Equal(t, statusOK, runStatusJSON(t, opts)[0].Status)
+
+	expiry := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rendered, err := clientcmd.LoadFromFile(targetPath)
+	require.NoError(t, err)
+	rendered.AuthInfos[rendered.Contexts["ctx1"].AuthInfo].Token = testJWT(t, expiry)
+	require.NoError(t, clientcmd.WriteToFile(*rendered, targetPath))
+
+	statusNow = func() time.Time { return expiry.Add(-time.Hour) }
+	require.Equal(t, statusOK, runStatusJSON(t, opts)[0].Status)
+
+	statusNow = func() time.Time { return expiry.Add(time.Hour) }
+	statuses := runStatusJSON(t, opts)
+	require.Equal(t, statusDrifted, statuses[0].Status)
+	require.Equal(t, []string{"credentials of context ctx1 expired 2024-01-01T00:00:00Z"}, statuses[0].Problems)
+}
+
+func TestRunStatusCmdMergedWorkspace(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	cfg = newImportedRenderCommandTestConfig(filepath.Join(tmpDir, "vgr.yaml"))
+	cfg.Workspaces["work"].Render = config.WorkspaceRenderMerged
+	require.NoError(t, runRenderCmd(context.Background(), "work", "", false, false, 5*time.Second))
+
+	statuses := runStatusJSON(t, statusOptions{output: "json"})
+	require.Len(t, statuses, 1)
+	require.Equal(t, "work", statuses[0].Name)
+	require.Equal(t, statusOK, statuses[0].Status, statuses[0].Problems)
+
+	cfg.Kubeconfigs["vgr"].Contexts["ctx2"] = &config.Context{ImportRef: cfg.Kubeconfigs["vgr"].Contexts["ctx1"].ImportRef}
+	statuses = runStatusJSON(t, statusOptions{output: "json"})
+	require.Equal(t, statusDrifted, statuses[0].Status)
+}
+
+func TestRunStatusCmdNamesSharedKubeconfigByResolvedWorkspace(t *testing.T) {
+	t.Setenv(envKubeconfig, "")
+	t.Setenv(envWorkspace, "")
+	targetPath := filepath.Join(t.TempDir(), "vgr.yaml")
+
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	cfg = newRenderCommandTestConfig(targetPath)
+	cfg.Workspaces["team"] = &config.Workspace{Kubeconfigs: []string{"vgr"}}
+	cfg.DefaultWorkspace = "work"
+	require.NoError(t, runRenderCmd(context.Background(), "work", "vgr", true, false, time.Second))
+	t.Setenv("KUBECONFIG", targetPath)
+
+	var stdout bytes.Buffer
+	require.NoError(t, runPromptCmd(promptOptions{format: `{{ .Workspace }}/{{ .Kubeconfig }}`, noColor: true}, &stdout))
+	require.Equal(t, "work/vgr\n", stdout.String())
+
+	statuses := runStatusJSON(t, statusOptions{output: "json"})
+	require.Len(t, statuses, 1)
+	require.Equal(t, "work/vgr", statuses[0].Name)
+}
+
+func runStatusJSON(t *testing.T, opts statusOptions) []kubeconfigStatus {
+	t.Helper()
+
+	var stdout bytes.Buffer
+	require.NoError(t, runStatusCmd(&stdout, opts))
+
+	var statuses []kubeconfigStatus
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &statuses))
+	return statuses
+}
+
+func testJWT(t *testing.T, expiry time.Time) string {
+	t.Helper()
+
+	claims, err := json.Marshal(map[string]int64{"exp": expiry.Unix()})
+	require.NoError(t, err)
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"none"}`)) + "." + encode(claims) + "." + encode([]byte("signature"))
+}
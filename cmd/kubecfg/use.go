@@ -1,64 +1,174 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 
-	"github.com/amimof/kubecfg/pkg/cmdutil"
 	"github.com/amimof/kubecfg/pkg/config"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
 func newUseCmd() *cobra.Command {
-	var glob []string
+	var (
+		glob []string
+		yes  bool
+	)
 	cmd := &cobra.Command{
-		Use:          "use",
-		Short:        "Use a rendered kubeconfig",
-		Long:         `Select and activate an existing kubeconfig file`,
-		Example:      `  kubecfg use`,
-		Args:         cobra.ExactArgs(0),
-		SilenceUsage: true,
+		Use:   "use [REF]",
+		Short: "Use a rendered kubeconfig",
+		Long: `Select and activate an existing kubeconfig file.
+
+Without REF, the file is picked from the files matching --glob. REF is resolved
+like in kubecfg render and the kubeconfig must already be rendered. REF can also
+be a workspace rendered as merged, which activates the whole workspace.`,
+		Example: `  kubecfg use
+  kubecfg use prod-eu
+  kubecfg use team`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeWith(1, completeRefs),
+		SilenceUsage:      true,
 		RunE: withConfig(func(cmd *cobra.Command, args []string) error {
-			return runUseCmd(glob)
+			if len(args) == 1 {
+				return runUseRefCmd(args[0], yes)
+			}
+			return runUseCmd(glob, yes)
 		}),
 	}
 	h, _ := os.UserHomeDir()
 
 	cmd.Flags().StringArrayVar(&glob, "glob", []string{path.Join(h, ".kube/*.yaml")}, "List files matching a pattern to include. This flag can be used multiple times.")
+	addYesFlag(cmd, &yes)
 
 	return cmd
 }
 
-func runUseCmd(glob []string) error {
-	selected, err := pickKubeconfig(glob)
+func runUseCmd(glob []string, yes bool) error {
+	compiler := config.NewCompiler(config.WithoutDecryption())
+	runtime, err := compiler.Compile(&cfg)
 	if err != nil {
 		return err
 	}
+	refreshPromptCache(runtime)
 
-	compiler := config.NewCompiler()
-	runtime, err := compiler.Compile(&cfg)
+	selected, err := pickKubeconfig(runtime, glob)
 	if err != nil {
 		return err
 	}
 
+	// A merged workspace file holds several kubeconfigs, each protected one
+	// is confirmed.
+	protected := false
+	for _, ref := range refsForFile(runtime, selected) {
+		name := selected
+		if ref.Workspace != nil {
+			name = ref.String()
+		}
+		if err := confirmProtected(ref.Kubeconfig, name, yes); err != nil {
+			return err
+		}
+		protected = protected || ref.Kubeconfig.Protected
+	}
+
 	err = setConfig(runtime.BaseDir, selected)
 	if err != nil {
 		return err
 	}
 
-	cmdutil.Printf(`{{ "✔" | FgGreen }} Using kubeconfig {{ .Kubeconfig | FgCyan }}`, cmdutil.Data{"Kubeconfig": selected})
+	printUsingKubeconfig("", selected, protected)
 	return nil
 }
 
-func pickKubeconfig(globs []string) (string, error) {
+// runUseRefCmd activates the rendered kubeconfig that ref resolves to.
+func runUseRefCmd(ref string, yes bool) error {
+	compiler := config.NewCompiler(config.WithoutDecryption())
+	runtime, err := compiler.Compile(&cfg)
+	if err != nil {
+		return err
+	}
+	refreshPromptCache(runtime)
+
+	rw := runtime.Workspace(ref)
+	if rw != nil && rw.Merged {
+		return useMergedWorkspace(runtime, rw, yes)
+	}
+
+	resolved, err := runtime.Resolve(ref)
+	if err != nil {
+		var ambiguous *config.AmbiguousRefError
+		if rw != nil && (errors.Is(err, config.ErrNoMatch) || errors.As(err, &ambiguous)) {
+			return fmt.Errorf("workspace %s is rendered as separate kubeconfigs, use one of them or set render: %s on the workspace", rw.Name, config.WorkspaceRenderMerged)
+		}
+		return err
+	}
+
+	rk := resolved.Kubeconfig
+	name := config.Ref{Workspace: resolved.Workspace, Kubeconfig: rk}.String()
+	if _, err := os.Stat(rk.Path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("kubeconfig %s is not rendered, run kubecfg render %s", name, ref)
+		}
+		return err
+	}
+
+	if err := confirmProtected(rk, name, yes); err != nil {
+		return err
+	}
+
+	if err := setConfig(runtime.BaseDir, rk.Path); err != nil {
+		return err
+	}
+
+	printUsingKubeconfig(workspaceDisplayName(resolved.Workspace), rk.Name, rk.Protected)
+	return nil
+}
+
+// useMergedWorkspace activates the merged kubeconfig of rw, after confirming
+// each protected kubeconfig in it.
+func useMergedWorkspace(rc *config.RuntimeConfig, rw *config.RuntimeWorkspace, yes bool) error {
+	if _, err := os.Stat(rw.Path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("workspace %s is not rendered, run kubecfg render %s", rw.Name, rw.Name)
+		}
+		return err
+	}
+
+	protected := false
+	for _, rk := range selectKubeconfigs(rw.Kubeconfigs, labels.Everything()) {
+		if err := confirmProtected(rk, rw.Name+"/"+rk.Name, yes); err != nil {
+			return err
+		}
+		protected = protected || rk.Protected
+	}
+
+	if err := setConfig(rc.BaseDir, rw.Path); err != nil {
+		return err
+	}
+
+	printUsingKubeconfig(rw.Name, "", protected)
+	return nil
+}
+
+// pickKubeconfig lets the user pick one of the files matching globs. Files of
+// protected kubeconfigs are marked like in pickContext.
+func pickKubeconfig(rc *config.RuntimeConfig, globs []string) (string, error) {
 	// Assemble items in Cfg
 	kubeconfigs := ListKubeconfigs(globs)
 
 	inputChan := make(chan string)
 	go func() {
 		for _, name := range kubeconfigs {
+			for _, ref := range refsForFile(rc, name) {
+				if ref.Kubeconfig.Protected {
+					name += "\t" + protectedMarker
+					break
+				}
+			}
 			inputChan <- name
 		}
 		close(inputChan)
@@ -68,6 +178,7 @@ func pickKubeconfig(globs []string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	selected, _, _ = strings.Cut(selected, "\t")
 
 	return selected, nil
 }
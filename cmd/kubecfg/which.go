@@ -36,12 +36,7 @@ func runWhichCmd(plain bool) error {
 	}
 
 	dst := path.Join(runtime.BaseDir, "config")
-	_, err = os.Stat(dst)
-	if err != nil {
-		return err
-	}
-
-	sEval, err := filepath.EvalSymlinks(dst)
+	sEval, err := activeKubeconfigPath(runtime.BaseDir)
 	if err != nil {
 		return err
 	}
@@ -57,3 +52,13 @@ func runWhichCmd(plain bool) error {
 
 	return nil
 }
+
+// activeKubeconfigPath returns the file that the config symlink in baseDir
+// points to.
+func activeKubeconfigPath(baseDir string) (string, error) {
+	dst := path.Join(baseDir, "config")
+	if _, err := os.Stat(dst); err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(dst)
+}
@@ -9,25 +9,37 @@ import (
 	"github.com/amimof/kubecfg/pkg/cmdutil/table"
 	"github.com/amimof/kubecfg/pkg/config"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 var workspacesStdout io.Writer = os.Stdout
 
 func newWorkspacesCmd() *cobra.Command {
+	var selector string
+
 	cmd := &cobra.Command{
-		Use:     "workspaces",
-		Short:   "List workspaces",
-		Long:    `List the workspaces defined in kubecfg.yaml.`,
+		Use:   "workspaces",
+		Short: "List workspaces",
+		Long: `List the workspaces defined in kubecfg.yaml. With --selector, only
+workspaces with a matching kubeconfig are listed and only matching
+kubeconfigs are counted.`,
 		Aliases: []string{"ws"},
 		Args:    cobra.ExactArgs(0),
 		RunE: withConfig(func(cmd *cobra.Command, args []string) error {
-			return runWorkspacesCmd(workspacesStdout)
+			sel, err := parseSelector(selector)
+			if err != nil {
+				return err
+			}
+			return runWorkspacesCmd(sel, workspacesStdout)
 		}),
 	}
+
+	addSelectorFlag(cmd, &selector)
+
 	return cmd
 }
 
-func runWorkspacesCmd(stdout io.Writer) error {
+func runWorkspacesCmd(selector labels.Selector, stdout io.Writer) error {
 	compiler := config.NewCompiler()
 
 	runtime, err := compiler.Compile(&cfg)
@@ -49,7 +61,11 @@ func runWorkspacesCmd(stdout io.Writer) error {
 
 	for _, name := range names {
 		workspace := runtime.Workspace(name)
-		if err := tbl.AddRow(name, workspace.Description, fmt.Sprintf("%d", len(workspace.Kubeconfigs))); err != nil {
+		kubeconfigs := selectKubeconfigs(workspace.Kubeconfigs, selector)
+		if len(kubeconfigs) == 0 && !selector.Empty() {
+			continue
+		}
+		if err := tbl.AddRow(name, workspace.Description, fmt.Sprintf("%d", len(kubeconfigs))); err != nil {
 			return err
 		}
 	}
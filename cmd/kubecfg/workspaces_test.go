@@ -7,6 +7,7 @@ import (
 
 	"github.com/amimof/kubecfg/pkg/config"
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 func TestRunWorkspacesCmdPrintsHeaderedTable(t *testing.T) {
@@ -18,7 +19,7 @@ func TestRunWorkspacesCmdPrintsHeaderedTable(t *testing.T) {
 	cfg = newWorkspacesCommandTestConfig()
 
 	var stdout bytes.Buffer
-	err := runWorkspacesCmd(&stdout)
+	err := runWorkspacesCmd(labels.Everything(), &stdout)
 	require.NoError(t, err)
 	require.Equal(t, []string{
 		"NAME       DESCRIPTION         KUBECONFIGS",
@@ -85,3 +86,30 @@ func newWorkspacesCommandTestConfig() config.Config {
 		},
 	}
 }
+
+func TestRunWorkspacesCmdFiltersBySelector(t *testing.T) {
+	originalCfg := cfg
+	t.Cleanup(func() {
+		cfg = originalCfg
+	})
+
+	cfg = newWorkspacesCommandTestConfig()
+	cfg.Kubeconfigs["alpha"].Labels = map[string]string{"env": "prod"}
+
+	selector, err := parseSelector("env=prod")
+	require.NoError(t, err)
+
+	var stdout bytes.Buffer
+	err = runWorkspacesCmd(selector, &stdout)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"NAME     DESCRIPTION         KUBECONFIGS",
+		"default  Main workspace      1",
+		"third    Untitled workspace  1",
+	}, strings.Split(strings.TrimSpace(stdout.String()), "\n"))
+}
+
+func TestParseSelectorRejectsInvalidSelector(t *testing.T) {
+	_, err := parseSelector("env in prod")
+	require.ErrorContains(t, err, `invalid selector "env in prod"`)
+}
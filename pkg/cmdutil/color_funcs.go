@@ -21,6 +21,36 @@ func FgHiMagenta(s string) string { return applyColor(s, color.FgHiMagenta) }
 func FgHiCyan(s string) string    { return applyColor(s, color.FgHiCyan) }
 func FgHiWhite(s string) string   { return applyColor(s, color.FgHiWhite) }
 
+// fgByName holds the foreground colors by the names used in config, see
+// config.KubeconfigColors.
+var fgByName = map[string]func(string) string{
+	"black":      FgBlack,
+	"red":        FgRed,
+	"green":      FgGreen,
+	"yellow":     FgYellow,
+	"blue":       FgBlue,
+	"magenta":    FgMagenta,
+	"cyan":       FgCyan,
+	"white":      FgWhite,
+	"hi-black":   FgHiBlack,
+	"hi-red":     FgHiRed,
+	"hi-green":   FgHiGreen,
+	"hi-yellow":  FgHiYellow,
+	"hi-blue":    FgHiBlue,
+	"hi-magenta": FgHiMagenta,
+	"hi-cyan":    FgHiCyan,
+	"hi-white":   FgHiWhite,
+}
+
+// Fg applies the foreground color called name, such as red or hi-cyan. Unknown
+// names, including an empty one, leave s as is.
+func Fg(name, s string) string {
+	if fn, ok := fgByName[name]; ok {
+		return fn(s)
+	}
+	return s
+}
+
 func BgBlack(s string) string     { return applyColor(s, color.BgBlack) }
 func BgRed(s string) string       { return applyColor(s, color.BgRed) }
 func BgGreen(s string) string     { return applyColor(s, color.BgGreen) }
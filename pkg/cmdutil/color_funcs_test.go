@@ -0,0 +1,26 @@
+package cmdutil
+
+import (
+	"testing"
+
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/fatih/color"
+)
+
+func TestFgKnowsEveryKubeconfigColor(t *testing.T) {
+	oldNoColor := color.NoColor
+	color.NoColor = false
+	t.Cleanup(func() {
+		color.NoColor = oldNoColor
+	})
+
+	for _, name := range config.KubeconfigColors {
+		if got := Fg(name, "prod"); got == "prod" {
+			t.Fatalf("expected %s to color the text, got %q", name, got)
+		}
+	}
+
+	if got := Fg("", "prod"); got != "prod" {
+		t.Fatalf("expected no color for an empty name, got %q", got)
+	}
+}
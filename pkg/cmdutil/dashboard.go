@@ -192,6 +192,14 @@ func (d *Dashboard) SetPhase(idx int, msg string) {
 	})
 }
 
+// SetStatus shows msg after the name of the running service at idx, such as
+// what it is waiting for. An empty msg clears it.
+func (d *Dashboard) SetStatus(idx int, msg string) {
+	d.Update(idx, func(s *ServiceState) {
+		s.container.UpdateMetadata("Status", msg)
+	})
+}
+
 // Fail marks the service as failed
 func (d *Dashboard) Fail(idx int) {
 	d.Update(idx, func(s *ServiceState) {
@@ -315,11 +323,12 @@ func NewDashboard(names []string, opts ...Option) (*Dashboard, error) {
 			"FailedMsg": "",
 			"Name":      n,
 			"Error":     "",
+			"Status":    "",
 		}
 
 		// Status line is always present.
 		elements := []*Element{
-			NewElement(`{{ if .Container.Failed }}[ {{"✖" | FgRed }} ] {{ .Container.FailedMsg | FgRed }}{{else if .Container.Done }}[ {{ "✔" | FgGreen }} ] {{ .Container.DoneMsg | FgGreen }}{{else}}[ {{ spinner | FgYellow }} ]{{ if .Prefix }}{{ .Prefix }}{{end}} {{ .Container.Name | Bold }}{{end}}`),
+			NewElement(`{{ if .Container.Failed }}[ {{"✖" | FgRed }} ] {{ .Container.FailedMsg | FgRed }}{{else if .Container.Done }}[ {{ "✔" | FgGreen }} ] {{ .Container.DoneMsg | FgGreen }}{{else}}[ {{ spinner | FgYellow }} ]{{ if .Prefix }}{{ .Prefix }}{{end}} {{ .Container.Name | Bold }}{{ if .Container.Status }} {{ .Container.Status | FgHiBlack }}{{ end }}{{end}}`),
 		}
 
 		// Append one element per active field, in defaultFields order.
@@ -578,6 +578,7 @@ var templateFuncs = template.FuncMap{
 	"FgHiMagenta": FgHiMagenta,
 	"FgHiCyan":    FgHiCyan,
 	"FgHiWhite":   FgHiWhite,
+	"Fg":          Fg,
 
 	// Background colors (16-color ANSI)
 	"BgBlack":     BgBlack,
@@ -12,7 +12,8 @@ import (
 )
 
 type Compiler struct {
-	Decryptor SecretDecryptor
+	Decryptor      SecretDecryptor
+	SkipDecryption bool
 }
 
 type CompilerOption func(*Compiler)
@@ -23,6 +24,15 @@ func WithDecryptor(d SecretDecryptor) CompilerOption {
 	}
 }
 
+// WithoutDecryption compiles encrypted auth info fields without decrypting
+// them. The resulting runtime config is suitable for inspection, such as
+// linting or shell completion, but not for rendering.
+func WithoutDecryption() CompilerOption {
+	return func(c *Compiler) {
+		c.SkipDecryption = true
+	}
+}
+
 func NewCompiler(opts ...CompilerOption) *Compiler {
 	c := &Compiler{}
 
@@ -37,6 +47,10 @@ func (c *Compiler) Compile(cfg *Config) (*RuntimeConfig, error) {
 		return nil, fmt.Errorf("config is nil")
 	}
 
+	if err := CheckVersion(cfg.Version); err != nil {
+		return nil, err
+	}
+
 	rt := &RuntimeConfig{
 		Version:           cfg.Version,
 		BaseDir:           ResolvePath("", cfg.BaseDir),
@@ -44,6 +58,10 @@ func (c *Compiler) Compile(cfg *Config) (*RuntimeConfig, error) {
 		Kubeconfigs:       make(map[string]*RuntimeKubeconfig),
 		KubeconfigAliases: make(map[string]*RuntimeKubeconfig),
 		Contexts:          make(map[string]*RuntimeContextRef),
+
+		Clusters:     make(map[string]*RuntimeCluster),
+		AuthInfos:    make(map[string]*RuntimeAuthInfo),
+		LoginSources: make(map[string]*RuntimeLoginSource),
 	}
 
 	if rt.BaseDir == "" {
@@ -55,6 +73,10 @@ func (c *Compiler) Compile(cfg *Config) (*RuntimeConfig, error) {
 		rt.BaseDir = defaultBaseDir
 	}
 
+	if err := c.compileShared(rt, cfg); err != nil {
+		return nil, err
+	}
+
 	if err := c.compileKubeconfigs(rt, cfg); err != nil {
 		return nil, err
 	}
@@ -71,16 +93,21 @@ func (c *Compiler) Compile(cfg *Config) (*RuntimeConfig, error) {
 }
 
 func (c *Compiler) compileKubeconfigs(rt *RuntimeConfig, cfg *Config) error {
-	for kubeconfigName, kubeconfig := range cfg.Kubeconfigs {
-		if kubeconfig == nil {
-			return fmt.Errorf("kubeconfigs.%s is nil", kubeconfigName)
+	for _, kubeconfigName := range sortedKeys(cfg.Kubeconfigs) {
+		kubeconfig, err := cfg.ResolveKubeconfig(kubeconfigName)
+		if err != nil {
+			return err
 		}
 
 		rkc := &RuntimeKubeconfig{
 			Name:             kubeconfigName,
+			Source:           kubeconfig.Source,
 			Path:             ResolvePath(rt.BaseDir, kubeconfig.Path),
 			Protected:        kubeconfig.Protected,
+			ProtectedConfirm: firstNonEmpty(kubeconfig.ProtectedConfirm, ProtectedConfirmYes),
+			Color:            kubeconfig.Color,
 			Aliases:          append([]string(nil), kubeconfig.Aliases...),
+			Labels:           mergeLabels(nil, kubeconfig.Labels),
 			DefaultNamespace: strings.TrimSpace(kubeconfig.DefaultNamespace),
 
 			LoginSources: make(map[string]*RuntimeLoginSource),
@@ -107,7 +134,7 @@ func (c *Compiler) compileKubeconfigs(rt *RuntimeConfig, cfg *Config) error {
 			return err
 		}
 
-		if err := compileContexts(rkc, kubeconfig); err != nil {
+		if err := compileContexts(rt, rkc, kubeconfig); err != nil {
 			return err
 		}
 
@@ -136,30 +163,35 @@ func (c *Compiler) compileKubeconfigs(rt *RuntimeConfig, cfg *Config) error {
 }
 
 func compileClusters(rkc *RuntimeKubeconfig, kc *Kubeconfig) error {
-	for name, cluster := range kc.Clusters {
+	for _, name := range sortedKeys(kc.Clusters) {
+		cluster := kc.Clusters[name]
 		if cluster == nil {
 			return fmt.Errorf("kubeconfigs.%s.clusters.%s is nil", rkc.Name, name)
 		}
 
-		rkc.Clusters[name] = &RuntimeCluster{
-			Name: name,
-			Cluster: &api.Cluster{
-				LocationOfOrigin:         cluster.LocationOfOrigin,
-				Server:                   cluster.Server,
-				TLSServerName:            cluster.TLSServerName,
-				InsecureSkipTLSVerify:    cluster.InsecureSkipTLSVerify,
-				CertificateAuthority:     cluster.CertificateAuthority,
-				CertificateAuthorityData: cluster.CertificateAuthorityData,
-				ProxyURL:                 cluster.ProxyURL,
-				DisableCompression:       cluster.DisableCompression,
-				Extensions:               cluster.Extensions,
-			},
-		}
+		rkc.Clusters[name] = compileCluster(name, cluster)
 	}
 
 	return nil
 }
 
+func compileCluster(name string, cluster *Cluster) *RuntimeCluster {
+	return &RuntimeCluster{
+		Name: name,
+		Cluster: &api.Cluster{
+			LocationOfOrigin:         cluster.LocationOfOrigin,
+			Server:                   cluster.Server,
+			TLSServerName:            cluster.TLSServerName,
+			InsecureSkipTLSVerify:    cluster.InsecureSkipTLSVerify,
+			CertificateAuthority:     cluster.CertificateAuthority,
+			CertificateAuthorityData: cluster.CertificateAuthorityData,
+			ProxyURL:                 cluster.ProxyURL,
+			DisableCompression:       cluster.DisableCompression,
+			Extensions:               cluster.Extensions,
+		},
+	}
+}
+
 func resolveExecEnvVars(src []ExecEnvVar) []api.ExecEnvVar {
 	execEnvVar := make([]api.ExecEnvVar, len(src))
 	for i, e := range src {
@@ -172,29 +204,40 @@ func resolveExecEnvVars(src []ExecEnvVar) []api.ExecEnvVar {
 }
 
 func (c *Compiler) compileLoginSources(rkc *RuntimeKubeconfig, kc *Kubeconfig) error {
-	for name, ls := range kc.LoginSources {
+	for _, name := range sortedKeys(kc.LoginSources) {
+		ls := kc.LoginSources[name]
 		if ls == nil {
 			return fmt.Errorf("kubeconfigs.%s.login_sources.%s is nil", rkc.Name, name)
 		}
 
-		envMap, err := loadEnvFile(ls.EnvFile)
+		rls, err := compileLoginSource(name, ls)
 		if err != nil {
 			return fmt.Errorf("kubeconfigs.%s.login_sources.%s.env_file: %w", rkc.Name, name, err)
 		}
 
-		rkc.LoginSources[name] = &RuntimeLoginSource{
-			Name:    name,
-			Command: ls.Command,
-			Args:    ls.Args,
-			Env:     mergeLoginEnv(ls.Env, envMap),
-		}
+		rkc.LoginSources[name] = rls
 	}
 	return nil
 }
 
+func compileLoginSource(name string, ls *LoginSource) (*RuntimeLoginSource, error) {
+	envMap, err := loadEnvFile(ls.EnvFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RuntimeLoginSource{
+		Name:    name,
+		Command: ls.Command,
+		Args:    ls.Args,
+		Env:     mergeLoginEnv(ls.Env, envMap),
+	}, nil
+}
+
 func (c *Compiler) compileAuthInfos(rkc *RuntimeKubeconfig, kc *Kubeconfig) error {
-	for name, ai := range kc.AuthInfos {
-		compiler := &AuthInfoCompiler{Decryptor: c.Decryptor}
+	for _, name := range sortedKeys(kc.AuthInfos) {
+		ai := kc.AuthInfos[name]
+		compiler := &AuthInfoCompiler{Decryptor: c.Decryptor, SkipDecryption: c.SkipDecryption}
 		rai, err := compiler.Compile(name, ai)
 		if err != nil {
 			return err
@@ -313,6 +356,13 @@ func resolveAuthInfosExec(e *ExecConfig) (*api.ExecConfig, error) {
 		return nil, err
 	}
 
+	// kubectl requires an interactive mode in v1 kubeconfigs, IfAvailable is
+	// what it defaults to for older ones.
+	interactiveMode := api.ExecInteractiveMode(e.InteractiveMode)
+	if interactiveMode == "" {
+		interactiveMode = api.IfAvailableExecInteractiveMode
+	}
+
 	return &api.ExecConfig{
 		Command:                 e.Command,
 		Args:                    e.Args,
@@ -321,14 +371,26 @@ func resolveAuthInfosExec(e *ExecConfig) (*api.ExecConfig, error) {
 		InstallHint:             e.InstallHint,
 		ProvideClusterInfo:      e.ProvideClusterInfo,
 		Config:                  e.Config,
-		InteractiveMode:         api.ExecInteractiveMode(e.InteractiveMode),
+		InteractiveMode:         interactiveMode,
 		StdinUnavailable:        e.StdinUnavailable,
 		StdinUnavailableMessage: e.StdinUnavailableMessage,
 	}, nil
 }
 
-func compileContexts(rkc *RuntimeKubeconfig, kc *Kubeconfig) error {
-	for name, context := range kc.Contexts {
+// compileContexts compiles the contexts of kc, including the ones generated by
+// its context templates, and adds the shared entries they reference to rkc.
+func compileContexts(rt *RuntimeConfig, rkc *RuntimeKubeconfig, kc *Kubeconfig) error {
+	contexts, generatedBy, err := expandContextTemplates(rkc.Name, kc)
+	if err != nil {
+		return err
+	}
+
+	if err := resolveSharedRefs(rt, rkc, kc, contexts); err != nil {
+		return err
+	}
+
+	for _, name := range sortedKeys(contexts) {
+		context := contexts[name]
 		if context == nil {
 			return fmt.Errorf("kubeconfigs.%s.contexts.%s is nil", rkc.Name, name)
 		}
@@ -357,6 +419,8 @@ func compileContexts(rkc *RuntimeKubeconfig, kc *Kubeconfig) error {
 			ClusterKey:  clusterKey,
 			AuthInfoKey: authInfoKey,
 			Namespace:   namespace,
+			Labels:      mergeLabels(kc.Labels, context.Labels),
+			Template:    generatedBy[name],
 			Import:      importRef,
 
 			Context: &api.Context{
@@ -427,7 +491,8 @@ func compileImportRef(rkc *RuntimeKubeconfig, contextName string, ref ImportRef)
 }
 
 func resolveRuntimeContexts(rkc *RuntimeKubeconfig) error {
-	for contextKey, ctx := range rkc.Contexts {
+	for _, contextKey := range sortedKeys(rkc.Contexts) {
+		ctx := rkc.Contexts[contextKey]
 		if ctx.Import == nil {
 			cluster, ok := rkc.Clusters[ctx.ClusterKey]
 			if !ok {
@@ -506,7 +571,8 @@ func compileNativeKubeconfig(rkc *RuntimeKubeconfig) {
 }
 
 func (c *Compiler) compileWorkspaces(rt *RuntimeConfig, cfg *Config) error {
-	for workspaceName, workspace := range cfg.Workspaces {
+	for _, workspaceName := range sortedKeys(cfg.Workspaces) {
+		workspace := cfg.Workspaces[workspaceName]
 		if workspace == nil {
 			return fmt.Errorf("workspaces.%s is nil", workspaceName)
 		}
@@ -515,11 +581,26 @@ func (c *Compiler) compileWorkspaces(rt *RuntimeConfig, cfg *Config) error {
 			Name: workspaceName,
 
 			Description: workspace.Description,
+			Source:      workspace.Source,
 
 			Kubeconfigs: make(map[string]*RuntimeKubeconfig),
+
+			Merged: workspace.Render == WorkspaceRenderMerged,
+		}
+		if rw.Merged {
+			path := workspace.Path
+			if path == "" {
+				path = "@/" + workspaceName + ".yaml"
+			}
+			rw.Path = ResolvePath(rt.BaseDir, path)
 		}
 
-		for _, kubeconfigName := range workspace.Kubeconfigs {
+		kubeconfigNames, err := cfg.WorkspaceKubeconfigs(workspaceName)
+		if err != nil {
+			return err
+		}
+
+		for _, kubeconfigName := range kubeconfigNames {
 			rkc, ok := rt.Kubeconfigs[kubeconfigName]
 			if !ok {
 				return fmt.Errorf(
@@ -667,7 +748,8 @@ func firstNonEmpty(values ...string) string {
 }
 
 type AuthInfoCompiler struct {
-	Decryptor SecretDecryptor
+	Decryptor      SecretDecryptor
+	SkipDecryption bool
 }
 
 type SecretDecryptor interface {
@@ -680,7 +762,7 @@ func (c *AuthInfoCompiler) Compile(name string, in *AuthInfo) (*RuntimeAuthInfo,
 		return nil, fmt.Errorf("authinfo %q is nil", name)
 	}
 
-	if in.HasEncryptedFields() && c.Decryptor == nil {
+	if in.HasEncryptedFields() && c.Decryptor == nil && !c.SkipDecryption {
 		return nil, fmt.Errorf("authinfo %q contains encrypted fields; configure identity_files or provide a passphrase", name)
 	}
 
@@ -713,6 +795,10 @@ func (c *AuthInfoCompiler) Compile(name string, in *AuthInfo) (*RuntimeAuthInfo,
 		},
 	}
 
+	if c.SkipDecryption {
+		return rai, nil
+	}
+
 	if in.EncryptedToken != "" {
 		token, err := c.Decryptor.DecryptString(in.EncryptedToken)
 		if err != nil {
@@ -11,20 +11,88 @@ type Config struct {
 	Kubeconfigs      map[string]*Kubeconfig `mapstructure:"kubeconfigs,omitempty" json:"kubeconfigs,omitempty" yaml:"kubeconfigs,omitempty"`
 	BaseDir          string                 `mapstructure:"base_dir,omitempty" json:"base_dir,omitempty" yaml:"base_dir,omitempty"`
 	IdentityFiles    []string               `mapstructure:"identity_files,omitempty" json:"identity_files,omitempty" yaml:"identity_files,omitempty"`
+	Lint             LintConfig             `mapstructure:"lint,omitempty" json:"lint,omitempty" yaml:"lint,omitempty"`
+	Include          []string               `mapstructure:"include,omitempty" json:"include,omitempty" yaml:"include,omitempty"`
+	Vars             map[string]string      `mapstructure:"vars,omitempty" json:"vars,omitempty" yaml:"vars,omitempty"`
+
+	// Backups is how many previous versions are kept of each rendered
+	// kubeconfig. Defaults to DefaultBackups.
+	Backups *int `mapstructure:"backups,omitempty" json:"backups,omitempty" yaml:"backups,omitempty"`
+
+	// Shared entries that contexts of any kubeconfig can reference as
+	// shared:<name>.
+	Clusters     map[string]*Cluster     `mapstructure:"clusters,omitempty" json:"clusters,omitempty" yaml:"clusters,omitempty"`
+	AuthInfos    map[string]*AuthInfo    `mapstructure:"auth_infos,omitempty" json:"auth_infos,omitempty" yaml:"auth_infos,omitempty"`
+	LoginSources map[string]*LoginSource `mapstructure:"login_sources,omitempty" json:"login_sources,omitempty" yaml:"login_sources,omitempty"`
+
+	// Overlays patch the config on the hosts they match.
+	Overlays []*Overlay `mapstructure:"overlays,omitempty" json:"overlays,omitempty" yaml:"overlays,omitempty"`
+
+	// File is the main config file, and Sources maps shared entries and
+	// vars, such as clusters.prod or vars.team, to the file that defined
+	// them. Both are set by ResolveIncludes.
+	File    string            `mapstructure:"-" json:"-" yaml:"-"`
+	Sources map[string]string `mapstructure:"-" json:"-" yaml:"-"`
+
+	// Expansions lists the values changed by Expand.
+	Expansions []Expansion `mapstructure:"-" json:"-" yaml:"-"`
+
+	// AppliedOverlays lists the indexes of the overlays applied by
+	// ApplyOverlays.
+	AppliedOverlays []int `mapstructure:"-" json:"-" yaml:"-"`
+}
+
+// BackupCount returns Backups, or DefaultBackups if it isn't set.
+func (c *Config) BackupCount() int {
+	if c.Backups == nil {
+		return DefaultBackups
+	}
+	return *c.Backups
+}
+
+// LintConfig configures the kubecfg lint command.
+type LintConfig struct {
+	// Disable lists rule IDs that should not be run.
+	Disable []string `mapstructure:"disable,omitempty" json:"disable,omitempty" yaml:"disable,omitempty"`
 }
 
 type Workspace struct {
 	Description       string   `mapstructure:"description,omitempty" json:"description,omitempty" yaml:"description,omitempty"`
 	Kubeconfigs       []string `mapstructure:"kubeconfigs,omitempty" json:"kubeconfigs,omitempty" yaml:"kubeconfigs,omitempty"`
 	DefaultKubeconfig string   `mapstructure:"default_kubeconfig,omitempty" json:"default_kubeconfig,omitempty" yaml:"default_kubeconfig,omitempty"`
+
+	// Selector adds every kubeconfig whose labels match to the workspace.
+	Selector *LabelSelector `mapstructure:"selector,omitempty" json:"selector,omitempty" yaml:"selector,omitempty"`
+
+	// Render is how the kubeconfigs of the workspace are rendered, one of
+	// WorkspaceRenderSeparate and WorkspaceRenderMerged.
+	Render string `mapstructure:"render,omitempty" json:"render,omitempty" yaml:"render,omitempty"`
+
+	// Path is the file a merged workspace is rendered to. Defaults to
+	// @/<workspace>.yaml.
+	Path string `mapstructure:"path,omitempty" json:"path,omitempty" yaml:"path,omitempty"`
+
+	// Source is the file the workspace was loaded from.
+	Source string `mapstructure:"-" json:"-" yaml:"-"`
 }
 
 type Kubeconfig struct {
-	Path           string   `json:"path,omitempty"`
-	Protected      bool     `json:"protected,omitempty"`
+	Extends   string `mapstructure:"extends,omitempty" json:"extends,omitempty" yaml:"extends,omitempty"`
+	Path      string `json:"path,omitempty"`
+	Protected bool   `json:"protected,omitempty"`
+
+	// ProtectedConfirm is how activating a protected kubeconfig is confirmed.
+	ProtectedConfirm string `mapstructure:"protected_confirm,omitempty" json:"protected_confirm,omitempty" yaml:"protected_confirm,omitempty"`
+
+	// Color is the color kubecfg prompt shows the kubeconfig in, one of
+	// KubeconfigColors.
+	Color string `mapstructure:"color,omitempty" json:"color,omitempty" yaml:"color,omitempty"`
+
 	Aliases        []string `json:"aliases,omitempty"`
 	CurrentContext string   `mapstructure:"current_context,omitempty" json:"current_context,omitempty" yaml:"current_context,omitempty"`
 
+	Labels map[string]string `mapstructure:"labels,omitempty" json:"labels,omitempty" yaml:"labels,omitempty"`
+
 	DefaultContext   string `mapstructure:"default_context,omitempty" json:"default_context,omitempty" yaml:"default_context,omitempty"`
 	DefaultNamespace string `mapstructure:"default_namespace,omitempty" json:"default_namespace,omitempty" yaml:"default_namespace,omitempty"`
 
@@ -32,6 +100,33 @@ type Kubeconfig struct {
 	Clusters     map[string]*Cluster     `mapstructure:"clusters,omitempty" json:"clusters,omitempty" yaml:"clusters,omitempty"`
 	AuthInfos    map[string]*AuthInfo    `mapstructure:"auth_infos,omitempty" json:"auth_infos,omitempty" yaml:"auth_infos,omitempty"`
 	Contexts     map[string]*Context     `mapstructure:"contexts,omitempty" json:"contexts,omitempty" yaml:"contexts,omitempty"`
+
+	ContextTemplates map[string]*ContextTemplate `mapstructure:"context_templates,omitempty" json:"context_templates,omitempty" yaml:"context_templates,omitempty"`
+
+	// Source is the file the kubeconfig was loaded from.
+	Source string `mapstructure:"-" json:"-" yaml:"-"`
+}
+
+// DefaultBackups is the number of backups kept of each rendered kubeconfig
+// when Config.Backups isn't set.
+const DefaultBackups = 3
+
+// Values of Workspace.Render. WorkspaceRenderSeparate is the default.
+const (
+	WorkspaceRenderSeparate = "separate"
+	WorkspaceRenderMerged   = "merged"
+)
+
+// Values of Kubeconfig.ProtectedConfirm. ProtectedConfirmYes is the default.
+const (
+	ProtectedConfirmYes      = "yes"
+	ProtectedConfirmTypeName = "type-name"
+)
+
+// KubeconfigColors are the supported values of Kubeconfig.Color.
+var KubeconfigColors = []string{
+	"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
+	"hi-black", "hi-red", "hi-green", "hi-yellow", "hi-blue", "hi-magenta", "hi-cyan", "hi-white",
 }
 
 type Cluster struct {
@@ -117,6 +212,7 @@ type Context struct {
 	Cluster          string                    `json:"cluster"`
 	AuthInfo         string                    `json:"user"`
 	Namespace        string                    `json:"namespace,omitempty"`
+	Labels           map[string]string         `mapstructure:"labels,omitempty" json:"labels,omitempty" yaml:"labels,omitempty"`
 	Extensions       map[string]runtime.Object `json:"extensions,omitempty"`
 	ImportRef        ImportRef                 `mapstructure:"import_ref" json:"import_ref" yaml:"import_ref"`
 }
@@ -130,10 +226,6 @@ type ImportRef struct {
 	AuthInfoName string `mapstructure:"auth_info" json:"auth_info" yaml:"auth_info"`
 }
 
-func (c *Config) Validate() error {
-	return nil
-}
-
 func (c *Config) Workspace(name string) *Workspace {
 	if ws, ok := c.Workspaces[name]; ok {
 		return ws
@@ -170,6 +262,12 @@ func (k *Kubeconfig) Context(name string) *Context {
 }
 
 func (c *Config) HasEncryptedAuthInfos() bool {
+	for _, authInfo := range c.AuthInfos {
+		if authInfo != nil && authInfo.HasEncryptedFields() {
+			return true
+		}
+	}
+
 	for _, kubeconfig := range c.Kubeconfigs {
 		if kubeconfig == nil {
 			continue
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"reflect"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/viper"
+)
+
+// DecodeHook is the viper option kubecfg.yaml is decoded with. It keeps the
+// default hooks of viper, except that strings are decoded into []byte fields,
+// such as certificate_authority_data, as they are instead of being split into
+// a list.
+func DecodeHook() viper.DecoderConfigOption {
+	return viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		stringToBytesHookFunc,
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+	))
+}
+
+func stringToBytesHookFunc(from, to reflect.Type, data any) (any, error) {
+	if from.Kind() != reflect.String || to != reflect.TypeOf([]byte(nil)) {
+		return data, nil
+	}
+	return []byte(data.(string)), nil
+}
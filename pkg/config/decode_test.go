@@ -0,0 +1,28 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeHookKeepsBytes(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	require.NoError(t, v.ReadConfig(strings.NewReader(`
+kubeconfigs:
+  prod:
+    clusters:
+      main:
+        certificate_authority_data: |
+          -----BEGIN CERTIFICATE-----
+          a,b
+          -----END CERTIFICATE-----
+`)))
+
+	var c Config
+	require.NoError(t, v.Unmarshal(&c, DecodeHook()))
+	require.Equal(t, "-----BEGIN CERTIFICATE-----\na,b\n-----END CERTIFICATE-----\n", string(c.Kubeconfigs["prod"].Clusters["main"].CertificateAuthorityData))
+}
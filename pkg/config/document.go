@@ -0,0 +1,294 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document is a kubecfg.yaml file parsed into a yaml.Node tree. Editing a
+// Document instead of a Config keeps comments and key order intact when the
+// file is written back. Paths use the same dotted notation as Diagnostic.
+type Document struct {
+	root *yaml.Node
+}
+
+// ParseDocument parses data into a Document. Empty input results in an empty
+// mapping.
+func ParseDocument(data []byte) (*Document, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		doc = yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}},
+		}
+	}
+
+	return &Document{root: &doc}, nil
+}
+
+// Root returns the top level node of the document, usually a mapping.
+func (d *Document) Root() *yaml.Node {
+	return d.root.Content[0]
+}
+
+// Lookup returns the value node at path or nil if it does not exist.
+func (d *Document) Lookup(path string) *yaml.Node {
+	trail := d.walk(splitPath(path))
+	if trail.remaining > 0 {
+		return nil
+	}
+	return trail.value
+}
+
+// Set replaces the value at path, creating intermediate mappings as needed.
+// Missing path segments are created as nested keys; use SetKey to add a key
+// that itself contains dots.
+func (d *Document) Set(path string, value *yaml.Node) error {
+	segments := splitPath(path)
+	if len(segments) == 0 {
+		return fmt.Errorf("path is empty")
+	}
+
+	trail := d.walk(segments)
+	if trail.remaining == 0 {
+		*trail.value = *value
+		return nil
+	}
+
+	node := trail.value
+	if !asMapping(node) {
+		return fmt.Errorf("%s: parent is not a mapping", path)
+	}
+
+	rest := segments[len(segments)-trail.remaining:]
+	for i, segment := range rest {
+		next := value
+		if i < len(rest)-1 {
+			next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: segment}, next)
+		node = next
+	}
+
+	return nil
+}
+
+// SetString sets the value at path to a string, see Set. An existing scalar is
+// updated in place so that its comments and quoting are kept.
+func (d *Document) SetString(path, value string) error {
+	if node := d.Lookup(path); node != nil && node.Kind == yaml.ScalarNode {
+		node.Tag = "!!str"
+		node.Value = value
+		return nil
+	}
+	return d.Set(path, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+}
+
+// SetKey sets key in the mapping at path, creating the mapping if needed. The
+// key is used verbatim.
+func (d *Document) SetKey(path, key string, value *yaml.Node) error {
+	node := d.Root()
+	if path != "" {
+		node = d.Lookup(path)
+		if node == nil {
+			if err := d.Set(path, &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}); err != nil {
+				return err
+			}
+			node = d.Lookup(path)
+		}
+	}
+
+	if !asMapping(node) {
+		return fmt.Errorf("%s is not a mapping", path)
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			*node.Content[i+1] = *value
+			return nil
+		}
+	}
+
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+	return nil
+}
+
+// Delete removes the mapping entry or sequence item at path. Returns false if
+// path does not exist.
+func (d *Document) Delete(path string) bool {
+	segments := splitPath(path)
+	if len(segments) == 0 {
+		return false
+	}
+
+	trail := d.walk(segments)
+	if trail.remaining > 0 || trail.parent == nil {
+		return false
+	}
+
+	parent := trail.parent
+	for i, child := range parent.Content {
+		if child != trail.value {
+			continue
+		}
+		if parent.Kind == yaml.MappingNode {
+			parent.Content = append(parent.Content[:i-1], parent.Content[i+1:]...)
+		} else {
+			parent.Content = append(parent.Content[:i], parent.Content[i+1:]...)
+		}
+		return true
+	}
+
+	return false
+}
+
+// Position returns the line and column of the node at path. For mapping
+// entries the position of the key is returned. If path only partially exists
+// in the document, the position of the deepest existing node is returned.
+func (d *Document) Position(path string) (int, int, bool) {
+	trail := d.walk(splitPath(path))
+	node := trail.key
+	if node == nil {
+		node = trail.value
+	}
+	if trail.parent == nil || node.Line == 0 {
+		return 0, 0, false
+	}
+
+	return node.Line, node.Column, true
+}
+
+// Decode decodes the value at path into out. It is a no-op if path does not
+// exist.
+func (d *Document) Decode(path string, out any) error {
+	node := d.Root()
+	if path != "" {
+		node = d.Lookup(path)
+	}
+	if node == nil {
+		return nil
+	}
+	return node.Decode(out)
+}
+
+// Bytes encodes the document back to YAML using two space indentation.
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(d.root); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// asMapping returns true if node is a mapping. Empty values, such as a key
+// without a value, are turned into an empty mapping in place. An empty flow
+// mapping, such as {}, is turned into a block mapping so that entries added
+// to it are written in block style like the rest of the file.
+func asMapping(node *yaml.Node) bool {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		node.Kind = yaml.MappingNode
+		node.Tag = "!!map"
+		node.Value = ""
+	}
+	if node.Kind == yaml.MappingNode && len(node.Content) == 0 {
+		node.Style &^= yaml.FlowStyle
+	}
+	return node.Kind == yaml.MappingNode
+}
+
+type documentTrail struct {
+	parent    *yaml.Node
+	key       *yaml.Node
+	value     *yaml.Node
+	remaining int
+}
+
+// walk follows segments as far as possible and returns the deepest node found
+// along with its parent and, for mapping entries, its key.
+func (d *Document) walk(segments []string) documentTrail {
+	trail := documentTrail{value: d.Root(), remaining: len(segments)}
+
+	for len(segments) > 0 {
+		node := trail.value
+		switch node.Kind {
+		case yaml.MappingNode:
+			key, value, consumed := lookupKey(node, segments)
+			if key == nil {
+				return trail
+			}
+			trail = documentTrail{parent: node, key: key, value: value}
+			segments = segments[consumed:]
+		case yaml.SequenceNode:
+			idx, err := strconv.Atoi(segments[0])
+			if err != nil || idx < 0 || idx >= len(node.Content) {
+				return trail
+			}
+			trail = documentTrail{parent: node, value: node.Content[idx]}
+			segments = segments[1:]
+		default:
+			return trail
+		}
+		trail.remaining = len(segments)
+	}
+
+	return trail
+}
+
+// lookupKey finds the map entry matching the longest prefix of segments. Map
+// keys may themselves contain dots, so kubeconfigs.prod.eu.path resolves to
+// the key "prod.eu" when it exists. Keys are matched case-insensitively, the
+// same way the config decoder matches them.
+func lookupKey(node *yaml.Node, segments []string) (*yaml.Node, *yaml.Node, int) {
+	for n := len(segments); n > 0; n-- {
+		want := strings.Join(segments[:n], ".")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if strings.EqualFold(node.Content[i].Value, want) {
+				return node.Content[i], node.Content[i+1], n
+			}
+		}
+	}
+	return nil, nil, 0
+}
+
+// splitPath splits a path such as workspaces.work.kubeconfigs[1] into
+// [workspaces work kubeconfigs 1].
+func splitPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+
+	var segments []string
+	for _, part := range strings.Split(path, ".") {
+		for {
+			open := strings.IndexByte(part, '[')
+			if open < 0 || !strings.HasSuffix(part, "]") {
+				segments = append(segments, part)
+				break
+			}
+			if open > 0 {
+				segments = append(segments, part[:open])
+			}
+			closing := strings.IndexByte(part[open:], ']') + open
+			segments = append(segments, part[open+1:closing])
+			part = part[closing+1:]
+			if part == "" {
+				break
+			}
+		}
+	}
+
+	return segments
+}
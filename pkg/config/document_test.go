@@ -0,0 +1,99 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestDocumentEditsPreserveComments(t *testing.T) {
+	doc, err := ParseDocument([]byte(`# shared team config
+version: v1
+kubeconfigs:
+  prod.eu:
+    # production
+    path: /tmp/prod
+    aliases:
+      - prod
+      - eu
+`))
+	require.NoError(t, err)
+
+	require.Equal(t, "/tmp/prod", doc.Lookup("kubeconfigs.prod.eu.path").Value)
+	require.True(t, doc.Delete("kubeconfigs.prod.eu.aliases[0]"))
+	require.False(t, doc.Delete("kubeconfigs.prod.eu.missing"))
+	require.NoError(t, doc.Set("kubeconfigs.prod.eu.default_namespace", &yaml.Node{Kind: yaml.ScalarNode, Value: "payments"}))
+	require.NoError(t, doc.SetKey("kubeconfigs", "dev.eu", &yaml.Node{Kind: yaml.MappingNode}))
+	require.NoError(t, doc.Set("default_workspace", &yaml.Node{Kind: yaml.ScalarNode, Value: "work"}))
+
+	out, err := doc.Bytes()
+	require.NoError(t, err)
+	require.Equal(t, `# shared team config
+version: v1
+kubeconfigs:
+  prod.eu:
+    # production
+    path: /tmp/prod
+    aliases:
+      - eu
+    default_namespace: payments
+  dev.eu: {}
+default_workspace: work
+`, string(out))
+}
+
+func TestParseDocumentEmpty(t *testing.T) {
+	doc, err := ParseDocument(nil)
+	require.NoError(t, err)
+	require.NoError(t, doc.Set("kubeconfigs.demo.path", &yaml.Node{Kind: yaml.ScalarNode, Value: "/tmp/demo"}))
+
+	out, err := doc.Bytes()
+	require.NoError(t, err)
+	require.Equal(t, "kubeconfigs:\n  demo:\n    path: /tmp/demo\n", string(out))
+}
+
+func TestDocumentSetKeyInEmptyFlowMapping(t *testing.T) {
+	doc, err := ParseDocument([]byte(`version: v1
+kubeconfigs: {}
+workspaces: {}
+`))
+	require.NoError(t, err)
+
+	kubeconfig := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	kubeconfig.Content = append(kubeconfig.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "path"},
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "/tmp/prod"},
+	)
+	require.NoError(t, doc.SetKey("kubeconfigs", "prod", kubeconfig))
+	require.NoError(t, doc.SetString("workspaces.work.description", "Work"))
+
+	out, err := doc.Bytes()
+	require.NoError(t, err)
+	require.Equal(t, `version: v1
+kubeconfigs:
+  prod:
+    path: /tmp/prod
+workspaces:
+  work:
+    description: Work
+`, string(out))
+}
+
+func TestDocumentSetStringKeepsComments(t *testing.T) {
+	doc, err := ParseDocument([]byte(`kubeconfigs:
+  prod:
+    current_context: "admin" # picked by hand
+`))
+	require.NoError(t, err)
+	require.NoError(t, doc.SetString("kubeconfigs.prod.current_context", "view"))
+	require.NoError(t, doc.SetString("kubeconfigs.prod.default_namespace", "payments"))
+
+	out, err := doc.Bytes()
+	require.NoError(t, err)
+	require.Equal(t, `kubeconfigs:
+  prod:
+    current_context: "view" # picked by hand
+    default_namespace: payments
+`, string(out))
+}
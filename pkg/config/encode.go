@@ -0,0 +1,152 @@
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Marshal returns c as kubecfg.yaml, using the same keys as the decoder and the
+// published schema. Zero values are omitted, except for entries that are set
+// but empty, such as selector: {}, since those are meaningful.
+func (c *Config) Marshal() ([]byte, error) {
+	node, err := encodeValue(reflect.ValueOf(c).Elem())
+	if err != nil {
+		return nil, err
+	}
+	if node == nil {
+		node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Encode returns v, such as a Kubeconfig or Workspace, as a node using the
+// same keys as Marshal. It is used to add entries to a Document.
+func Encode(v any) (*yaml.Node, error) {
+	node, err := encodeValue(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	if node == nil {
+		node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	return node, nil
+}
+
+// encodeValue returns the node for v, or nil if v is a zero value that should
+// be omitted.
+func encodeValue(v reflect.Value) (*yaml.Node, error) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil, nil
+		}
+		node, err := encodeValue(v.Elem())
+		if node != nil || err != nil {
+			return node, err
+		}
+		// A pointer is set on purpose, such as backups: 0.
+		switch v.Elem().Kind() {
+		case reflect.Struct:
+			node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		case reflect.Bool, reflect.Int, reflect.String:
+			node = &yaml.Node{}
+			err = node.Encode(v.Elem().Interface())
+		}
+		return node, err
+	case reflect.Interface:
+		// Extensions hold runtime.Objects that can't be written back.
+		return nil, nil
+	case reflect.Struct:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for i := range v.NumField() {
+			key, ok := schemaKey(v.Type().Field(i))
+			if !ok {
+				continue
+			}
+			value, err := encodeValue(v.Field(i))
+			if err != nil {
+				return nil, err
+			}
+			if value != nil {
+				node.Content = append(node.Content, scalarNode(key), value)
+			}
+		}
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return node, nil
+	case reflect.Map:
+		if v.IsNil() || v.Type().Key().Kind() != reflect.String {
+			return nil, nil
+		}
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		slices.Sort(keys)
+
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, key := range keys {
+			value, err := encodeValue(v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key())))
+			if err != nil {
+				return nil, err
+			}
+			if value == nil {
+				value = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+			}
+			node.Content = append(node.Content, scalarNode(key), value)
+		}
+		return node, nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if v.Len() == 0 {
+				return nil, nil
+			}
+			return scalarNode(string(v.Bytes())), nil
+		}
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for i := range v.Len() {
+			value, err := encodeValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			if value == nil {
+				value = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+			}
+			node.Content = append(node.Content, value)
+		}
+		return node, nil
+	}
+
+	if v.IsZero() {
+		return nil, nil
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(v.Interface()); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func scalarNode(value string) *yaml.Node {
+	node := &yaml.Node{}
+	// Encoding a string can't fail.
+	_ = node.Encode(value)
+	return node
+}
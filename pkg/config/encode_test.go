@@ -0,0 +1,85 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalUsesDecoderKeys(t *testing.T) {
+	cfg := &Config{
+		Version: CurrentVersion,
+		Workspaces: map[string]*Workspace{
+			"all": {Kubeconfigs: []string{"prod"}, Selector: &LabelSelector{}},
+		},
+		Kubeconfigs: map[string]*Kubeconfig{
+			"prod": {
+				Path:           "@/prod.yaml",
+				CurrentContext: "admin",
+				Clusters:       map[string]*Cluster{"main": {Server: "https://prod.example.com"}},
+				AuthInfos:      map[string]*AuthInfo{"admin": {ClientCertificate: "/admin.crt", ClientKeyData: []byte("key")}},
+				Contexts:       map[string]*Context{"admin": {Cluster: "main", AuthInfo: "admin", Namespace: "0123"}},
+			},
+		},
+	}
+
+	b, err := cfg.Marshal()
+	require.NoError(t, err)
+	require.Equal(t, `version: v1
+workspaces:
+  all:
+    kubeconfigs:
+      - prod
+    selector: {}
+kubeconfigs:
+  prod:
+    path: '@/prod.yaml'
+    current_context: admin
+    clusters:
+      main:
+        server: https://prod.example.com
+    auth_infos:
+      admin:
+        clientCertificate: /admin.crt
+        clientKeyData: key
+    contexts:
+      admin:
+        cluster: main
+        authInfo: admin
+        namespace: "0123"
+`, string(b))
+}
+
+func TestMarshalRoundTrips(t *testing.T) {
+	cfg := newOverlaysTestConfig()
+
+	b, err := cfg.Marshal()
+	require.NoError(t, err)
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	require.NoError(t, v.ReadConfig(bytes.NewReader(b)))
+
+	var decoded Config
+	require.NoError(t, v.Unmarshal(&decoded, DecodeHook()))
+	require.Equal(t, cfg, &decoded)
+}
+
+func TestMarshalKeepsPointersToZeroValues(t *testing.T) {
+	backups := 0
+	cfg := &Config{Version: CurrentVersion, Backups: &backups}
+
+	b, err := cfg.Marshal()
+	require.NoError(t, err)
+	require.Contains(t, string(b), "backups: 0\n")
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	require.NoError(t, v.ReadConfig(bytes.NewReader(b)))
+
+	var decoded Config
+	require.NoError(t, v.Unmarshal(&decoded, DecodeHook()))
+	require.Equal(t, 0, decoded.BackupCount())
+}
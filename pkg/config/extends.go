@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ExtendsError is returned when the extends chain of a kubeconfig can't be
+// resolved. Kubeconfig is the kubeconfig whose extends is at fault and Chain
+// is the chain followed, starting at the kubeconfig being resolved.
+type ExtendsError struct {
+	Kubeconfig string
+	Chain      []string
+	Missing    string
+}
+
+func (e *ExtendsError) Error() string {
+	return fmt.Sprintf("kubeconfigs.%s.extends %s", e.Kubeconfig, e.Message())
+}
+
+// Message returns the error without the field path.
+func (e *ExtendsError) Message() string {
+	chain := strings.Join(e.Chain, " -> ")
+	if e.Missing != "" {
+		return fmt.Sprintf("references missing kubeconfig %q (%s)", e.Missing, chain)
+	}
+	return fmt.Sprintf("forms a cycle (%s)", chain)
+}
+
+// ResolveKubeconfig returns the kubeconfig called name with everything it
+// inherits through extends merged in. Clusters, auth infos, contexts, context
+// templates and login sources are merged by key, and fields set on an entry
+// override the fields of the inherited entry with the same key.
+// current_context and default_namespace are inherited when not set. Everything
+// else is never inherited. The config is not modified.
+func (c *Config) ResolveKubeconfig(name string) (*Kubeconfig, error) {
+	kc := c.Kubeconfig(name)
+	if kc == nil {
+		return nil, fmt.Errorf("kubeconfigs.%s is nil", name)
+	}
+
+	chain := []string{name}
+	for current := kc; strings.TrimSpace(current.Extends) != ""; {
+		base := strings.TrimSpace(current.Extends)
+		last := chain[len(chain)-1]
+
+		if slices.Contains(chain, base) {
+			// Every member of a cycle is at fault, blame the one being
+			// resolved if it is part of it.
+			if base == name {
+				last = name
+			}
+			return nil, &ExtendsError{Kubeconfig: last, Chain: append(chain, base)}
+		}
+		chain = append(chain, base)
+
+		next, ok := c.Kubeconfigs[base]
+		if !ok {
+			return nil, &ExtendsError{Kubeconfig: last, Chain: chain, Missing: base}
+		}
+		if next == nil {
+			break
+		}
+		current = next
+	}
+
+	// Merge from the root of the chain down so that the closest definition wins.
+	resolved := &Kubeconfig{}
+	for _, link := range slices.Backward(chain) {
+		if kc := c.Kubeconfigs[link]; kc != nil {
+			resolved = extendKubeconfig(resolved, kc)
+		}
+	}
+
+	return resolved, nil
+}
+
+func extendKubeconfig(base, kc *Kubeconfig) *Kubeconfig {
+	res := *kc
+
+	res.CurrentContext = firstNonEmpty(kc.CurrentContext, base.CurrentContext)
+	res.DefaultNamespace = firstNonEmpty(kc.DefaultNamespace, base.DefaultNamespace)
+
+	res.Clusters = extendEntries(base.Clusters, kc.Clusters)
+	res.AuthInfos = extendEntries(base.AuthInfos, kc.AuthInfos)
+	res.Contexts = extendEntries(base.Contexts, kc.Contexts)
+	res.LoginSources = extendEntries(base.LoginSources, kc.LoginSources)
+	res.ContextTemplates = extendEntries(base.ContextTemplates, kc.ContextTemplates)
+
+	return &res
+}
+
+// extendEntries returns the union of base and local. Entries present in both
+// are merged field by field with extendEntry.
+func extendEntries[T any](base, local map[string]*T) map[string]*T {
+	if len(base) == 0 {
+		return local
+	}
+
+	res := make(map[string]*T, len(base)+len(local))
+	for name, entry := range base {
+		res[name] = entry
+	}
+	for name, entry := range local {
+		res[name] = extendEntry(res[name], entry)
+	}
+
+	return res
+}
+
+// extendEntry returns a copy of base with every non-zero field of local set on
+// it. A nil local entry is kept as nil so that validation still reports it.
+func extendEntry[T any](base, local *T) *T {
+	if base == nil || local == nil {
+		return local
+	}
+
+	res := *base
+	dst := reflect.ValueOf(&res).Elem()
+	src := reflect.ValueOf(local).Elem()
+	for i := range src.NumField() {
+		if field := src.Field(i); !field.IsZero() {
+			dst.Field(i).Set(field)
+		}
+	}
+
+	return &res
+}
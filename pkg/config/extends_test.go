@@ -0,0 +1,109 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newExtendsTestConfig() *Config {
+	return &Config{
+		Kubeconfigs: map[string]*Kubeconfig{
+			"staging": {
+				Path:             "/tmp/staging",
+				Aliases:          []string{"stg"},
+				DefaultNamespace: "staging",
+				CurrentContext:   "admin",
+				Clusters: map[string]*Cluster{
+					"main": {Server: "https://staging.example.com", CertificateAuthority: "/etc/ca.pem"},
+				},
+				AuthInfos: map[string]*AuthInfo{
+					"admin": {Token: "token"},
+				},
+				Contexts: map[string]*Context{
+					"admin": {Cluster: "main", AuthInfo: "admin"},
+				},
+			},
+			"production": {
+				Extends:          "staging",
+				Path:             "/tmp/production",
+				DefaultNamespace: "production",
+				Clusters: map[string]*Cluster{
+					"main": {Server: "https://production.example.com"},
+				},
+				Contexts: map[string]*Context{
+					"readonly": {Cluster: "main", AuthInfo: "admin", Namespace: "audit"},
+				},
+			},
+		},
+	}
+}
+
+func TestResolveKubeconfigInheritsFromBase(t *testing.T) {
+	cfg := newExtendsTestConfig()
+
+	kc, err := cfg.ResolveKubeconfig("production")
+	require.NoError(t, err)
+
+	require.Equal(t, "/tmp/production", kc.Path)
+	require.Empty(t, kc.Aliases)
+	require.Equal(t, "production", kc.DefaultNamespace)
+	require.Equal(t, "admin", kc.CurrentContext)
+	require.Equal(t, &Cluster{Server: "https://production.example.com", CertificateAuthority: "/etc/ca.pem"}, kc.Clusters["main"])
+	require.Equal(t, "token", kc.AuthInfos["admin"].Token)
+	require.ElementsMatch(t, []string{"admin", "readonly"}, sortedKeys(kc.Contexts))
+
+	// The base is left untouched.
+	require.Equal(t, "https://staging.example.com", cfg.Kubeconfigs["staging"].Clusters["main"].Server)
+	require.Len(t, cfg.Kubeconfigs["production"].Contexts, 1)
+}
+
+func TestResolveKubeconfigReportsCycleWithChain(t *testing.T) {
+	cfg := newExtendsTestConfig()
+	cfg.Kubeconfigs["staging"].Extends = "dev"
+	cfg.Kubeconfigs["dev"] = &Kubeconfig{Path: "/tmp/dev", Extends: "production"}
+
+	_, err := cfg.ResolveKubeconfig("production")
+	require.EqualError(t, err, "kubeconfigs.production.extends forms a cycle (production -> staging -> dev -> production)")
+}
+
+func TestResolveKubeconfigBlamesCycleMember(t *testing.T) {
+	cfg := newExtendsTestConfig()
+	cfg.Kubeconfigs["staging"].Extends = "dev"
+	cfg.Kubeconfigs["dev"] = &Kubeconfig{Path: "/tmp/dev", Extends: "staging"}
+
+	_, err := cfg.ResolveKubeconfig("production")
+	require.EqualError(t, err, "kubeconfigs.dev.extends forms a cycle (production -> staging -> dev -> staging)")
+}
+
+func TestResolveKubeconfigReportsMissingBaseWithChain(t *testing.T) {
+	cfg := newExtendsTestConfig()
+	cfg.Kubeconfigs["staging"].Extends = "base"
+
+	_, err := cfg.ResolveKubeconfig("production")
+	require.EqualError(t, err, `kubeconfigs.staging.extends references missing kubeconfig "base" (production -> staging -> base)`)
+}
+
+func TestCompileResolvesExtends(t *testing.T) {
+	rt, err := NewCompiler().Compile(newExtendsTestConfig())
+	require.NoError(t, err)
+
+	prod := rt.Kubeconfigs["production"]
+	require.Equal(t, "https://production.example.com", prod.Config.Clusters["main"].Server)
+	require.Equal(t, "/etc/ca.pem", prod.Config.Clusters["main"].CertificateAuthority)
+	require.Equal(t, "production", prod.Config.Contexts["admin"].Namespace)
+	require.Equal(t, "audit", prod.Config.Contexts["readonly"].Namespace)
+	require.Equal(t, "admin", prod.Config.CurrentContext)
+	require.Equal(t, "https://staging.example.com", rt.Kubeconfigs["staging"].Config.Clusters["main"].Server)
+}
+
+func TestDiagnoseResolvesExtends(t *testing.T) {
+	cfg := newExtendsTestConfig()
+	require.Empty(t, cfg.Diagnose())
+
+	cfg.Kubeconfigs["staging"].Extends = "production"
+	require.Equal(t, []string{
+		"error: kubeconfigs.production.extends forms a cycle (production -> staging -> production)",
+		"error: kubeconfigs.staging.extends forms a cycle (staging -> production -> staging)",
+	}, diagnosticStrings(cfg.Diagnose()))
+}
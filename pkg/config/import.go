@@ -0,0 +1,112 @@
+package config
+
+import (
+	"fmt"
+
+	api "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// SecretEncryptor encrypts secret auth info fields, see AuthInfo.Encrypt.
+type SecretEncryptor interface {
+	EncryptString(plaintext string) (string, error)
+}
+
+// FromAPIConfig converts a kubeconfig, as loaded by clientcmd, into a
+// Kubeconfig. Extensions and the exec plugin config can't be written to
+// kubecfg.yaml and are left out.
+func FromAPIConfig(in *api.Config) *Kubeconfig {
+	out := &Kubeconfig{
+		CurrentContext: in.CurrentContext,
+		Clusters:       make(map[string]*Cluster, len(in.Clusters)),
+		AuthInfos:      make(map[string]*AuthInfo, len(in.AuthInfos)),
+		Contexts:       make(map[string]*Context, len(in.Contexts)),
+	}
+
+	for name, c := range in.Clusters {
+		out.Clusters[name] = &Cluster{
+			Server:                   c.Server,
+			TLSServerName:            c.TLSServerName,
+			InsecureSkipTLSVerify:    c.InsecureSkipTLSVerify,
+			CertificateAuthority:     c.CertificateAuthority,
+			CertificateAuthorityData: c.CertificateAuthorityData,
+			ProxyURL:                 c.ProxyURL,
+			DisableCompression:       c.DisableCompression,
+		}
+	}
+
+	for name, a := range in.AuthInfos {
+		authInfo := &AuthInfo{
+			ClientCertificate:     a.ClientCertificate,
+			ClientCertificateData: a.ClientCertificateData,
+			ClientKey:             a.ClientKey,
+			ClientKeyData:         a.ClientKeyData,
+			Token:                 a.Token,
+			TokenFile:             a.TokenFile,
+			Impersonate:           a.Impersonate,
+			ImpersonateUID:        a.ImpersonateUID,
+			ImpersonateGroups:     a.ImpersonateGroups,
+			ImpersonateUserExtra:  a.ImpersonateUserExtra,
+			Username:              a.Username,
+			Password:              a.Password,
+			AuthProvider:          (*AuthProviderConfig)(a.AuthProvider),
+		}
+		if a.Exec != nil {
+			env := make([]ExecEnvVar, len(a.Exec.Env))
+			for i, e := range a.Exec.Env {
+				env[i] = ExecEnvVar{Name: e.Name, Value: e.Value}
+			}
+			authInfo.Exec = &ExecConfig{
+				Command:                 a.Exec.Command,
+				Args:                    a.Exec.Args,
+				Env:                     env,
+				APIVersion:              a.Exec.APIVersion,
+				InstallHint:             a.Exec.InstallHint,
+				ProvideClusterInfo:      a.Exec.ProvideClusterInfo,
+				InteractiveMode:         ExecInteractiveMode(a.Exec.InteractiveMode),
+				StdinUnavailable:        a.Exec.StdinUnavailable,
+				StdinUnavailableMessage: a.Exec.StdinUnavailableMessage,
+			}
+		}
+		out.AuthInfos[name] = authInfo
+	}
+
+	for name, c := range in.Contexts {
+		out.Contexts[name] = &Context{
+			Cluster:   c.Cluster,
+			AuthInfo:  c.AuthInfo,
+			Namespace: c.Namespace,
+		}
+	}
+
+	return out
+}
+
+// Encrypt moves the token, password and client key data of a into their
+// encrypted counterparts.
+func (a *AuthInfo) Encrypt(enc SecretEncryptor) error {
+	if a.Token != "" {
+		encrypted, err := enc.EncryptString(a.Token)
+		if err != nil {
+			return fmt.Errorf("encrypt token: %w", err)
+		}
+		a.EncryptedToken, a.Token = encrypted, ""
+	}
+
+	if a.Password != "" {
+		encrypted, err := enc.EncryptString(a.Password)
+		if err != nil {
+			return fmt.Errorf("encrypt password: %w", err)
+		}
+		a.EncryptedPassword, a.Password = encrypted, ""
+	}
+
+	if len(a.ClientKeyData) > 0 {
+		encrypted, err := enc.EncryptString(string(a.ClientKeyData))
+		if err != nil {
+			return fmt.Errorf("encrypt client key data: %w", err)
+		}
+		a.EncryptedClientKeyData, a.ClientKeyData = []byte(encrypted), nil
+	}
+
+	return nil
+}
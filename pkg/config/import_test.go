@@ -0,0 +1,55 @@
+package config
+
+import (
+	"testing"
+
+	"filippo.io/age"
+	"github.com/amimof/kubecfg/pkg/decrypt"
+	"github.com/stretchr/testify/require"
+	api "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestFromAPIConfig(t *testing.T) {
+	in := api.NewConfig()
+	in.CurrentContext = "admin"
+	in.Clusters["main"] = &api.Cluster{Server: "https://prod.example.com", CertificateAuthorityData: []byte("ca")}
+	in.AuthInfos["admin"] = &api.AuthInfo{
+		Token: "secret",
+		Exec: &api.ExecConfig{
+			Command: "kubelogin",
+			Env:     []api.ExecEnvVar{{Name: "TENANT", Value: "acme"}},
+		},
+	}
+	in.Contexts["admin"] = &api.Context{Cluster: "main", AuthInfo: "admin", Namespace: "apps", LocationOfOrigin: "/tmp/prod.yaml"}
+
+	out := FromAPIConfig(in)
+	require.Equal(t, "admin", out.CurrentContext)
+	require.Equal(t, &Cluster{Server: "https://prod.example.com", CertificateAuthorityData: []byte("ca")}, out.Clusters["main"])
+	require.Equal(t, "secret", out.AuthInfos["admin"].Token)
+	require.Equal(t, []ExecEnvVar{{Name: "TENANT", Value: "acme"}}, out.AuthInfos["admin"].Exec.Env)
+	require.Equal(t, &Context{Cluster: "main", AuthInfo: "admin", Namespace: "apps"}, out.Contexts["admin"])
+}
+
+func TestAuthInfoEncrypt(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	encryptor, err := decrypt.NewAgeEncryptor(identity.Recipient())
+	require.NoError(t, err)
+	decryptor, err := decrypt.NewAgeDecryptor(identity)
+	require.NoError(t, err)
+
+	authInfo := &AuthInfo{Token: "secret", ClientKeyData: []byte("key"), ClientCertificateData: []byte("cert")}
+	require.NoError(t, authInfo.Encrypt(encryptor))
+
+	require.Empty(t, authInfo.Token)
+	require.Nil(t, authInfo.ClientKeyData)
+	require.Equal(t, []byte("cert"), authInfo.ClientCertificateData)
+	require.True(t, authInfo.HasEncryptedFields())
+
+	token, err := decryptor.DecryptString(authInfo.EncryptedToken)
+	require.NoError(t, err)
+	require.Equal(t, "secret", token)
+	key, err := decryptor.DecryptBytes(authInfo.EncryptedClientKeyData)
+	require.NoError(t, err)
+	require.Equal(t, []byte("key"), key)
+}
@@ -0,0 +1,359 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ConfDir returns the directory whose *.yaml files are merged into the config
+// file at path. For ~/.config/kubecfg.yaml that is ~/.config/kubecfg.d.
+func ConfDir(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".d"
+}
+
+// LoadFile decodes a single kubecfg YAML file the same way the main config
+// file is decoded. Includes are not resolved.
+func LoadFile(path string) (*Config, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	var c Config
+	if err := v.Unmarshal(&c, DecodeHook()); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &c, nil
+}
+
+// ResolveIncludes merges every file matched by the include globs and every
+// *.yaml file in ConfDir(file) into c, in that order. file is the path c was
+// loaded from and is recorded as the source of its kubeconfigs and workspaces.
+//
+// Maps merge by key. Defining the same kubeconfig, workspace or var in two
+// files is an error, as is setting a top level value such as default_workspace
+// to different values. Lists such as identity_files are concatenated. Fragments
+// can not include other files.
+func (c *Config) ResolveIncludes(file string) error {
+	c.File, c.Sources = file, make(map[string]string)
+	m := &configMerger{cfg: c, sources: make(map[string]string)}
+	m.record(c, file)
+
+	files, err := includedFiles(c.Include, file)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, f := range files {
+		fragment, err := LoadFile(f)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		errs = append(errs, m.merge(fragment, f)...)
+	}
+
+	return errors.Join(errs...)
+}
+
+// SourceOf returns the file that defined the value at the dotted path, or an
+// empty string if it is not known.
+func (c *Config) SourceOf(path string) string {
+	if name, ok := pathEntry(path, "kubeconfigs", c.Kubeconfigs); ok {
+		return c.Kubeconfigs[name].Source
+	}
+	if name, ok := pathEntry(path, "workspaces", c.Workspaces); ok {
+		return c.Workspaces[name].Source
+	}
+
+	// Sources holds shared entries and vars, whose names may contain dots
+	// as well.
+	var match string
+	for entry := range c.Sources {
+		if len(entry) > len(match) && (path == entry || strings.HasPrefix(path, entry+".") || strings.HasPrefix(path, entry+"[")) {
+			match = entry
+		}
+	}
+	return c.Sources[match]
+}
+
+// LocalPath returns path as it is spelled in the file SourceOf returns for it.
+// The two only differ for overlays, which are numbered across all files once
+// merged.
+func (c *Config) LocalPath(path string) string {
+	rest, ok := strings.CutPrefix(path, "overlays[")
+	if !ok {
+		return path
+	}
+	end := strings.IndexByte(rest, ']')
+	if end < 0 {
+		return path
+	}
+	i, err := strconv.Atoi(rest[:end])
+	if err != nil {
+		return path
+	}
+
+	source, local := c.Sources[overlayEntry(i)], 0
+	for j := range i {
+		if c.Sources[overlayEntry(j)] == source {
+			local++
+		}
+	}
+	return overlayEntry(local) + rest[end+1:]
+}
+
+func overlayEntry(i int) string {
+	return fmt.Sprintf("overlays[%d]", i)
+}
+
+// pathEntry returns the key of m that path points into, for example vgr for
+// kubeconfigs.vgr.path. The longest matching key wins since keys may contain
+// dots.
+func pathEntry[V any](path, prefix string, m map[string]*V) (string, bool) {
+	rest, ok := strings.CutPrefix(path, prefix+".")
+	if !ok {
+		return "", false
+	}
+
+	var match string
+	for name, v := range m {
+		if v == nil || len(name) <= len(match) {
+			continue
+		}
+		if rest == name || strings.HasPrefix(rest, name+".") || strings.HasPrefix(rest, name+"[") {
+			match = name
+		}
+	}
+
+	return match, match != ""
+}
+
+func includedFiles(patterns []string, file string) ([]string, error) {
+	dir := filepath.Dir(file)
+	seen := map[string]struct{}{filepath.Clean(file): {}}
+
+	var files []string
+	add := func(matches []string) {
+		slices.Sort(matches)
+		for _, match := range matches {
+			match = filepath.Clean(match)
+			if _, ok := seen[match]; ok {
+				continue
+			}
+			seen[match] = struct{}{}
+			files = append(files, match)
+		}
+	}
+
+	for i, pattern := range patterns {
+		pattern = ResolvePath("", pattern)
+		if pattern == "" {
+			continue
+		}
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("include[%d] %q: %w", i, patterns[i], err)
+		}
+		add(matches)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(ConfDir(file), "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	add(matches)
+
+	return files, nil
+}
+
+type configMerger struct {
+	cfg *Config
+	// sources maps top level values to the file that set them. Shared
+	// entries and vars are recorded in cfg.Sources.
+	sources map[string]string
+}
+
+// record sets file as the source of everything defined by c that does not
+// already have one.
+func (m *configMerger) record(c *Config, file string) {
+	for _, kc := range c.Kubeconfigs {
+		if kc != nil && kc.Source == "" {
+			kc.Source = file
+		}
+	}
+	for _, ws := range c.Workspaces {
+		if ws != nil && ws.Source == "" {
+			ws.Source = file
+		}
+	}
+	for key, names := range map[string][]string{
+		"clusters":      sortedKeys(c.Clusters),
+		"auth_infos":    sortedKeys(c.AuthInfos),
+		"login_sources": sortedKeys(c.LoginSources),
+		"vars":          sortedKeys(c.Vars),
+	} {
+		for _, name := range names {
+			if _, ok := m.cfg.Sources[key+"."+name]; !ok {
+				m.cfg.Sources[key+"."+name] = file
+			}
+		}
+	}
+	// The overlays of c are the last ones appended.
+	first := len(m.cfg.Overlays) - len(c.Overlays)
+	for i := range c.Overlays {
+		m.cfg.Sources[overlayEntry(first+i)] = file
+	}
+	for key, value := range map[string]string{
+		"version":           c.Version,
+		"default_workspace": c.DefaultWorkspace,
+		"base_dir":          c.BaseDir,
+	} {
+		if _, ok := m.sources[key]; !ok && value != "" {
+			m.sources[key] = file
+		}
+	}
+	if _, ok := m.sources["backups"]; !ok && c.Backups != nil {
+		m.sources["backups"] = file
+	}
+}
+
+func (m *configMerger) merge(src *Config, file string) []error {
+	var errs []error
+
+	if len(src.Include) > 0 {
+		errs = append(errs, fmt.Errorf("%s: include is only supported in the main config file", file))
+	}
+
+	if err := CheckVersion(src.Version); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", file, err))
+	}
+
+	errs = append(errs, m.mergeValue("version", &m.cfg.Version, src.Version, file)...)
+	errs = append(errs, m.mergeValue("default_workspace", &m.cfg.DefaultWorkspace, src.DefaultWorkspace, file)...)
+	errs = append(errs, m.mergeValue("base_dir", &m.cfg.BaseDir, src.BaseDir, file)...)
+	errs = append(errs, m.mergeBackups(src.Backups, file)...)
+
+	for _, name := range sortedKeys(src.Kubeconfigs) {
+		if existing, ok := m.cfg.Kubeconfigs[name]; ok {
+			errs = append(errs, fmt.Errorf("kubeconfigs.%s in %s is already defined in %s", name, file, sourceName(existing.Source)))
+			continue
+		}
+		if m.cfg.Kubeconfigs == nil {
+			m.cfg.Kubeconfigs = make(map[string]*Kubeconfig)
+		}
+		m.cfg.Kubeconfigs[name] = src.Kubeconfigs[name]
+	}
+
+	for _, name := range sortedKeys(src.Workspaces) {
+		if existing, ok := m.cfg.Workspaces[name]; ok {
+			errs = append(errs, fmt.Errorf("workspaces.%s in %s is already defined in %s", name, file, sourceName(existing.Source)))
+			continue
+		}
+		if m.cfg.Workspaces == nil {
+			m.cfg.Workspaces = make(map[string]*Workspace)
+		}
+		m.cfg.Workspaces[name] = src.Workspaces[name]
+	}
+
+	errs = append(errs, mergeShared(m, "clusters", &m.cfg.Clusters, src.Clusters, file)...)
+	errs = append(errs, mergeShared(m, "auth_infos", &m.cfg.AuthInfos, src.AuthInfos, file)...)
+	errs = append(errs, mergeShared(m, "login_sources", &m.cfg.LoginSources, src.LoginSources, file)...)
+	errs = append(errs, m.mergeVars(src.Vars, file)...)
+
+	m.cfg.IdentityFiles = appendMissing(m.cfg.IdentityFiles, src.IdentityFiles...)
+	m.cfg.Overlays = append(m.cfg.Overlays, src.Overlays...)
+	m.cfg.Lint.Disable = appendMissing(m.cfg.Lint.Disable, src.Lint.Disable...)
+
+	m.record(src, file)
+
+	return errs
+}
+
+// mergeShared adds the shared entries of src to dst. Entries can only be
+// defined in one file.
+func mergeShared[V any](m *configMerger, key string, dst *map[string]*V, src map[string]*V, file string) []error {
+	var errs []error
+	for _, name := range sortedKeys(src) {
+		if _, ok := (*dst)[name]; ok {
+			errs = append(errs, fmt.Errorf("%s.%s in %s is already defined in %s", key, name, file, sourceName(m.cfg.Sources[key+"."+name])))
+			continue
+		}
+		if *dst == nil {
+			*dst = make(map[string]*V)
+		}
+		(*dst)[name] = src[name]
+	}
+	return errs
+}
+
+// mergeVars adds vars to the config. Like shared entries, a var can only be
+// defined in one file.
+func (m *configMerger) mergeVars(vars map[string]string, file string) []error {
+	var errs []error
+	for _, name := range sortedKeys(vars) {
+		if _, ok := m.cfg.Vars[name]; ok {
+			errs = append(errs, fmt.Errorf("vars.%s in %s is already defined in %s", name, file, sourceName(m.cfg.Sources["vars."+name])))
+			continue
+		}
+		if m.cfg.Vars == nil {
+			m.cfg.Vars = make(map[string]string)
+		}
+		m.cfg.Vars[name] = vars[name]
+	}
+	return errs
+}
+
+func (m *configMerger) mergeValue(key string, dst *string, value, file string) []error {
+	if value == "" || value == *dst {
+		return nil
+	}
+	if *dst != "" {
+		return []error{fmt.Errorf("%s %q in %s conflicts with %q set in %s", key, value, file, *dst, sourceName(m.sources[key]))}
+	}
+	*dst = value
+	return nil
+}
+
+// mergeBackups is like mergeValue for backups, which is set when it isn't nil.
+func (m *configMerger) mergeBackups(value *int, file string) []error {
+	if value == nil || (m.cfg.Backups != nil && *m.cfg.Backups == *value) {
+		return nil
+	}
+	if m.cfg.Backups != nil {
+		return []error{fmt.Errorf("backups %d in %s conflicts with %d set in %s", *value, file, *m.cfg.Backups, sourceName(m.sources["backups"]))}
+	}
+	m.cfg.Backups = value
+	return nil
+}
+
+func sourceName(file string) string {
+	if file == "" {
+		return "the main config file"
+	}
+	return file
+}
+
+func appendMissing(dst []string, values ...string) []string {
+	for _, value := range values {
+		if !slices.Contains(dst, value) {
+			dst = append(dst, value)
+		}
+	}
+	return dst
+}
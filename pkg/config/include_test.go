@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveIncludesMergesIncludesAndConfDir(t *testing.T) {
+	dir := t.TempDir()
+	main := writeIncludeTestFile(t, dir, "kubecfg.yaml", `version: v1
+include:
+  - teams/*.yaml
+identity_files:
+  - ~/age.txt
+vars:
+  team: platform
+workspaces:
+  work:
+    kubeconfigs: [prod]
+kubeconfigs:
+  prod:
+    path: /tmp/prod
+`)
+	writeIncludeTestFile(t, dir, "teams/dev.yaml", `kubeconfigs:
+  dev:
+    path: /tmp/${var:dev_dir}
+vars:
+  dev_dir: dev
+workspaces:
+  dev:
+    kubeconfigs: [dev]
+identity_files:
+  - ~/age.txt
+  - ~/dev.txt
+`)
+	writeIncludeTestFile(t, dir, "kubecfg.d/ops.yaml", `default_workspace: work
+kubeconfigs:
+  ops:
+    path: /tmp/ops
+`)
+	writeIncludeTestFile(t, dir, "kubecfg.d/notes.txt", `not: yaml config`)
+
+	cfg, err := LoadFile(main)
+	require.NoError(t, err)
+	require.NoError(t, cfg.ResolveIncludes(main))
+
+	require.ElementsMatch(t, []string{"prod", "dev", "ops"}, sortedKeys(cfg.Kubeconfigs))
+	require.ElementsMatch(t, []string{"work", "dev"}, sortedKeys(cfg.Workspaces))
+	require.Equal(t, "work", cfg.DefaultWorkspace)
+	require.Equal(t, []string{"~/age.txt", "~/dev.txt"}, cfg.IdentityFiles)
+	require.Equal(t, map[string]string{"team": "platform", "dev_dir": "dev"}, cfg.Vars)
+
+	expanded, diags := cfg.Expand()
+	require.Empty(t, diags)
+	require.Equal(t, "/tmp/dev", expanded.Kubeconfigs["dev"].Path)
+
+	require.Equal(t, main, cfg.Kubeconfigs["prod"].Source)
+	require.Equal(t, filepath.Join(dir, "teams", "dev.yaml"), cfg.Kubeconfigs["dev"].Source)
+	require.Equal(t, filepath.Join(dir, "kubecfg.d", "ops.yaml"), cfg.Kubeconfigs["ops"].Source)
+	require.Equal(t, filepath.Join(dir, "teams", "dev.yaml"), cfg.Workspaces["dev"].Source)
+	require.Equal(t, filepath.Join(dir, "kubecfg.d", "ops.yaml"), cfg.SourceOf("kubeconfigs.ops.path"))
+	require.Equal(t, "", cfg.SourceOf("default_workspace"))
+}
+
+func TestResolveIncludesReportsDuplicatesWithSourceFile(t *testing.T) {
+	dir := t.TempDir()
+	main := writeIncludeTestFile(t, dir, "kubecfg.yaml", `default_workspace: work
+backups: 0
+vars:
+  team: platform
+workspaces:
+  work:
+    kubeconfigs: [prod]
+kubeconfigs:
+  prod:
+    path: /tmp/prod
+`)
+	fragment := writeIncludeTestFile(t, dir, "kubecfg.d/team.yaml", `default_workspace: team
+backups: 5
+vars:
+  team: platform
+workspaces:
+  work:
+    kubeconfigs: [prod]
+kubeconfigs:
+  prod:
+    path: /tmp/other
+`)
+
+	cfg, err := LoadFile(main)
+	require.NoError(t, err)
+
+	err = cfg.ResolveIncludes(main)
+	require.Error(t, err)
+	require.ErrorContains(t, err, "kubeconfigs.prod in "+fragment+" is already defined in "+main)
+	require.ErrorContains(t, err, "workspaces.work in "+fragment+" is already defined in "+main)
+	require.ErrorContains(t, err, `default_workspace "team" in `+fragment+` conflicts with "work" set in `+main)
+	require.ErrorContains(t, err, `backups 5 in `+fragment+` conflicts with 0 set in `+main)
+	require.ErrorContains(t, err, "vars.team in "+fragment+" is already defined in "+main)
+	require.Equal(t, "/tmp/prod", cfg.Kubeconfigs["prod"].Path)
+}
+
+func TestResolveIncludesRejectsNestedIncludes(t *testing.T) {
+	dir := t.TempDir()
+	main := writeIncludeTestFile(t, dir, "kubecfg.yaml", "include: [a.yaml]\n")
+	writeIncludeTestFile(t, dir, "a.yaml", "include: [b.yaml]\n")
+
+	cfg, err := LoadFile(main)
+	require.NoError(t, err)
+	require.ErrorContains(t, cfg.ResolveIncludes(main), "include is only supported in the main config file")
+}
+
+func TestDiagnoseSetsSourceFile(t *testing.T) {
+	cfg := Config{
+		Kubeconfigs: map[string]*Kubeconfig{
+			"team.dev": {Source: "/conf.d/team.yaml"},
+		},
+	}
+
+	diags := cfg.Diagnose()
+	require.Len(t, diags, 1)
+	require.Equal(t, "kubeconfigs.team.dev.path", diags[0].Path)
+	require.Equal(t, "/conf.d/team.yaml", diags[0].File)
+}
+
+func writeIncludeTestFile(t *testing.T, dir, name, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+
+	return path
+}
@@ -0,0 +1,279 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Expansion is a string value that contained references, before and after
+// they were expanded.
+type Expansion struct {
+	Path  string
+	Raw   string
+	Value string
+}
+
+// Expand returns a copy of c with ${env:NAME}, ${var:name} and ${file:path}
+// references in string fields replaced by their values. Relative file paths
+// are relative to the file the value was defined in. Vars may reference
+// env, files and other vars. $${ is a literal ${ and any other ${ is kept as
+// is. References that can't be resolved are left as is and reported as error
+// diagnostics. The returned config lists every expanded value in Expansions.
+func (c *Config) Expand() (*Config, Diagnostics) {
+	e := &expander{
+		cfg:       c,
+		vars:      c.Vars,
+		resolved:  make(map[string]string),
+		resolving: make(map[string]bool),
+		failed:    make(map[string]bool),
+	}
+
+	// Expand every var first so that problems in vars are reported even if
+	// they are unused.
+	for _, name := range sortedKeys(c.Vars) {
+		e.lookupVar(name)
+	}
+
+	res := e.copy("", reflect.ValueOf(c).Elem()).Addr().Interface().(*Config)
+	res.Expansions = e.expansions
+
+	e.diags.Sort()
+	return res, e.diags
+}
+
+// Expansion returns the expansion recorded for path, if any.
+func (c *Config) Expansion(path string) (Expansion, bool) {
+	for _, exp := range c.Expansions {
+		if exp.Path == path {
+			return exp, true
+		}
+	}
+	return Expansion{}, false
+}
+
+type expander struct {
+	cfg        *Config
+	vars       map[string]string
+	resolved   map[string]string
+	resolving  map[string]bool
+	failed     map[string]bool
+	diags      Diagnostics
+	expansions []Expansion
+}
+
+// copy returns a deep copy of v with strings expanded. Byte slices and
+// interfaces are shared with v.
+func (e *expander) copy(path string, v reflect.Value) reflect.Value {
+	res := reflect.New(v.Type()).Elem()
+
+	switch v.Kind() {
+	case reflect.String:
+		res.SetString(e.expand(path, v.String()))
+	case reflect.Pointer:
+		if !v.IsNil() {
+			res.Set(e.copy(path, v.Elem()).Addr())
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			key, ok := schemaKey(field)
+			if !ok || key == "vars" || key == "overlays" {
+				res.Field(i).Set(v.Field(i))
+				continue
+			}
+			res.Field(i).Set(e.copy(joinPath(path, key), v.Field(i)))
+		}
+	case reflect.Slice:
+		if v.IsNil() || v.Type().Elem().Kind() == reflect.Uint8 {
+			res.Set(v)
+			break
+		}
+		res.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+		for i := range v.Len() {
+			res.Index(i).Set(e.copy(fmt.Sprintf("%s[%d]", path, i), v.Index(i)))
+		}
+	case reflect.Map:
+		if v.IsNil() || v.Type().Key().Kind() != reflect.String {
+			res.Set(v)
+			break
+		}
+		res.Set(reflect.MakeMapWithSize(v.Type(), v.Len()))
+		for _, key := range v.MapKeys() {
+			res.SetMapIndex(key, e.copy(joinPath(path, key.String()), v.MapIndex(key)))
+		}
+	default:
+		res.Set(v)
+	}
+
+	return res
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// expand expands the references in s and records the expansion. path is used
+// in diagnostics.
+func (e *expander) expand(path, s string) string {
+	if !strings.Contains(s, "${") {
+		return s
+	}
+
+	value := e.expandString(path, s)
+	e.expansions = append(e.expansions, Expansion{Path: path, Raw: s, Value: value})
+	return value
+}
+
+// referenceKinds are the prefixes that start a reference. Any other ${ is
+// kept as is, so existing values such as ${HOME} in command args still work.
+var referenceKinds = []string{"env", "var", "file"}
+
+func (e *expander) expandString(path, s string) string {
+	var b strings.Builder
+
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+
+		// $${ escapes a literal ${.
+		if start > 0 && s[start-1] == '$' {
+			b.WriteString(s[:start-1])
+			b.WriteString("${")
+			s = s[start+2:]
+			continue
+		}
+
+		b.WriteString(s[:start])
+		s = s[start:]
+
+		kind, ok := referenceKind(s[2:])
+		if !ok {
+			b.WriteString("${")
+			s = s[2:]
+			continue
+		}
+
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			e.errorf(path, "has an unterminated reference %q", s)
+			b.WriteString(s)
+			return b.String()
+		}
+
+		ref, arg := s[:end+1], strings.TrimSpace(s[len(kind)+3:end])
+		if value, ok := e.resolve(path, kind, arg); ok {
+			b.WriteString(value)
+		} else {
+			b.WriteString(ref)
+		}
+
+		s = s[end+1:]
+	}
+}
+
+func referenceKind(s string) (string, bool) {
+	for _, kind := range referenceKinds {
+		if strings.HasPrefix(s, kind+":") {
+			return kind, true
+		}
+	}
+	return "", false
+}
+
+func (e *expander) resolve(path, kind, arg string) (string, bool) {
+	if arg == "" {
+		e.errorf(path, "has an empty ${%s:} reference", kind)
+		return "", false
+	}
+
+	switch kind {
+	case "env":
+		value, ok := os.LookupEnv(arg)
+		if !ok {
+			e.errorf(path, "references undefined environment variable %q", arg)
+		}
+		return value, ok
+	case "var":
+		if _, ok := e.vars[arg]; !ok {
+			e.errorf(path, "references undefined var %q", arg)
+			return "", false
+		}
+		value, ok := e.lookupVar(arg)
+		// Vars are cached, so a var that depends on one that failed is
+		// reported here, not only the first time the failure is found.
+		if !ok && strings.HasPrefix(path, "vars.") {
+			e.errorf(path, "has an undefined reference via vars.%s", arg)
+		}
+		return value, ok
+	default:
+		data, err := os.ReadFile(e.filePath(path, arg))
+		if err != nil {
+			e.errorf(path, "references unreadable file %q: %v", arg, err)
+			return "", false
+		}
+		value := strings.TrimSuffix(string(data), "\n")
+		return strings.TrimSuffix(value, "\r"), true
+	}
+}
+
+// filePath resolves the ${file:arg} reference in the value at path. Relative
+// paths are relative to the file that defined the value, or the main config
+// file if that isn't known.
+func (e *expander) filePath(path, arg string) string {
+	p := ResolvePath("", arg)
+	if filepath.IsAbs(p) {
+		return p
+	}
+	source := e.cfg.SourceOf(path)
+	if source == "" {
+		source = e.cfg.File
+	}
+	if source == "" {
+		return p
+	}
+	return filepath.Join(filepath.Dir(source), p)
+}
+
+// lookupVar expands the var called name once and caches the result. Problems
+// are reported on the var itself, not on every value that uses it.
+func (e *expander) lookupVar(name string) (string, bool) {
+	if value, ok := e.resolved[name]; ok {
+		return value, true
+	}
+	if e.failed[name] {
+		return "", false
+	}
+
+	path := "vars." + name
+	if e.resolving[name] {
+		e.failed[name] = true
+		e.errorf(path, "is part of a reference cycle")
+		return "", false
+	}
+
+	e.resolving[name] = true
+	before := len(e.diags)
+	value := e.expand(path, e.vars[name])
+	delete(e.resolving, name)
+
+	if len(e.diags) > before || e.failed[name] {
+		e.failed[name] = true
+		return "", false
+	}
+
+	e.resolved[name] = value
+	return value, true
+}
+
+func (e *expander) errorf(path, format string, args ...any) {
+	e.diags = append(e.diags, Diagnostic{Path: path, Severity: SeverityError, Message: fmt.Sprintf(format, args...)})
+}
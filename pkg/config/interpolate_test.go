@@ -0,0 +1,154 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandReplacesReferences(t *testing.T) {
+	t.Setenv("KUBECFG_TEST_USER", "alice")
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("s3cr3t\n"), 0o600))
+
+	cfg := &Config{
+		BaseDir: "${var:home}/kube",
+		Vars: map[string]string{
+			"home": "/home/${env:KUBECFG_TEST_USER}",
+			"team": "platform",
+		},
+		Kubeconfigs: map[string]*Kubeconfig{
+			"demo": {
+				Path: "${var:home}/.kube/${var:team}.yaml",
+				AuthInfos: map[string]*AuthInfo{
+					"admin": {Token: "${file:" + tokenFile + "}"},
+				},
+				LoginSources: map[string]*LoginSource{
+					"vault": {Command: "vault", Args: []string{"login", "-path=${HOME}", "$${var:team}"}},
+				},
+			},
+		},
+	}
+
+	expanded, diags := cfg.Expand()
+	require.Empty(t, diags)
+
+	require.Equal(t, "/home/alice/kube", expanded.BaseDir)
+	require.Equal(t, "/home/alice/.kube/platform.yaml", expanded.Kubeconfigs["demo"].Path)
+	require.Equal(t, "s3cr3t", expanded.Kubeconfigs["demo"].AuthInfos["admin"].Token)
+	require.Equal(t, []string{"login", "-path=${HOME}", "${var:team}"}, expanded.Kubeconfigs["demo"].LoginSources["vault"].Args)
+
+	exp, ok := expanded.Expansion("kubeconfigs.demo.path")
+	require.True(t, ok)
+	require.Equal(t, Expansion{Path: "kubeconfigs.demo.path", Raw: "${var:home}/.kube/${var:team}.yaml", Value: "/home/alice/.kube/platform.yaml"}, exp)
+
+	// The original config is left untouched.
+	require.Equal(t, "${var:home}/.kube/${var:team}.yaml", cfg.Kubeconfigs["demo"].Path)
+	require.Empty(t, cfg.Expansions)
+}
+
+func TestExpandResolvesFilesRelativeToSource(t *testing.T) {
+	dir := t.TempDir()
+	main := writeIncludeTestFile(t, dir, "kubecfg.yaml", `kubeconfigs:
+  main:
+    path: /tmp/main
+    auth_infos:
+      admin:
+        token: ${file:secrets/main}
+`)
+	writeIncludeTestFile(t, dir, "kubecfg.d/team.yaml", `vars:
+  team_token: ${file:team-token}
+kubeconfigs:
+  team:
+    path: /tmp/team
+    auth_infos:
+      admin:
+        token: ${file:secrets/team}
+        username: ${var:team_token}
+`)
+	writeIncludeTestFile(t, dir, "secrets/main", "main-secret\n")
+	writeIncludeTestFile(t, dir, "kubecfg.d/secrets/team", "team-secret\n")
+	writeIncludeTestFile(t, dir, "kubecfg.d/team-token", "var-secret\n")
+
+	// The current directory must not matter.
+	t.Chdir(t.TempDir())
+
+	cfg, err := LoadFile(main)
+	require.NoError(t, err)
+	require.NoError(t, cfg.ResolveIncludes(main))
+
+	expanded, diags := cfg.Expand()
+	require.Empty(t, diags)
+	require.Equal(t, "main-secret", expanded.Kubeconfigs["main"].AuthInfos["admin"].Token)
+	require.Equal(t, "team-secret", expanded.Kubeconfigs["team"].AuthInfos["admin"].Token)
+	require.Equal(t, "var-secret", expanded.Kubeconfigs["team"].AuthInfos["admin"].Username)
+}
+
+func TestExpandReportsUndefinedReferences(t *testing.T) {
+	cfg := &Config{
+		Vars: map[string]string{"home": "/home/${env:KUBECFG_TEST_UNDEFINED}"},
+		Kubeconfigs: map[string]*Kubeconfig{
+			"demo": {Path: "${var:missing}/demo.yaml", DefaultNamespace: "${var:home}"},
+		},
+	}
+
+	expanded, diags := cfg.Expand()
+	require.Equal(t, []string{
+		`error: kubeconfigs.demo.path references undefined var "missing"`,
+		`error: vars.home references undefined environment variable "KUBECFG_TEST_UNDEFINED"`,
+	}, diagnosticStrings(diags))
+
+	// Unresolved references are kept as is.
+	require.Equal(t, "${var:missing}/demo.yaml", expanded.Kubeconfigs["demo"].Path)
+	require.Equal(t, "${var:home}", expanded.Kubeconfigs["demo"].DefaultNamespace)
+}
+
+func TestExpandReportsVarCycles(t *testing.T) {
+	cfg := &Config{
+		Vars: map[string]string{
+			"a": "${var:b}",
+			"b": "${var:a}",
+		},
+	}
+
+	_, diags := cfg.Expand()
+	require.Contains(t, diagnosticStrings(diags), "error: vars.a is part of a reference cycle")
+	require.True(t, diags.HasErrors())
+}
+
+func TestExpandReportsMalformedReferences(t *testing.T) {
+	cfg := &Config{
+		BaseDir:          "${env:HOME",
+		DefaultWorkspace: "${var:}",
+	}
+
+	_, diags := cfg.Expand()
+	require.Equal(t, []string{
+		`error: base_dir has an unterminated reference "${env:HOME"`,
+		`error: default_workspace has an empty ${var:} reference`,
+	}, diagnosticStrings(diags))
+}
+
+func TestExpandReportsVarsThatDependOnFailedVars(t *testing.T) {
+	cfg := &Config{
+		Vars: map[string]string{
+			"a":    "${var:base}/a",
+			"b":    "${var:a}/b",
+			"base": "${file:/nonexistent/kubecfg-test}",
+		},
+		Kubeconfigs: map[string]*Kubeconfig{
+			"demo": {Path: "${var:b}/demo.yaml"},
+		},
+	}
+
+	expanded, diags := cfg.Expand()
+	require.Equal(t, []string{
+		`error: vars.a has an undefined reference via vars.base`,
+		`error: vars.b has an undefined reference via vars.a`,
+		`error: vars.base references unreadable file "/nonexistent/kubecfg-test": open /nonexistent/kubecfg-test: no such file or directory`,
+	}, diagnosticStrings(diags))
+	require.Equal(t, "${var:b}/demo.yaml", expanded.Kubeconfigs["demo"].Path)
+}
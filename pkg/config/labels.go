@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// LabelSelector selects kubeconfigs by their labels, with the same semantics
+// as a Kubernetes label selector. An empty selector matches everything, a nil
+// one matches nothing.
+type LabelSelector struct {
+	MatchLabels      map[string]string          `mapstructure:"matchLabels,omitempty" json:"matchLabels,omitempty" yaml:"matchLabels,omitempty"`
+	MatchExpressions []LabelSelectorRequirement `mapstructure:"matchExpressions,omitempty" json:"matchExpressions,omitempty" yaml:"matchExpressions,omitempty"`
+}
+
+// LabelSelectorRequirement is a single expression of a LabelSelector.
+type LabelSelectorRequirement struct {
+	Key      string   `json:"key"`
+	Operator string   `json:"operator"`
+	Values   []string `json:"values,omitempty"`
+}
+
+// Selector converts s to a labels.Selector. A nil selector matches nothing.
+func (s *LabelSelector) Selector() (labels.Selector, error) {
+	if s == nil {
+		return labels.Nothing(), nil
+	}
+
+	ls := &metav1.LabelSelector{MatchLabels: s.MatchLabels}
+	for _, req := range s.MatchExpressions {
+		ls.MatchExpressions = append(ls.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key:      req.Key,
+			Operator: metav1.LabelSelectorOperator(req.Operator),
+			Values:   req.Values,
+		})
+	}
+
+	return metav1.LabelSelectorAsSelector(ls)
+}
+
+// WorkspaceKubeconfigs returns the kubeconfigs of the workspace called name:
+// the ones it lists, followed by the ones its selector matches in name order.
+func (c *Config) WorkspaceKubeconfigs(name string) ([]string, error) {
+	ws := c.Workspace(name)
+	if ws == nil {
+		return nil, fmt.Errorf("workspaces.%s is nil", name)
+	}
+
+	res := slices.Clone(ws.Kubeconfigs)
+	if ws.Selector == nil {
+		return res, nil
+	}
+
+	selector, err := ws.Selector.Selector()
+	if err != nil {
+		return nil, fmt.Errorf("workspaces.%s.selector: %w", name, err)
+	}
+
+	for _, kcName := range sortedKeys(c.Kubeconfigs) {
+		kc := c.Kubeconfigs[kcName]
+		if kc == nil || slices.Contains(res, kcName) {
+			continue
+		}
+		if selector.Matches(labels.Set(kc.Labels)) {
+			res = append(res, kcName)
+		}
+	}
+
+	return res, nil
+}
+
+// mergeLabels returns the labels of base overridden by the labels of local.
+func mergeLabels(base, local map[string]string) map[string]string {
+	res := make(map[string]string, len(base)+len(local))
+	maps.Copy(res, base)
+	maps.Copy(res, local)
+	return res
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newLabelsTestConfig() *Config {
+	return &Config{
+		BaseDir: "/tmp",
+		Workspaces: map[string]*Workspace{
+			"prod": {
+				Kubeconfigs:       []string{"legacy"},
+				DefaultKubeconfig: "payments-prod",
+				Selector: &LabelSelector{
+					MatchLabels: map[string]string{"env": "prod"},
+					MatchExpressions: []LabelSelectorRequirement{
+						{Key: "team", Operator: "In", Values: []string{"payments", "search"}},
+					},
+				},
+			},
+			"manual": {Kubeconfigs: []string{"legacy"}},
+		},
+		Kubeconfigs: map[string]*Kubeconfig{
+			"legacy":        newLabelsTestKubeconfig(nil),
+			"payments-prod": newLabelsTestKubeconfig(map[string]string{"env": "prod", "team": "payments"}),
+			"search-prod":   newLabelsTestKubeconfig(map[string]string{"env": "prod", "team": "search"}),
+			"payments-dev":  newLabelsTestKubeconfig(map[string]string{"env": "dev", "team": "payments"}),
+			"infra-prod":    newLabelsTestKubeconfig(map[string]string{"env": "prod", "team": "infra"}),
+		},
+	}
+}
+
+func newLabelsTestKubeconfig(labels map[string]string) *Kubeconfig {
+	return &Kubeconfig{
+		Path:   "@/" + labels["team"] + labels["env"] + ".yaml",
+		Labels: labels,
+		Clusters: map[string]*Cluster{
+			"main": {Server: "https://example.com"},
+		},
+		AuthInfos: map[string]*AuthInfo{
+			"admin": {Token: "token"},
+		},
+		Contexts: map[string]*Context{
+			"admin": {Cluster: "main", AuthInfo: "admin", Labels: map[string]string{"role": "admin"}},
+		},
+	}
+}
+
+func TestWorkspaceKubeconfigsAddsSelectedKubeconfigs(t *testing.T) {
+	cfg := newLabelsTestConfig()
+
+	names, err := cfg.WorkspaceKubeconfigs("prod")
+	require.NoError(t, err)
+	require.Equal(t, []string{"legacy", "payments-prod", "search-prod"}, names)
+
+	names, err = cfg.WorkspaceKubeconfigs("manual")
+	require.NoError(t, err)
+	require.Equal(t, []string{"legacy"}, names)
+}
+
+func TestWorkspaceKubeconfigsFailsOnInvalidSelector(t *testing.T) {
+	cfg := newLabelsTestConfig()
+	cfg.Workspaces["prod"].Selector.MatchExpressions[0].Operator = "Near"
+
+	_, err := cfg.WorkspaceKubeconfigs("prod")
+	require.ErrorContains(t, err, "workspaces.prod.selector: ")
+	require.ErrorContains(t, err, `"Near" is not a valid label selector operator`)
+}
+
+func TestCompileResolvesWorkspaceSelectorAndLabels(t *testing.T) {
+	rt, err := NewCompiler().Compile(newLabelsTestConfig())
+	require.NoError(t, err)
+
+	ws := rt.Workspace("prod")
+	require.ElementsMatch(t, []string{"legacy", "payments-prod", "search-prod"}, sortedKeys(ws.Kubeconfigs))
+	require.Equal(t, "payments-prod", ws.DefaultKubeconfig.Name)
+
+	// Contexts get the labels of their kubeconfig.
+	rk := rt.Kubeconfigs["payments-prod"]
+	require.Equal(t, map[string]string{"env": "prod", "team": "payments"}, rk.Labels)
+	require.Equal(t, map[string]string{"env": "prod", "team": "payments", "role": "admin"}, rk.Context("admin").Labels)
+}
+
+func TestDiagnoseReportsInvalidSelector(t *testing.T) {
+	cfg := newLabelsTestConfig()
+	require.Empty(t, cfg.Diagnose())
+
+	cfg.Workspaces["prod"].Selector.MatchExpressions[0].Values = nil
+	diags := diagnosticStrings(cfg.Diagnose())
+	require.Len(t, diags, 2)
+	require.Contains(t, diags[0], "error: workspaces.prod.default_kubeconfig references missing kubeconfig \"payments-prod\"")
+	require.Contains(t, diags[1], "error: workspaces.prod.selector is invalid: ")
+}
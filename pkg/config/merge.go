@@ -0,0 +1,91 @@
+package config
+
+import (
+	"slices"
+	"strings"
+
+	api "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// MergedName returns the name of a cluster, user or context of kubeconfig in
+// the merged kubeconfig of a workspace.
+func MergedName(kubeconfig, name string) string {
+	return kubeconfig + "/" + name
+}
+
+// Merge combines the configs of the kubeconfigs in rw into one, for
+// workspaces rendered as merged. Clusters, users and contexts are renamed with
+// MergedName so that entries of different kubeconfigs can't overwrite each
+// other. The current context is the one of the default kubeconfig, or of the
+// first kubeconfig by name that has one.
+func (rw *RuntimeWorkspace) Merge() *api.Config {
+	out := api.NewConfig()
+
+	names := make([]string, 0, len(rw.Kubeconfigs))
+	for name := range rw.Kubeconfigs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	if rw.DefaultKubeconfig != nil {
+		names = slices.DeleteFunc(names, func(name string) bool { return name == rw.DefaultKubeconfig.Name })
+		names = append([]string{rw.DefaultKubeconfig.Name}, names...)
+	}
+
+	for _, name := range names {
+		kc := rw.Kubeconfigs[name].Config
+		if kc == nil {
+			continue
+		}
+
+		for key, cluster := range kc.Clusters {
+			out.Clusters[MergedName(name, key)] = cluster
+		}
+		for key, authInfo := range kc.AuthInfos {
+			out.AuthInfos[MergedName(name, key)] = authInfo
+		}
+		for key, ctx := range kc.Contexts {
+			merged := *ctx
+			merged.Cluster = MergedName(name, ctx.Cluster)
+			merged.AuthInfo = MergedName(name, ctx.AuthInfo)
+			out.Contexts[MergedName(name, key)] = &merged
+		}
+
+		if out.CurrentContext == "" && kc.CurrentContext != "" {
+			out.CurrentContext = MergedName(name, kc.CurrentContext)
+		}
+	}
+
+	return out
+}
+
+// Unmerge returns the clusters, users and contexts of kubeconfig in merged, a
+// kubeconfig written by Merge, under their own names. The entries are shared
+// with merged.
+func Unmerge(merged *api.Config, kubeconfig string) *api.Config {
+	out := api.NewConfig()
+	prefix := MergedName(kubeconfig, "")
+
+	for key, cluster := range merged.Clusters {
+		if name, ok := strings.CutPrefix(key, prefix); ok {
+			out.Clusters[name] = cluster
+		}
+	}
+	for key, authInfo := range merged.AuthInfos {
+		if name, ok := strings.CutPrefix(key, prefix); ok {
+			out.AuthInfos[name] = authInfo
+		}
+	}
+	for key, ctx := range merged.Contexts {
+		if name, ok := strings.CutPrefix(key, prefix); ok {
+			unmerged := *ctx
+			unmerged.Cluster = strings.TrimPrefix(ctx.Cluster, prefix)
+			unmerged.AuthInfo = strings.TrimPrefix(ctx.AuthInfo, prefix)
+			out.Contexts[name] = &unmerged
+		}
+	}
+	if name, ok := strings.CutPrefix(merged.CurrentContext, prefix); ok {
+		out.CurrentContext = name
+	}
+
+	return out
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func newMergeTestKubeconfig(name, server string) *RuntimeKubeconfig {
+	kc := api.NewConfig()
+	kc.Clusters["cluster"] = &api.Cluster{Server: server}
+	kc.AuthInfos["user"] = &api.AuthInfo{Token: name}
+	kc.Contexts["admin"] = &api.Context{Cluster: "cluster", AuthInfo: "user", Namespace: "apps"}
+	kc.CurrentContext = "admin"
+	return &RuntimeKubeconfig{Name: name, Config: kc}
+}
+
+func TestRuntimeWorkspaceMerge(t *testing.T) {
+	dev := newMergeTestKubeconfig("dev", "https://dev.example.com")
+	prod := newMergeTestKubeconfig("prod", "https://prod.example.com")
+	rw := &RuntimeWorkspace{
+		Name:              "team",
+		Merged:            true,
+		DefaultKubeconfig: prod,
+		Kubeconfigs:       map[string]*RuntimeKubeconfig{"dev": dev, "prod": prod},
+	}
+
+	merged := rw.Merge()
+	require.Equal(t, "prod/admin", merged.CurrentContext)
+	require.Len(t, merged.Clusters, 2)
+	require.Equal(t, "https://dev.example.com", merged.Clusters["dev/cluster"].Server)
+	require.Equal(t, "https://prod.example.com", merged.Clusters["prod/cluster"].Server)
+	require.Equal(t, "dev", merged.AuthInfos["dev/user"].Token)
+	require.Equal(t, &api.Context{Cluster: "dev/cluster", AuthInfo: "dev/user", Namespace: "apps"}, merged.Contexts["dev/admin"])
+
+	// The configs of the kubeconfigs are left alone.
+	require.Equal(t, "cluster", dev.Config.Contexts["admin"].Cluster)
+
+	rw.DefaultKubeconfig = nil
+	require.Equal(t, "dev/admin", rw.Merge().CurrentContext)
+}
+
+func TestUnmerge(t *testing.T) {
+	dev := newMergeTestKubeconfig("dev", "https://dev.example.com")
+	prod := newMergeTestKubeconfig("prod", "https://prod.example.com")
+	rw := &RuntimeWorkspace{
+		Name:        "team",
+		Merged:      true,
+		Kubeconfigs: map[string]*RuntimeKubeconfig{"dev": dev, "prod": prod},
+	}
+
+	merged := rw.Merge()
+	require.Equal(t, dev.Config, Unmerge(merged, "dev"))
+
+	// The current context of the merged kubeconfig belongs to dev.
+	unmerged := Unmerge(merged, "prod")
+	require.Empty(t, unmerged.CurrentContext)
+	require.Equal(t, prod.Config.Contexts, unmerged.Contexts)
+
+	require.Empty(t, Unmerge(merged, "staging").Contexts)
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	VersionV1 = "v1"
+
+	// CurrentVersion is the config version understood by the compiler and
+	// written by Migrate.
+	CurrentVersion = VersionV1
+)
+
+var (
+	ErrUnsupportedVersion = errors.New("unsupported config version")
+	ErrNeedsMigration     = errors.New("config needs migration")
+)
+
+// Migration upgrades a document from one config version to the next. Apply
+// only rewrites fields, the version key is updated by Migrate.
+type Migration struct {
+	From        string
+	To          string
+	Description string
+	Apply       func(doc *Document) error
+}
+
+// migrations is the ordered chain of upgrade steps ending at CurrentVersion.
+// To change the config format, bump CurrentVersion and append a step here.
+var migrations = []Migration{
+	{
+		From:        "",
+		To:          VersionV1,
+		Description: "set version to v1",
+		Apply:       func(doc *Document) error { return nil },
+	},
+}
+
+// CheckVersion returns nil if version can be compiled. An empty version is
+// treated as the current version since unversioned files predate versioning
+// and share the v1 format.
+func CheckVersion(version string) error {
+	version = strings.TrimSpace(version)
+	if version == "" || version == CurrentVersion {
+		return nil
+	}
+
+	if slices.ContainsFunc(migrations, func(m Migration) bool { return m.From == version }) {
+		return fmt.Errorf("%w: version %q is outdated, run kubecfg migrate to upgrade to %s", ErrNeedsMigration, version, CurrentVersion)
+	}
+
+	return fmt.Errorf("%w %q: this kubecfg supports %s, upgrade kubecfg or change version", ErrUnsupportedVersion, version, CurrentVersion)
+}
+
+// Migrate upgrades doc to CurrentVersion one step at a time and returns the
+// steps that were applied. Comments and key order are kept.
+func Migrate(doc *Document) ([]Migration, error) {
+	return migrate(doc, migrations, CurrentVersion)
+}
+
+// migrate upgrades doc to target through the steps in chain.
+func migrate(doc *Document, chain []Migration, target string) ([]Migration, error) {
+	version := documentVersion(doc)
+
+	var applied []Migration
+	for version != target {
+		idx := slices.IndexFunc(chain, func(m Migration) bool { return m.From == version })
+		if idx < 0 {
+			return applied, fmt.Errorf("%w %q: no migration to %s", ErrUnsupportedVersion, version, target)
+		}
+
+		m := chain[idx]
+		if err := m.Apply(doc); err != nil {
+			return applied, fmt.Errorf("migrate %s to %s: %w", displayVersion(m.From), m.To, err)
+		}
+		if err := setDocumentVersion(doc, m.To); err != nil {
+			return applied, err
+		}
+
+		applied = append(applied, m)
+		version = m.To
+	}
+
+	return applied, nil
+}
+
+func documentVersion(doc *Document) string {
+	node := doc.Lookup("version")
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return strings.TrimSpace(node.Value)
+}
+
+// setDocumentVersion updates the version key in place, or adds it as the first
+// key so that it sits where people expect it.
+func setDocumentVersion(doc *Document, version string) error {
+	if node := doc.Lookup("version"); node != nil {
+		node.Kind = yaml.ScalarNode
+		node.Tag = "!!str"
+		node.Value = version
+		node.Content = nil
+		return nil
+	}
+
+	root := doc.Root()
+	if !asMapping(root) {
+		return fmt.Errorf("top level of config is not a mapping")
+	}
+
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}
+	if len(root.Content) > 0 {
+		// Keep a leading file comment at the top of the file.
+		key.HeadComment = root.Content[0].HeadComment
+		root.Content[0].HeadComment = ""
+	}
+
+	root.Content = append([]*yaml.Node{key, {Kind: yaml.ScalarNode, Tag: "!!str", Value: version}}, root.Content...)
+	return nil
+}
+
+func displayVersion(version string) string {
+	if version == "" {
+		return "unversioned"
+	}
+	return version
+}
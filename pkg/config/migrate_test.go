@@ -0,0 +1,110 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateUnversionedDocument(t *testing.T) {
+	doc, err := ParseDocument([]byte(`# my clusters
+kubeconfigs:
+  demo:
+    path: /tmp/demo # rendered here
+`))
+	require.NoError(t, err)
+
+	applied, err := Migrate(doc)
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	require.Equal(t, "", applied[0].From)
+	require.Equal(t, VersionV1, applied[0].To)
+
+	out, err := doc.Bytes()
+	require.NoError(t, err)
+	require.Equal(t, `# my clusters
+version: v1
+kubeconfigs:
+  demo:
+    path: /tmp/demo # rendered here
+`, string(out))
+}
+
+func TestMigrateAppliesStepsInOrder(t *testing.T) {
+	doc, err := ParseDocument([]byte("version: v1\nbase: /tmp # kept\n"))
+	require.NoError(t, err)
+
+	var versions []string
+	chain := []Migration{
+		{From: "v2", To: "v3", Description: "rename dir to base_dir", Apply: func(doc *Document) error {
+			versions = append(versions, documentVersion(doc))
+			dir := doc.Lookup("dir")
+			doc.Delete("dir")
+			return doc.Set("base_dir", dir)
+		}},
+		{From: "v1", To: "v2", Description: "rename base to dir", Apply: func(doc *Document) error {
+			versions = append(versions, documentVersion(doc))
+			base := doc.Lookup("base")
+			doc.Delete("base")
+			return doc.Set("dir", base)
+		}},
+	}
+
+	applied, err := migrate(doc, chain, "v3")
+	require.NoError(t, err)
+	require.Equal(t, []string{"rename base to dir", "rename dir to base_dir"}, []string{applied[0].Description, applied[1].Description})
+	require.Equal(t, []string{"v1", "v2"}, versions)
+
+	out, err := doc.Bytes()
+	require.NoError(t, err)
+	require.Equal(t, "version: v3\nbase_dir: /tmp # kept\n", string(out))
+
+	// A chain that doesn't reach the target stops where it ends.
+	doc, err = ParseDocument([]byte("version: v1\n"))
+	require.NoError(t, err)
+	applied, err = migrate(doc, chain[1:], "v3")
+	require.ErrorIs(t, err, ErrUnsupportedVersion)
+	require.Len(t, applied, 1)
+}
+
+func TestMigrateCurrentVersionIsNoop(t *testing.T) {
+	doc, err := ParseDocument([]byte("version: v1 # current\n"))
+	require.NoError(t, err)
+
+	applied, err := Migrate(doc)
+	require.NoError(t, err)
+	require.Empty(t, applied)
+
+	out, err := doc.Bytes()
+	require.NoError(t, err)
+	require.Equal(t, "version: v1 # current\n", string(out))
+}
+
+func TestMigrateUnknownVersion(t *testing.T) {
+	doc, err := ParseDocument([]byte("version: v9\n"))
+	require.NoError(t, err)
+
+	_, err = Migrate(doc)
+	require.ErrorIs(t, err, ErrUnsupportedVersion)
+}
+
+func TestCheckVersion(t *testing.T) {
+	require.NoError(t, CheckVersion(""))
+	require.NoError(t, CheckVersion("v1"))
+	require.ErrorIs(t, CheckVersion("v9"), ErrUnsupportedVersion)
+}
+
+func TestCompileRejectsUnknownVersion(t *testing.T) {
+	_, err := NewCompiler().Compile(&Config{Version: "v2"})
+	require.ErrorIs(t, err, ErrUnsupportedVersion)
+	require.EqualError(t, err, `unsupported config version "v2": this kubecfg supports v1, upgrade kubecfg or change version`)
+}
+
+func TestDiagnoseReportsUnknownVersion(t *testing.T) {
+	diags := (&Config{Version: "v2"}).Diagnose()
+	require.Equal(t, Diagnostics{{
+		Path:     "version",
+		Severity: SeverityError,
+		Message:  `"v2" is not supported by this kubecfg, expected v1`,
+	}}, diags)
+}
@@ -0,0 +1,242 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"os/user"
+	"path"
+	"strings"
+)
+
+// Overlay patches the config on the hosts matched by Match. Overlays are
+// applied in order after includes are merged and before interpolation, so a
+// later overlay wins over an earlier one.
+type Overlay struct {
+	Name  string       `mapstructure:"name,omitempty" json:"name,omitempty" yaml:"name,omitempty"`
+	Match OverlayMatch `mapstructure:"match,omitempty" json:"match,omitempty" yaml:"match,omitempty"`
+
+	BaseDir          string   `mapstructure:"base_dir,omitempty" json:"base_dir,omitempty" yaml:"base_dir,omitempty"`
+	DefaultWorkspace string   `mapstructure:"default_workspace,omitempty" json:"default_workspace,omitempty" yaml:"default_workspace,omitempty"`
+	IdentityFiles    []string `mapstructure:"identity_files,omitempty" json:"identity_files,omitempty" yaml:"identity_files,omitempty"`
+
+	LoginSources map[string]*LoginSourceOverlay `mapstructure:"login_sources,omitempty" json:"login_sources,omitempty" yaml:"login_sources,omitempty"`
+	Kubeconfigs  map[string]*KubeconfigOverlay  `mapstructure:"kubeconfigs,omitempty" json:"kubeconfigs,omitempty" yaml:"kubeconfigs,omitempty"`
+}
+
+// OverlayMatch selects the hosts an overlay applies to. Every matcher that is
+// set must match.
+type OverlayMatch struct {
+	// Hostname is a glob matched against the hostname.
+	Hostname string `mapstructure:"hostname,omitempty" json:"hostname,omitempty" yaml:"hostname,omitempty"`
+	// User is the name of the current user.
+	User string `mapstructure:"user,omitempty" json:"user,omitempty" yaml:"user,omitempty"`
+	// Env is NAME to match when the variable is set, or NAME=value to match
+	// its value.
+	Env string `mapstructure:"env,omitempty" json:"env,omitempty" yaml:"env,omitempty"`
+}
+
+// KubeconfigOverlay patches a kubeconfig.
+type KubeconfigOverlay struct {
+	Path         string                         `mapstructure:"path,omitempty" json:"path,omitempty" yaml:"path,omitempty"`
+	LoginSources map[string]*LoginSourceOverlay `mapstructure:"login_sources,omitempty" json:"login_sources,omitempty" yaml:"login_sources,omitempty"`
+}
+
+// LoginSourceOverlay patches a login source. Env entries replace the entries
+// with the same key and the others are appended.
+type LoginSourceOverlay struct {
+	Env []string `mapstructure:"env,omitempty" json:"env,omitempty" yaml:"env,omitempty"`
+}
+
+// Host is what overlays are matched against.
+type Host struct {
+	Hostname  string
+	Username  string
+	LookupEnv func(key string) (string, bool)
+}
+
+// CurrentHost returns the host kubecfg runs on. Values that can't be
+// determined are left empty.
+func CurrentHost() Host {
+	h := Host{LookupEnv: os.LookupEnv}
+	h.Hostname, _ = os.Hostname()
+	if u, err := user.Current(); err == nil {
+		h.Username = u.Username
+	} else {
+		h.Username = os.Getenv("USER")
+	}
+	return h
+}
+
+// IsEmpty returns true if none of the matchers are set.
+func (m OverlayMatch) IsEmpty() bool {
+	return m.Hostname == "" && m.User == "" && m.Env == ""
+}
+
+// Matches returns true if every matcher that is set matches h. An empty match
+// matches nothing.
+func (m OverlayMatch) Matches(h Host) (bool, error) {
+	if m.IsEmpty() {
+		return false, nil
+	}
+
+	if m.Hostname != "" {
+		ok, err := path.Match(m.Hostname, h.Hostname)
+		if err != nil {
+			return false, fmt.Errorf("hostname is not a valid glob: %w", err)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+
+	if m.User != "" && m.User != h.Username {
+		return false, nil
+	}
+
+	if m.Env != "" {
+		if h.LookupEnv == nil {
+			return false, nil
+		}
+		name, want, hasValue := strings.Cut(m.Env, "=")
+		value, ok := h.LookupEnv(name)
+		if !ok || (hasValue && value != want) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// checkGlob returns an error if pattern is not a valid glob.
+func checkGlob(pattern string) error {
+	_, err := path.Match(pattern, "")
+	return err
+}
+
+// ApplyOverlays returns a copy of c with the overlays that match h applied.
+// The overlays themselves are kept, and the indexes of the ones that were
+// applied are listed in AppliedOverlays. Entries that are patched are copied,
+// c is not modified.
+func (c *Config) ApplyOverlays(h Host) (*Config, error) {
+	res := *c
+	res.AppliedOverlays = nil
+
+	for i, overlay := range c.Overlays {
+		if overlay == nil {
+			continue
+		}
+		overlayPath := fmt.Sprintf("overlays[%d]", i)
+
+		ok, err := overlay.Match.Matches(h)
+		if err != nil {
+			return nil, fmt.Errorf("%s.match.%w", overlayPath, err)
+		}
+		if !ok {
+			continue
+		}
+
+		if err := res.applyOverlay(overlayPath, overlay); err != nil {
+			return nil, err
+		}
+		res.AppliedOverlays = append(res.AppliedOverlays, i)
+	}
+
+	return &res, nil
+}
+
+func (c *Config) applyOverlay(overlayPath string, overlay *Overlay) error {
+	if overlay.BaseDir != "" {
+		c.BaseDir = overlay.BaseDir
+	}
+	if overlay.DefaultWorkspace != "" {
+		c.DefaultWorkspace = overlay.DefaultWorkspace
+	}
+	if overlay.IdentityFiles != nil {
+		c.IdentityFiles = overlay.IdentityFiles
+	}
+
+	if len(overlay.LoginSources) > 0 {
+		sources, err := patchLoginSources(overlayPath, "shared ", c.LoginSources, overlay.LoginSources)
+		if err != nil {
+			return err
+		}
+		c.LoginSources = sources
+	}
+
+	if len(overlay.Kubeconfigs) > 0 {
+		c.Kubeconfigs = maps.Clone(c.Kubeconfigs)
+	}
+	for _, name := range sortedKeys(overlay.Kubeconfigs) {
+		patch := overlay.Kubeconfigs[name]
+		patchPath := fmt.Sprintf("%s.kubeconfigs.%s", overlayPath, name)
+		if patch == nil {
+			continue
+		}
+
+		kc, ok := c.Kubeconfigs[name]
+		if !ok || kc == nil {
+			return fmt.Errorf("%s references missing kubeconfig %q", patchPath, name)
+		}
+
+		copied := *kc
+		if patch.Path != "" {
+			copied.Path = patch.Path
+		}
+		if len(patch.LoginSources) > 0 {
+			sources, err := patchLoginSources(patchPath, "", kc.LoginSources, patch.LoginSources)
+			if err != nil {
+				return err
+			}
+			copied.LoginSources = sources
+		}
+		c.Kubeconfigs[name] = &copied
+	}
+
+	return nil
+}
+
+// patchLoginSources returns a copy of sources with patches applied. kind
+// qualifies the login sources in errors.
+func patchLoginSources(overlayPath, kind string, sources map[string]*LoginSource, patches map[string]*LoginSourceOverlay) (map[string]*LoginSource, error) {
+	res := maps.Clone(sources)
+	for _, name := range sortedKeys(patches) {
+		patch := patches[name]
+		if patch == nil {
+			continue
+		}
+
+		source, ok := res[name]
+		if !ok || source == nil {
+			return nil, fmt.Errorf("%s.login_sources.%s references missing %slogin source %q", overlayPath, name, kind, name)
+		}
+
+		copied := *source
+		copied.Env = patchEnv(source.Env, patch.Env)
+		res[name] = &copied
+	}
+	return res, nil
+}
+
+// patchEnv returns env with the KEY=VALUE entries of patch applied. Entries
+// with a key already in env replace it in place, the others are appended.
+func patchEnv(env, patch []string) []string {
+	res := make([]string, len(env), len(env)+len(patch))
+	copy(res, env)
+
+	for _, entry := range patch {
+		key, _, _ := strings.Cut(entry, "=")
+		replaced := false
+		for i, existing := range res {
+			if existingKey, _, _ := strings.Cut(existing, "="); existingKey == key {
+				res[i] = entry
+				replaced = true
+			}
+		}
+		if !replaced {
+			res = append(res, entry)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newOverlaysTestConfig() *Config {
+	return &Config{
+		Version:          CurrentVersion,
+		BaseDir:          "~/.kube",
+		DefaultWorkspace: "laptop",
+		IdentityFiles:    []string{"~/.age/key.txt"},
+		Workspaces: map[string]*Workspace{
+			"laptop": {Kubeconfigs: []string{"prod"}},
+			"ci":     {Kubeconfigs: []string{"prod"}},
+		},
+		LoginSources: map[string]*LoginSource{
+			"sso": {Command: "sso-login", Env: []string{"AWS_PROFILE=dev", "AWS_REGION=eu-west-1"}},
+		},
+		Kubeconfigs: map[string]*Kubeconfig{
+			"prod": {
+				Path: "@/prod.yaml",
+				LoginSources: map[string]*LoginSource{
+					"oidc": {Command: "oidc-login", Env: []string{"BROWSER=firefox"}},
+				},
+				Clusters:  map[string]*Cluster{"main": {Server: "https://prod.example.com"}},
+				AuthInfos: map[string]*AuthInfo{"admin": {Token: "token"}},
+				Contexts:  map[string]*Context{"admin": {Cluster: "main", AuthInfo: "admin"}},
+			},
+		},
+		Overlays: []*Overlay{
+			{
+				Name:             "ci",
+				Match:            OverlayMatch{Env: "CI=true"},
+				BaseDir:          "/builds/kube",
+				DefaultWorkspace: "ci",
+				IdentityFiles:    []string{"/run/secrets/age"},
+				LoginSources: map[string]*LoginSourceOverlay{
+					"sso": {Env: []string{"AWS_PROFILE=ci"}},
+				},
+				Kubeconfigs: map[string]*KubeconfigOverlay{
+					"prod": {
+						Path: "/builds/kube/prod.yaml",
+						LoginSources: map[string]*LoginSourceOverlay{
+							"oidc": {Env: []string{"BROWSER=none", "HEADLESS=1"}},
+						},
+					},
+				},
+			},
+			{
+				Match:   OverlayMatch{Hostname: "jump-*", User: "ops"},
+				BaseDir: "/srv/kube",
+			},
+		},
+	}
+}
+
+func newOverlaysTestHost(hostname, username string, env map[string]string) Host {
+	return Host{
+		Hostname: hostname,
+		Username: username,
+		LookupEnv: func(key string) (string, bool) {
+			value, ok := env[key]
+			return value, ok
+		},
+	}
+}
+
+func TestOverlayMatchMatches(t *testing.T) {
+	host := newOverlaysTestHost("jump-01.example.com", "ops", map[string]string{"CI": "true", "EMPTY": ""})
+
+	tests := []struct {
+		name  string
+		match OverlayMatch
+		want  bool
+	}{
+		{"hostname glob", OverlayMatch{Hostname: "jump-*"}, true},
+		{"hostname mismatch", OverlayMatch{Hostname: "laptop-*"}, false},
+		{"user", OverlayMatch{User: "ops"}, true},
+		{"user mismatch", OverlayMatch{User: "root"}, false},
+		{"env set", OverlayMatch{Env: "EMPTY"}, true},
+		{"env unset", OverlayMatch{Env: "GITHUB_ACTIONS"}, false},
+		{"env value", OverlayMatch{Env: "CI=true"}, true},
+		{"env value mismatch", OverlayMatch{Env: "CI=false"}, false},
+		{"all matchers", OverlayMatch{Hostname: "jump-*", User: "ops", Env: "CI"}, true},
+		{"one matcher fails", OverlayMatch{Hostname: "jump-*", User: "root"}, false},
+		{"empty", OverlayMatch{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.match.Matches(host)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestApplyOverlaysPatchesMatchingOverlays(t *testing.T) {
+	cfg := newOverlaysTestConfig()
+
+	res, err := cfg.ApplyOverlays(newOverlaysTestHost("runner-1", "gitlab", map[string]string{"CI": "true"}))
+	require.NoError(t, err)
+
+	require.Equal(t, []int{0}, res.AppliedOverlays)
+	require.Equal(t, "/builds/kube", res.BaseDir)
+	require.Equal(t, "ci", res.DefaultWorkspace)
+	require.Equal(t, []string{"/run/secrets/age"}, res.IdentityFiles)
+	require.Equal(t, []string{"AWS_PROFILE=ci", "AWS_REGION=eu-west-1"}, res.LoginSources["sso"].Env)
+	require.Equal(t, "/builds/kube/prod.yaml", res.Kubeconfigs["prod"].Path)
+	require.Equal(t, []string{"BROWSER=none", "HEADLESS=1"}, res.Kubeconfigs["prod"].LoginSources["oidc"].Env)
+
+	// The original config is not modified.
+	original := newOverlaysTestConfig()
+	require.Equal(t, original.LoginSources, cfg.LoginSources)
+	require.Equal(t, original.Kubeconfigs, cfg.Kubeconfigs)
+	require.Equal(t, original.BaseDir, cfg.BaseDir)
+}
+
+func TestApplyOverlaysAppliesInOrder(t *testing.T) {
+	cfg := newOverlaysTestConfig()
+
+	res, err := cfg.ApplyOverlays(newOverlaysTestHost("jump-01", "ops", map[string]string{"CI": "true"}))
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 1}, res.AppliedOverlays)
+	require.Equal(t, "/srv/kube", res.BaseDir)
+
+	res, err = cfg.ApplyOverlays(newOverlaysTestHost("laptop", "me", nil))
+	require.NoError(t, err)
+	require.Empty(t, res.AppliedOverlays)
+	require.Equal(t, "~/.kube", res.BaseDir)
+}
+
+func TestApplyOverlaysFailsOnMissingReferences(t *testing.T) {
+	host := newOverlaysTestHost("runner-1", "gitlab", map[string]string{"CI": "true"})
+
+	cfg := newOverlaysTestConfig()
+	cfg.Overlays[0].Kubeconfigs["dev"] = &KubeconfigOverlay{Path: "/tmp/dev.yaml"}
+	_, err := cfg.ApplyOverlays(host)
+	require.EqualError(t, err, `overlays[0].kubeconfigs.dev references missing kubeconfig "dev"`)
+
+	cfg = newOverlaysTestConfig()
+	cfg.Overlays[0].LoginSources["vault"] = &LoginSourceOverlay{Env: []string{"VAULT_ADDR=x"}}
+	_, err = cfg.ApplyOverlays(host)
+	require.EqualError(t, err, `overlays[0].login_sources.vault references missing shared login source "vault"`)
+
+	cfg = newOverlaysTestConfig()
+	cfg.Overlays[0].Match.Hostname = "["
+	_, err = cfg.ApplyOverlays(host)
+	require.EqualError(t, err, "overlays[0].match.hostname is not a valid glob: syntax error in pattern")
+}
+
+func TestDiagnoseReportsOverlayErrors(t *testing.T) {
+	cfg := newOverlaysTestConfig()
+	require.Empty(t, cfg.Diagnose())
+
+	cfg.Overlays[0].DefaultWorkspace = "runners"
+	cfg.Overlays[0].Kubeconfigs["prod"].LoginSources["vault"] = &LoginSourceOverlay{}
+	cfg.Overlays[1].Match = OverlayMatch{}
+	cfg.Overlays = append(cfg.Overlays, nil, &Overlay{
+		Match:        OverlayMatch{Hostname: "["},
+		LoginSources: map[string]*LoginSourceOverlay{"vault": {}},
+		Kubeconfigs:  map[string]*KubeconfigOverlay{"dev": {}},
+	})
+
+	require.Equal(t, []string{
+		`error: overlays[0].default_workspace references missing workspace "runners"`,
+		`error: overlays[0].kubeconfigs.prod.login_sources.vault references missing login source "vault"`,
+		`error: overlays[1].match requires hostname, user or env`,
+		`error: overlays[2] is nil`,
+		`error: overlays[3].kubeconfigs.dev references missing kubeconfig "dev"`,
+		`error: overlays[3].login_sources.vault references missing shared login source "vault"`,
+		`error: overlays[3].match.hostname is not a valid glob: syntax error in pattern`,
+	}, diagnosticStrings(cfg.Diagnose()))
+}
+
+func TestExpandSkipsOverlays(t *testing.T) {
+	cfg := newOverlaysTestConfig()
+	cfg.Overlays[0].BaseDir = "${env:KUBECFG_TEST_UNSET_VARIABLE}"
+
+	res, diags := cfg.Expand()
+	require.Empty(t, diags)
+	require.Equal(t, "${env:KUBECFG_TEST_UNSET_VARIABLE}", res.Overlays[0].BaseDir)
+}
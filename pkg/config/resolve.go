@@ -0,0 +1,187 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrNoMatch is returned by Resolve when a reference matches nothing.
+var ErrNoMatch = errors.New("no kubeconfig or context matches")
+
+// AmbiguousRefError is returned by Resolve when a reference matches more than
+// one kubeconfig or context. Candidates are the matches as ws/kc or ws/kc/ctx.
+type AmbiguousRefError struct {
+	Ref        string
+	Candidates []string
+}
+
+func (e *AmbiguousRefError) Error() string {
+	return fmt.Sprintf("%q is ambiguous, it matches %s", e.Ref, strings.Join(e.Candidates, ", "))
+}
+
+// Ref is a kubeconfig, or a context of a kubeconfig, resolved from a name given
+// on the command line. Workspace is nil for kubeconfigs that are not in any
+// workspace, and Context is nil when the name refers to the kubeconfig.
+type Ref struct {
+	Workspace  *RuntimeWorkspace
+	Kubeconfig *RuntimeKubeconfig
+	Context    *RuntimeContext
+}
+
+// String returns the reference as ws/kc/ctx, leaving out the parts that are
+// not set.
+func (r Ref) String() string {
+	var parts []string
+	if r.Workspace != nil {
+		parts = append(parts, r.Workspace.Name)
+	}
+	if r.Kubeconfig != nil {
+		parts = append(parts, r.Kubeconfig.Name)
+	}
+	if r.Context != nil {
+		parts = append(parts, r.Context.Name)
+	}
+	return strings.Join(parts, "/")
+}
+
+// Resolve resolves ref, which is one of kc, ws/kc, kc/ctx or ws/kc/ctx where kc
+// is a kubeconfig name or alias. When nothing matches exactly, ref is matched
+// case-insensitively against every ws/kc, and then every ws/kc/ctx, and used if
+// it is part of only one of them. A kubeconfig in several workspaces resolves
+// to the default workspace if it is in it, and to the first workspace by name
+// otherwise.
+func (rc *RuntimeConfig) Resolve(ref string) (Ref, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return Ref{}, fmt.Errorf("%w %q", ErrNoMatch, ref)
+	}
+
+	matches := rc.resolveExact(ref)
+	if len(matches) == 0 {
+		matches = rc.resolveFuzzy(ref)
+	}
+
+	switch len(matches) {
+	case 0:
+		return Ref{}, fmt.Errorf("%w %q", ErrNoMatch, ref)
+	case 1:
+		return matches[0], nil
+	}
+
+	candidates := make([]string, len(matches))
+	for i, m := range matches {
+		candidates[i] = m.String()
+	}
+	slices.Sort(candidates)
+	return Ref{}, &AmbiguousRefError{Ref: ref, Candidates: candidates}
+}
+
+func (rc *RuntimeConfig) resolveExact(ref string) []Ref {
+	parts := strings.Split(ref, "/")
+
+	switch len(parts) {
+	case 1:
+		if rk, ok := rc.KubeconfigAliases[parts[0]]; ok {
+			return []Ref{{Workspace: rc.workspaceOf(rk), Kubeconfig: rk}}
+		}
+	case 2:
+		// Both ws/kc and kc/ctx are tried, a match for both is ambiguous.
+		var matches []Ref
+		if ws := rc.Workspace(parts[0]); ws != nil {
+			if rk := rc.workspaceKubeconfig(ws, parts[1]); rk != nil {
+				matches = append(matches, Ref{Workspace: ws, Kubeconfig: rk})
+			}
+		}
+		if rk, ok := rc.KubeconfigAliases[parts[0]]; ok {
+			if ctx := rk.Context(parts[1]); ctx != nil {
+				matches = append(matches, Ref{Workspace: rc.workspaceOf(rk), Kubeconfig: rk, Context: ctx})
+			}
+		}
+		return matches
+	case 3:
+		if ws := rc.Workspace(parts[0]); ws != nil {
+			if rk := rc.workspaceKubeconfig(ws, parts[1]); rk != nil {
+				if ctx := rk.Context(parts[2]); ctx != nil {
+					return []Ref{{Workspace: ws, Kubeconfig: rk, Context: ctx}}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolveFuzzy returns the kubeconfigs whose ws/kc contains ref, or if there
+// are none, the contexts whose ws/kc/ctx contains ref. A kubeconfig in several
+// workspaces counts once.
+func (rc *RuntimeConfig) resolveFuzzy(ref string) []Ref {
+	needle := strings.ToLower(ref)
+
+	var kubeconfigs []Ref
+	seen := make(map[*RuntimeKubeconfig]bool)
+	for _, ws := range rc.sortedWorkspaces() {
+		for _, name := range sortedKeys(ws.Kubeconfigs) {
+			rk := ws.Kubeconfigs[name]
+			if seen[rk] || !strings.Contains(strings.ToLower(ws.Name+"/"+rk.Name), needle) {
+				continue
+			}
+			seen[rk] = true
+			kubeconfigs = append(kubeconfigs, Ref{Workspace: rc.workspaceOf(rk), Kubeconfig: rk})
+		}
+	}
+	if len(kubeconfigs) > 0 {
+		return kubeconfigs
+	}
+
+	var contexts []Ref
+	seenContexts := make(map[*RuntimeContext]bool)
+	for _, ws := range rc.sortedWorkspaces() {
+		for _, name := range sortedKeys(ws.Kubeconfigs) {
+			rk := ws.Kubeconfigs[name]
+			for _, ctxName := range sortedKeys(rk.Contexts) {
+				ctx := rk.Contexts[ctxName]
+				if seenContexts[ctx] || !strings.Contains(strings.ToLower(ws.Name+"/"+rk.Name+"/"+ctx.Name), needle) {
+					continue
+				}
+				seenContexts[ctx] = true
+				contexts = append(contexts, Ref{Workspace: rc.workspaceOf(rk), Kubeconfig: rk, Context: ctx})
+			}
+		}
+	}
+	return contexts
+}
+
+// workspaceKubeconfig returns the kubeconfig of ws called name, which may also
+// be one of its aliases.
+func (rc *RuntimeConfig) workspaceKubeconfig(ws *RuntimeWorkspace, name string) *RuntimeKubeconfig {
+	if rk := ws.Kubeconfig(name); rk != nil {
+		return rk
+	}
+	if rk, ok := rc.KubeconfigAliases[name]; ok && ws.Kubeconfig(rk.Name) == rk {
+		return rk
+	}
+	return nil
+}
+
+// workspaceOf returns the workspace rk is resolved in, see Resolve.
+func (rc *RuntimeConfig) workspaceOf(rk *RuntimeKubeconfig) *RuntimeWorkspace {
+	if ws := rc.DefaultWorkspace; ws != nil && ws.Kubeconfig(rk.Name) == rk {
+		return ws
+	}
+	for _, ws := range rc.sortedWorkspaces() {
+		if ws.Kubeconfig(rk.Name) == rk {
+			return ws
+		}
+	}
+	return nil
+}
+
+func (rc *RuntimeConfig) sortedWorkspaces() []*RuntimeWorkspace {
+	res := make([]*RuntimeWorkspace, 0, len(rc.Workspaces))
+	for _, name := range sortedKeys(rc.Workspaces) {
+		res = append(res, rc.Workspaces[name])
+	}
+	return res
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newResolveTestRuntime(t *testing.T) *RuntimeConfig {
+	t.Helper()
+
+	kubeconfig := func(path string, aliases []string, contexts ...string) *Kubeconfig {
+		kc := &Kubeconfig{
+			Path:      path,
+			Aliases:   aliases,
+			Clusters:  map[string]*Cluster{"cluster": {Server: "https://example.com"}},
+			AuthInfos: map[string]*AuthInfo{"user": {Token: "token"}},
+			Contexts:  map[string]*Context{},
+		}
+		for _, name := range contexts {
+			kc.Contexts[name] = &Context{Cluster: "cluster", AuthInfo: "user"}
+		}
+		return kc
+	}
+
+	cfg := &Config{
+		BaseDir:          "/tmp",
+		DefaultWorkspace: "work",
+		Kubeconfigs: map[string]*Kubeconfig{
+			"payments-eu": kubeconfig("@/payments-eu.yaml", []string{"prod-eu"}, "admin", "view"),
+			"payments-us": kubeconfig("@/payments-us.yaml", nil, "admin", "view"),
+			"homelab":     kubeconfig("@/homelab.yaml", nil, "mainframe"),
+			"work":        kubeconfig("@/work.yaml", nil, "admin", "payments-eu"),
+		},
+		Workspaces: map[string]*Workspace{
+			"work": {Kubeconfigs: []string{"payments-eu", "payments-us"}},
+			"prod": {Kubeconfigs: []string{"payments-eu"}},
+			"home": {Kubeconfigs: []string{"homelab", "work"}},
+		},
+	}
+
+	rt, err := NewCompiler().Compile(cfg)
+	require.NoError(t, err)
+	return rt
+}
+
+func TestResolve(t *testing.T) {
+	rt := newResolveTestRuntime(t)
+
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{ref: "payments-eu", want: "work/payments-eu"},
+		{ref: "prod-eu", want: "work/payments-eu"},
+		{ref: "prod/payments-eu", want: "prod/payments-eu"},
+		{ref: "prod/prod-eu", want: "prod/payments-eu"},
+		{ref: "prod-eu/admin", want: "work/payments-eu/admin"},
+		{ref: "prod/prod-eu/view", want: "prod/payments-eu/view"},
+		{ref: "home/homelab/mainframe", want: "home/homelab/mainframe"},
+		// Fuzzy matches.
+		{ref: "US", want: "work/payments-us"},
+		{ref: "frame", want: "home/homelab/mainframe"},
+		{ref: "lab", want: "home/homelab"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			ref, err := rt.Resolve(tt.ref)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, ref.String())
+		})
+	}
+}
+
+func TestResolveAmbiguous(t *testing.T) {
+	rt := newResolveTestRuntime(t)
+
+	tests := []struct {
+		ref        string
+		candidates []string
+	}{
+		{ref: "payments", candidates: []string{"work/payments-eu", "work/payments-us"}},
+		{ref: "admin", candidates: []string{"home/work/admin", "work/payments-eu/admin", "work/payments-us/admin"}},
+		// Both workspace work with kubeconfig payments-eu, and kubeconfig work
+		// with context payments-eu.
+		{ref: "work/payments-eu", candidates: []string{"home/work/payments-eu", "work/payments-eu"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			_, err := rt.Resolve(tt.ref)
+			var ambiguous *AmbiguousRefError
+			require.ErrorAs(t, err, &ambiguous)
+			require.Equal(t, tt.candidates, ambiguous.Candidates)
+		})
+	}
+}
+
+func TestResolveNoMatch(t *testing.T) {
+	rt := newResolveTestRuntime(t)
+
+	_, err := rt.Resolve("staging")
+	require.ErrorIs(t, err, ErrNoMatch)
+	require.EqualError(t, err, `no kubeconfig or context matches "staging"`)
+}
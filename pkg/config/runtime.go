@@ -13,6 +13,11 @@ type RuntimeConfig struct {
 	Kubeconfigs      map[string]*RuntimeKubeconfig
 	DefaultWorkspace *RuntimeWorkspace
 
+	// Shared entries, also added to every kubeconfig that references them.
+	Clusters     map[string]*RuntimeCluster
+	AuthInfos    map[string]*RuntimeAuthInfo
+	LoginSources map[string]*RuntimeLoginSource
+
 	// Lookup indexes for CLI ergonomics.
 	KubeconfigAliases map[string]*RuntimeKubeconfig
 	Contexts          map[string]*RuntimeContextRef
@@ -21,16 +26,26 @@ type RuntimeConfig struct {
 type RuntimeWorkspace struct {
 	Name              string
 	Description       string
+	Source            string
 	DefaultKubeconfig *RuntimeKubeconfig
 	Kubeconfigs       map[string]*RuntimeKubeconfig
+
+	// Merged is true if the kubeconfigs are rendered to a single file at
+	// Path, see Merge.
+	Merged bool
+	Path   string
 }
 
 type RuntimeKubeconfig struct {
-	Name string
+	Name   string
+	Source string
 
-	Path      string
-	Protected bool
-	Aliases   []string
+	Path             string
+	Protected        bool
+	ProtectedConfirm string
+	Color            string
+	Aliases          []string
+	Labels           map[string]string
 
 	LoginSources map[string]*RuntimeLoginSource
 
@@ -154,6 +169,13 @@ type RuntimeContext struct {
 
 	Namespace string
 
+	// Labels are the labels of the kubeconfig overridden by the labels of
+	// the context.
+	Labels map[string]string
+
+	// Template is the context template that generated the context, if any.
+	Template string
+
 	Import *RuntimeImportRef
 
 	Context *api.Context
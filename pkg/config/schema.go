@@ -0,0 +1,322 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// SchemaID is the URL the published schema is served from.
+const SchemaID = "https://raw.githubusercontent.com/amimof/kubecfg/main/kubecfg.schema.json"
+
+// schemaDescriptions documents fields in the generated schema, keyed by
+// TypeName.key. Editors show these on hover and during completion.
+var schemaDescriptions = map[string]string{
+	"Config.version":           "Config version. Older versions can be upgraded with kubecfg migrate.",
+	"Config.default_workspace": "Workspace used when --workspace is omitted.",
+	"Config.workspaces":        "Named groups of kubeconfigs.",
+	"Config.kubeconfigs":       "Kubeconfigs rendered by kubecfg, keyed by name.",
+	"Config.base_dir":          "Base directory used for paths starting with \"@/\". Defaults to ~/.kube.",
+	"Config.identity_files":    "age identity files used to decrypt encrypted fields.",
+	"Config.backups":           "Number of previous versions kept of each rendered kubeconfig, as <path>.bak.1 (newest) to <path>.bak.N. Defaults to 3, 0 disables backups.",
+	"Config.lint":              "Settings for kubecfg lint.",
+	"Config.vars":              "Values available to ${var:name} references in any string field.",
+	"Config.clusters":          "Clusters shared by all kubeconfigs. Referenced from contexts as shared:<name>.",
+	"Config.auth_infos":        "Auth infos shared by all kubeconfigs. Referenced from contexts as shared:<name>.",
+	"Config.login_sources":     "Login sources shared by all kubeconfigs. Referenced from import_ref as shared:<name> and run once per render.",
+	"Config.include":           "Globs of additional config files to merge, relative to this file. Files in kubecfg.d are merged as well.",
+	"Config.overlays":          "Patches applied in order on the hosts they match, before interpolation.",
+
+	"LintConfig.disable": "Lint rule IDs that should not be run.",
+
+	"Overlay.name":              "Name shown by kubecfg config view --effective.",
+	"Overlay.match":             "Hosts the overlay applies to. Every matcher that is set must match.",
+	"Overlay.base_dir":          "Replaces base_dir.",
+	"Overlay.default_workspace": "Replaces default_workspace.",
+	"Overlay.identity_files":    "Replaces identity_files.",
+	"Overlay.login_sources":     "Patches shared login sources.",
+	"Overlay.kubeconfigs":       "Patches kubeconfigs.",
+
+	"OverlayMatch.hostname": "Glob matched against the hostname, for example *.ci.example.com.",
+	"OverlayMatch.user":     "Name of the current user.",
+	"OverlayMatch.env":      "NAME to match when the environment variable is set, or NAME=value to match its value.",
+
+	"KubeconfigOverlay.path":          "Replaces the output path of the kubeconfig.",
+	"KubeconfigOverlay.login_sources": "Patches login sources of the kubeconfig.",
+
+	"LoginSourceOverlay.env": "KEY=VALUE entries that replace entries with the same key in env. Others are appended.",
+
+	"Workspace.description":        "Free-form description shown by kubecfg workspaces.",
+	"Workspace.kubeconfigs":        "Names of kubeconfigs in this workspace.",
+	"Workspace.default_kubeconfig": "Kubeconfig selected when none is given. Must be listed in kubeconfigs or matched by selector.",
+	"Workspace.selector":           "Adds every kubeconfig whose labels match to the workspace, in addition to kubeconfigs.",
+	"Workspace.render":             "separate renders each kubeconfig to its own path, merged renders the workspace to a single file with entries named <kubeconfig>/<name>.",
+	"Workspace.path":               "Output path of a merged workspace. Defaults to @/<workspace>.yaml.",
+
+	"LabelSelector.matchLabels":      "Labels a kubeconfig must have, all of them with the given value.",
+	"LabelSelector.matchExpressions": "Expressions a kubeconfig's labels must all satisfy.",
+
+	"LabelSelectorRequirement.operator": "One of In, NotIn, Exists and DoesNotExist.",
+	"LabelSelectorRequirement.values":   "Values for In and NotIn. Must be empty for Exists and DoesNotExist.",
+
+	"Kubeconfig.extends":           "Kubeconfig to inherit clusters, auth_infos, contexts, context_templates, login_sources, current_context and default_namespace from. Local entries win.",
+	"Kubeconfig.path":              "Output path of the rendered kubeconfig. Paths starting with \"@/\" are relative to base_dir.",
+	"Kubeconfig.protected":         "Asks for confirmation before render, use and login activate the kubeconfig.",
+	"Kubeconfig.protected_confirm": "How activation is confirmed: yes answers a y/N prompt, type-name requires typing the kubeconfig name.",
+	"Kubeconfig.color":             "Color kubecfg prompt shows the kubeconfig in, for example red for production.",
+	"Kubeconfig.aliases":           "Alternative names accepted wherever a kubeconfig name is.",
+	"Kubeconfig.labels":            "Labels matched by workspace selectors and the -l flag.",
+	"Kubeconfig.current_context":   "current-context of the rendered kubeconfig.",
+	"Kubeconfig.default_context":   "Context used when current_context is not set.",
+	"Kubeconfig.default_namespace": "Namespace used by contexts that do not set one.",
+	"Kubeconfig.login_sources":     "Commands that produce kubeconfigs to import contexts from.",
+	"Kubeconfig.context_templates": "Templates that generate a context for every combination of clusters, namespaces and users.",
+
+	"Context.cluster":    "Cluster key from this kubeconfig, or shared:<name> for a shared cluster. Required unless import_ref is set.",
+	"Context.authInfo":   "Auth info key from this kubeconfig, or shared:<name> for a shared auth info. Required unless import_ref is set.",
+	"Context.import_ref": "Imports cluster and auth info from the kubeconfig produced by a login source.",
+	"Context.labels":     "Labels matched by the -l flag. Added to the labels of the kubeconfig.",
+
+	"ImportRef.login_source": "Login source in this kubeconfig to import from, or shared:<name> for a shared login source.",
+	"ImportRef.context":      "Context in the login source output to import.",
+	"ImportRef.cluster":      "Name of the imported cluster. Defaults to the name used by the login source.",
+	"ImportRef.auth_info":    "Name of the imported auth info. Defaults to the name used by the login source.",
+
+	"ContextTemplate.name":       "text/template naming each generated context, for example {{.cluster}}-{{.namespace}}-{{.user}}.",
+	"ContextTemplate.clusters":   "Cluster keys from this kubeconfig, or shared:<name> for shared clusters.",
+	"ContextTemplate.namespaces": "Namespaces of the generated contexts. Defaults to the default namespace of the kubeconfig.",
+	"ContextTemplate.users":      "Auth info keys from this kubeconfig, or shared:<name> for shared auth infos.",
+	"ContextTemplate.labels":     "Labels of the generated contexts.",
+
+	"LoginSource.command":    "Command to run.",
+	"LoginSource.outputMode": "Reserved, not currently used by kubecfg.",
+	"LoginSource.env_file":   "File with KEY=VALUE lines. Overrides duplicate keys from env.",
+}
+
+// schemaRules adds constraints to type definitions that can't be derived from
+// the struct tree. They mirror the checks done by Validate and the compiler.
+var schemaRules = map[string]func(def map[string]any){
+	"Config": func(def map[string]any) {
+		version := def["properties"].(map[string]any)["version"].(map[string]any)
+		version["enum"] = []string{CurrentVersion}
+		backups := def["properties"].(map[string]any)["backups"].(map[string]any)
+		backups["minimum"] = 0
+	},
+	"Workspace": func(def map[string]any) {
+		render := def["properties"].(map[string]any)["render"].(map[string]any)
+		render["enum"] = []string{WorkspaceRenderSeparate, WorkspaceRenderMerged}
+	},
+	"Kubeconfig": func(def map[string]any) {
+		def["required"] = []string{"path"}
+		confirm := def["properties"].(map[string]any)["protected_confirm"].(map[string]any)
+		confirm["enum"] = []string{ProtectedConfirmYes, ProtectedConfirmTypeName}
+		color := def["properties"].(map[string]any)["color"].(map[string]any)
+		color["enum"] = KubeconfigColors
+	},
+	"LoginSource": func(def map[string]any) {
+		def["required"] = []string{"command"}
+	},
+	"ContextTemplate": func(def map[string]any) {
+		def["required"] = []string{"name", "clusters", "users"}
+	},
+	"AuthProviderConfig": func(def map[string]any) {
+		def["required"] = []string{"name"}
+	},
+	"ExecConfig": func(def map[string]any) {
+		def["required"] = []string{"command"}
+	},
+	"LabelSelectorRequirement": func(def map[string]any) {
+		def["required"] = []string{"key", "operator"}
+		operator := def["properties"].(map[string]any)["operator"].(map[string]any)
+		operator["enum"] = []string{"In", "NotIn", "Exists", "DoesNotExist"}
+	},
+	"ExecEnvVar": func(def map[string]any) {
+		def["required"] = []string{"name", "value"}
+	},
+	// cluster and authInfo are required unless the context imports them.
+	"Context": func(def map[string]any) {
+		def["if"] = map[string]any{
+			"required": []string{"import_ref"},
+			"properties": map[string]any{
+				"import_ref": map[string]any{"type": "object", "minProperties": 1},
+			},
+		}
+		def["else"] = map[string]any{
+			"required": []string{"cluster", "authInfo"},
+		}
+	},
+	// login_source and context are required together, and explicit names are
+	// only allowed alongside them.
+	"ImportRef": func(def map[string]any) {
+		def["dependencies"] = map[string]any{
+			"login_source": []string{"context"},
+			"context":      []string{"login_source"},
+			"cluster":      []string{"login_source", "context"},
+			"auth_info":    []string{"login_source", "context"},
+		}
+	},
+}
+
+var (
+	runtimeObjectType       = reflect.TypeFor[runtime.Object]()
+	execInteractiveModeType = reflect.TypeFor[ExecInteractiveMode]()
+)
+
+// Schema returns a JSON Schema (draft-07) describing kubecfg.yaml, generated
+// from the Config struct tree.
+func Schema() ([]byte, error) {
+	g := &schemaGenerator{definitions: make(map[string]any)}
+
+	root, err := g.structSchema(reflect.TypeFor[Config]())
+	if err != nil {
+		return nil, err
+	}
+
+	root["$schema"] = "http://json-schema.org/draft-07/schema#"
+	root["$id"] = SchemaID
+	root["title"] = "kubecfg configuration"
+	root["definitions"] = g.definitions
+
+	b, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(b, '\n'), nil
+}
+
+type schemaGenerator struct {
+	definitions map[string]any
+}
+
+func (g *schemaGenerator) typeSchema(t reflect.Type) (map[string]any, error) {
+	switch {
+	case t == runtimeObjectType:
+		return map[string]any{}, nil
+	case t == execInteractiveModeType:
+		return map[string]any{
+			"type": "string",
+			"enum": []string{"Never", "IfAvailable", "Always"},
+		}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.typeSchema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string"}, nil
+		}
+		items, err := g.typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		values, err := g.typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		if _, ok := g.definitions[t.Name()]; !ok {
+			// Reserve the name first so that recursive types terminate.
+			g.definitions[t.Name()] = nil
+			def, err := g.structSchema(t)
+			if err != nil {
+				return nil, err
+			}
+			g.definitions[t.Name()] = def
+		}
+		return map[string]any{"$ref": "#/definitions/" + t.Name()}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) (map[string]any, error) {
+	properties := make(map[string]any)
+
+	for field := range t.Fields() {
+		key, ok := schemaKey(field)
+		if !ok {
+			continue
+		}
+
+		prop, err := g.typeSchema(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t.Name(), field.Name, err)
+		}
+
+		if desc, ok := schemaDescriptions[t.Name()+"."+key]; ok {
+			if _, isRef := prop["$ref"]; isRef {
+				// Siblings of $ref are ignored in draft-07.
+				prop = map[string]any{"allOf": []any{prop}}
+			}
+			prop["description"] = desc
+		}
+
+		properties[key] = prop
+	}
+
+	def := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+
+	if rule, ok := schemaRules[t.Name()]; ok {
+		rule(def)
+	}
+
+	return def, nil
+}
+
+// schemaKey returns the key a field is decoded from. The decoder uses the
+// mapstructure tag and falls back to the field name, matched
+// case-insensitively, so untagged fields are published in lower camel case.
+func schemaKey(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+
+	name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+	if name == "-" {
+		return "", false
+	}
+	if name != "" {
+		return name, true
+	}
+
+	return lowerCamel(field.Name), true
+}
+
+// lowerCamel lowercases the leading upper case run of name, keeping the last
+// letter of an initialism that starts the next word, so APIVersion becomes
+// apiVersion and Token becomes token.
+func lowerCamel(name string) string {
+	runes := []rune(name)
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) {
+			break
+		}
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaMatchesPublishedFile(t *testing.T) {
+	schema, err := Schema()
+	require.NoError(t, err)
+
+	published, err := os.ReadFile(filepath.Join("..", "..", "kubecfg.schema.json"))
+	require.NoError(t, err)
+	require.Equal(t, string(published), string(schema), "kubecfg.schema.json is out of date, run make schema")
+}
+
+func TestSchemaCoversAllFields(t *testing.T) {
+	definitions := loadSchemaDefinitions(t)
+
+	for _, typ := range []reflect.Type{
+		reflect.TypeFor[Workspace](),
+		reflect.TypeFor[Kubeconfig](),
+		reflect.TypeFor[Cluster](),
+		reflect.TypeFor[AuthInfo](),
+		reflect.TypeFor[LoginSource](),
+		reflect.TypeFor[Context](),
+		reflect.TypeFor[ImportRef](),
+		reflect.TypeFor[ExecConfig](),
+	} {
+		def, ok := definitions[typ.Name()].(map[string]any)
+		require.True(t, ok, "missing definition for %s", typ.Name())
+		properties := def["properties"].(map[string]any)
+
+		keys := 0
+		for field := range typ.Fields() {
+			key, ok := schemaKey(field)
+			if !ok {
+				continue
+			}
+			require.Contains(t, properties, key, "%s.%s is not in the schema", typ.Name(), field.Name)
+			keys++
+		}
+		require.Len(t, properties, keys, "%s has properties that are not struct fields", typ.Name())
+	}
+}
+
+func TestSchemaEncodesContextRules(t *testing.T) {
+	definitions := loadSchemaDefinitions(t)
+
+	context := definitions["Context"].(map[string]any)
+	require.Equal(t, []any{"cluster", "authInfo"}, context["else"].(map[string]any)["required"])
+	require.Equal(t, []any{"import_ref"}, context["if"].(map[string]any)["required"])
+
+	importRef := definitions["ImportRef"].(map[string]any)
+	require.Equal(t, map[string]any{
+		"login_source": []any{"context"},
+		"context":      []any{"login_source"},
+		"cluster":      []any{"login_source", "context"},
+		"auth_info":    []any{"login_source", "context"},
+	}, importRef["dependencies"])
+
+	kubeconfig := definitions["Kubeconfig"].(map[string]any)
+	require.Equal(t, []any{"path"}, kubeconfig["required"])
+}
+
+func loadSchemaDefinitions(t *testing.T) map[string]any {
+	t.Helper()
+
+	schema, err := Schema()
+	require.NoError(t, err)
+
+	var root map[string]any
+	require.NoError(t, json.Unmarshal(schema, &root))
+
+	return root["definitions"].(map[string]any)
+}
+
+func TestLowerCamel(t *testing.T) {
+	for in, want := range map[string]string{
+		"Token":            "token",
+		"TokenFile":        "tokenFile",
+		"APIVersion":       "apiVersion",
+		"ImpersonateUID":   "impersonateUID",
+		"LocationOfOrigin": "locationOfOrigin",
+		"UID":              "uid",
+	} {
+		require.Equal(t, want, lowerCamel(in), in)
+	}
+}
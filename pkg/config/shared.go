@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SharedPrefix marks a reference to one of the top level clusters, auth_infos
+// or login_sources, for example cluster: shared:prod-eu.
+const SharedPrefix = "shared:"
+
+// SharedName returns the name of the shared entry that ref points to, and
+// false if ref is not a shared reference.
+func SharedName(ref string) (string, bool) {
+	return strings.CutPrefix(strings.TrimSpace(ref), SharedPrefix)
+}
+
+// compileShared compiles the top level clusters, auth infos and login sources
+// once so that every kubeconfig referencing them gets the same runtime entry.
+func (c *Compiler) compileShared(rt *RuntimeConfig, cfg *Config) error {
+	for _, name := range sortedKeys(cfg.Clusters) {
+		cluster := cfg.Clusters[name]
+		if cluster == nil {
+			return fmt.Errorf("clusters.%s is nil", name)
+		}
+		rt.Clusters[name] = compileCluster(name, cluster)
+	}
+
+	for _, name := range sortedKeys(cfg.AuthInfos) {
+		compiler := &AuthInfoCompiler{Decryptor: c.Decryptor, SkipDecryption: c.SkipDecryption}
+		rai, err := compiler.Compile(name, cfg.AuthInfos[name])
+		if err != nil {
+			return fmt.Errorf("auth_infos.%s: %w", name, err)
+		}
+		rt.AuthInfos[name] = rai
+	}
+
+	for _, name := range sortedKeys(cfg.LoginSources) {
+		ls := cfg.LoginSources[name]
+		if ls == nil {
+			return fmt.Errorf("login_sources.%s is nil", name)
+		}
+		rls, err := compileLoginSource(name, ls)
+		if err != nil {
+			return fmt.Errorf("login_sources.%s.env_file: %w", name, err)
+		}
+		rt.LoginSources[name] = rls
+	}
+
+	return nil
+}
+
+// resolveSharedRefs adds the shared entries referenced by contexts to rkc,
+// keyed by the reference so that the contexts resolve them like local entries.
+// They are rendered under their shared name, which must not also be used by a
+// local entry of kc.
+func resolveSharedRefs(rt *RuntimeConfig, rkc *RuntimeKubeconfig, kc *Kubeconfig, contexts map[string]*Context) error {
+	for _, contextName := range sortedKeys(contexts) {
+		context := contexts[contextName]
+		if context == nil {
+			continue
+		}
+		path := fmt.Sprintf("kubeconfigs.%s.contexts.%s", rkc.Name, contextName)
+
+		if name, ok := SharedName(context.Cluster); ok {
+			cluster, ok := rt.Clusters[name]
+			if !ok {
+				return fmt.Errorf("%s.cluster references missing shared cluster %q", path, name)
+			}
+			if _, ok := kc.Clusters[name]; ok {
+				return fmt.Errorf("%s.cluster references shared cluster %q which clashes with the cluster of the same name in this kubeconfig", path, name)
+			}
+			rkc.Clusters[SharedPrefix+name] = cluster
+		}
+
+		if name, ok := SharedName(context.AuthInfo); ok {
+			authInfo, ok := rt.AuthInfos[name]
+			if !ok {
+				return fmt.Errorf("%s.authinfo references missing shared authinfo %q", path, name)
+			}
+			if _, ok := kc.AuthInfos[name]; ok {
+				return fmt.Errorf("%s.authinfo references shared authinfo %q which clashes with the authinfo of the same name in this kubeconfig", path, name)
+			}
+			rkc.AuthInfos[SharedPrefix+name] = authInfo
+		}
+
+		if name, ok := SharedName(context.ImportRef.LoginSourceName); ok {
+			source, ok := rt.LoginSources[name]
+			if !ok {
+				return fmt.Errorf("%s.import_ref.login_source references missing shared login source %q", path, name)
+			}
+			rkc.LoginSources[SharedPrefix+name] = source
+		}
+	}
+
+	return nil
+}
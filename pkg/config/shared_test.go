@@ -0,0 +1,89 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newSharedTestConfig() *Config {
+	return &Config{
+		BaseDir: "/tmp",
+		Clusters: map[string]*Cluster{
+			"prod-eu": {Server: "https://prod-eu.example.com", CertificateAuthority: "/etc/ca.pem"},
+		},
+		AuthInfos: map[string]*AuthInfo{
+			"oidc": {Token: "token"},
+		},
+		LoginSources: map[string]*LoginSource{
+			"sso": {Command: "sso-login"},
+		},
+		Kubeconfigs: map[string]*Kubeconfig{
+			"team-a": {
+				Path: "@/team-a.yaml",
+				Contexts: map[string]*Context{
+					"prod": {Cluster: "shared:prod-eu", AuthInfo: "shared:oidc"},
+				},
+			},
+			"team-b": {
+				Path: "@/team-b.yaml",
+				Contexts: map[string]*Context{
+					"prod": {Cluster: "shared:prod-eu", AuthInfo: "shared:oidc"},
+					"sso":  {ImportRef: ImportRef{LoginSourceName: "shared:sso", ContextName: "prod"}},
+				},
+			},
+		},
+	}
+}
+
+func TestCompileResolvesSharedEntries(t *testing.T) {
+	rt, err := NewCompiler().Compile(newSharedTestConfig())
+	require.NoError(t, err)
+
+	a, b := rt.Kubeconfigs["team-a"], rt.Kubeconfigs["team-b"]
+
+	// Shared entries are rendered under their own name.
+	require.Equal(t, "https://prod-eu.example.com", a.Config.Clusters["prod-eu"].Server)
+	require.Equal(t, "token", a.Config.AuthInfos["oidc"].Token)
+	require.Equal(t, "prod-eu", a.Config.Contexts["prod"].Cluster)
+	require.Equal(t, "oidc", a.Config.Contexts["prod"].AuthInfo)
+
+	// Every kubeconfig gets the same runtime entry.
+	require.Same(t, rt.Clusters["prod-eu"], a.Cluster("shared:prod-eu"))
+	require.Same(t, rt.Clusters["prod-eu"], b.Cluster("shared:prod-eu"))
+	require.Same(t, rt.LoginSources["sso"], b.LoginSources["shared:sso"])
+
+	// Only referenced entries are added.
+	require.NotContains(t, a.LoginSources, "shared:sso")
+}
+
+func TestCompileFailsWhenSharedEntryIsMissing(t *testing.T) {
+	cfg := newSharedTestConfig()
+	cfg.Kubeconfigs["team-a"].Contexts["prod"].Cluster = "shared:prod-us"
+
+	_, err := NewCompiler().Compile(cfg)
+	require.EqualError(t, err, `kubeconfigs.team-a.contexts.prod.cluster references missing shared cluster "prod-us"`)
+}
+
+func TestCompileFailsWhenSharedEntryClashesWithLocalEntry(t *testing.T) {
+	cfg := newSharedTestConfig()
+	cfg.Kubeconfigs["team-a"].AuthInfos = map[string]*AuthInfo{"oidc": {Token: "local"}}
+
+	_, err := NewCompiler().Compile(cfg)
+	require.EqualError(t, err, `kubeconfigs.team-a.contexts.prod.authinfo references shared authinfo "oidc" which clashes with the authinfo of the same name in this kubeconfig`)
+}
+
+func TestDiagnoseReportsSharedReferences(t *testing.T) {
+	cfg := newSharedTestConfig()
+	cfg.LoginSources["sso"].Command = ""
+	cfg.Kubeconfigs["team-a"].Contexts["prod"].Cluster = "shared:prod-us"
+	cfg.Kubeconfigs["team-b"].Contexts["sso"].ImportRef.LoginSourceName = "shared:missing"
+	cfg.Kubeconfigs["team-b"].AuthInfos = map[string]*AuthInfo{"oidc": {}}
+
+	require.Equal(t, []string{
+		`error: kubeconfigs.team-a.contexts.prod.cluster references missing shared cluster "prod-us"`,
+		`error: kubeconfigs.team-b.contexts.prod.authinfo references shared authinfo "oidc" which clashes with the authinfo of the same name in this kubeconfig`,
+		`error: kubeconfigs.team-b.contexts.sso.import_ref.login_source references missing shared login source "missing"`,
+		`error: login_sources.sso.command is required`,
+	}, diagnosticStrings(cfg.Diagnose()))
+}
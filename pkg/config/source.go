@@ -0,0 +1,67 @@
+package config
+
+import "os"
+
+// SourceMap resolves dotted field paths, as used by Diagnostic, to line and
+// column positions in a YAML document.
+type SourceMap struct {
+	doc *Document
+}
+
+// NewSourceMap parses data and returns a SourceMap for it.
+func NewSourceMap(data []byte) (*SourceMap, error) {
+	doc, err := ParseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	return &SourceMap{doc: doc}, nil
+}
+
+// Position returns the position of the node at path. If path only partially
+// exists in the document, the position of the deepest existing node is
+// returned. Map keys are matched case-insensitively, the same way the config
+// decoder matches them.
+func (s *SourceMap) Position(path string) (int, int, bool) {
+	if s == nil || s.doc == nil {
+		return 0, 0, false
+	}
+
+	return s.doc.Position(path)
+}
+
+// Locate fills in Line and Column on every diagnostic whose path can be found
+// in the source document.
+func (s *SourceMap) Locate(diags Diagnostics) {
+	for i := range diags {
+		if line, column, ok := s.Position(diags[i].Path); ok {
+			diags[i].Line = line
+			diags[i].Column = column
+		}
+	}
+}
+
+// LocateFiles fills in Line and Column on every diagnostic from the file it was
+// loaded from. Diagnostics without a File are assigned defaultFile. Files that
+// can't be read or parsed are skipped.
+func LocateFiles(diags Diagnostics, defaultFile string) {
+	sourceMaps := make(map[string]*SourceMap)
+
+	for i := range diags {
+		if diags[i].File == "" {
+			diags[i].File = defaultFile
+		}
+
+		sourceMap, ok := sourceMaps[diags[i].File]
+		if !ok {
+			if data, err := os.ReadFile(diags[i].File); err == nil {
+				sourceMap, _ = NewSourceMap(data)
+			}
+			sourceMaps[diags[i].File] = sourceMap
+		}
+
+		if line, column, ok := sourceMap.Position(diags[i].Path); ok {
+			diags[i].Line = line
+			diags[i].Column = column
+		}
+	}
+}
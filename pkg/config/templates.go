@@ -0,0 +1,133 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// ContextTemplate generates a context for every combination of its clusters,
+// namespaces and users. Name is a text/template that names each context, with
+// .cluster, .namespace and .user set to the combination.
+type ContextTemplate struct {
+	Name       string            `mapstructure:"name,omitempty" json:"name,omitempty" yaml:"name,omitempty"`
+	Clusters   []string          `mapstructure:"clusters,omitempty" json:"clusters,omitempty" yaml:"clusters,omitempty"`
+	Namespaces []string          `mapstructure:"namespaces,omitempty" json:"namespaces,omitempty" yaml:"namespaces,omitempty"`
+	Users      []string          `mapstructure:"users,omitempty" json:"users,omitempty" yaml:"users,omitempty"`
+	Labels     map[string]string `mapstructure:"labels,omitempty" json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// TemplateError is returned when a context template can't be expanded. Field
+// is the template field at fault.
+type TemplateError struct {
+	Field   string
+	Message string
+}
+
+func (e *TemplateError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+func templateErrorf(field, format string, args ...any) *TemplateError {
+	return &TemplateError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+// Expand returns the names of the contexts generated by t in generation order
+// and the contexts keyed by name. Without namespaces, the contexts use the
+// default namespace of the kubeconfig. Errors are of type *TemplateError.
+func (t *ContextTemplate) Expand() ([]string, map[string]*Context, error) {
+	switch {
+	case strings.TrimSpace(t.Name) == "":
+		return nil, nil, templateErrorf("name", "is required")
+	case len(t.Clusters) == 0:
+		return nil, nil, templateErrorf("clusters", "is required")
+	case len(t.Users) == 0:
+		return nil, nil, templateErrorf("users", "is required")
+	}
+
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(t.Name)
+	if err != nil {
+		return nil, nil, templateErrorf("name", "is not a valid template: %v", err)
+	}
+
+	namespaces := t.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+
+	var names []string
+	contexts := make(map[string]*Context)
+	for _, cluster := range t.Clusters {
+		for _, namespace := range namespaces {
+			for _, user := range t.Users {
+				var b bytes.Buffer
+				err := tmpl.Execute(&b, map[string]string{
+					"cluster":   cluster,
+					"namespace": namespace,
+					"user":      user,
+				})
+				if err != nil {
+					return nil, nil, templateErrorf("name", "is not a valid template: %v", err)
+				}
+
+				name := strings.TrimSpace(b.String())
+				if name == "" {
+					return nil, nil, templateErrorf("name", "generates an empty context name for cluster %q, namespace %q and user %q", cluster, namespace, user)
+				}
+				if _, ok := contexts[name]; ok {
+					return nil, nil, templateErrorf("name", "generates context %q more than once", name)
+				}
+
+				names = append(names, name)
+				contexts[name] = &Context{
+					Cluster:   cluster,
+					AuthInfo:  user,
+					Namespace: namespace,
+					Labels:    t.Labels,
+				}
+			}
+		}
+	}
+
+	return names, contexts, nil
+}
+
+// expandContextTemplates returns the contexts of kc together with the ones
+// generated by its context templates, and the template that generated each
+// generated context. Generated contexts can't replace other contexts.
+func expandContextTemplates(kcName string, kc *Kubeconfig) (map[string]*Context, map[string]string, error) {
+	if len(kc.ContextTemplates) == 0 {
+		return kc.Contexts, nil, nil
+	}
+
+	contexts := make(map[string]*Context, len(kc.Contexts))
+	for name, ctx := range kc.Contexts {
+		contexts[name] = ctx
+	}
+
+	generatedBy := make(map[string]string)
+	for _, templateName := range sortedKeys(kc.ContextTemplates) {
+		path := fmt.Sprintf("kubeconfigs.%s.context_templates.%s", kcName, templateName)
+
+		tmpl := kc.ContextTemplates[templateName]
+		if tmpl == nil {
+			return nil, nil, fmt.Errorf("%s is nil", path)
+		}
+
+		names, generated, err := tmpl.Expand()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s.%w", path, err)
+		}
+
+		for _, name := range names {
+			if _, ok := contexts[name]; ok {
+				return nil, nil, fmt.Errorf("%s.name generates context %q which is already defined", path, name)
+			}
+			contexts[name] = generated[name]
+			generatedBy[name] = templateName
+		}
+	}
+
+	return contexts, generatedBy, nil
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTemplatesTestConfig() *Config {
+	return &Config{
+		BaseDir: "/tmp",
+		Clusters: map[string]*Cluster{
+			"edge": {Server: "https://edge.example.com"},
+		},
+		Kubeconfigs: map[string]*Kubeconfig{
+			"payments": {
+				Path:             "@/payments.yaml",
+				DefaultNamespace: "default",
+				CurrentContext:   "prod-api-admin",
+				Labels:           map[string]string{"team": "payments"},
+				Clusters: map[string]*Cluster{
+					"prod":  {Server: "https://prod.example.com"},
+					"stage": {Server: "https://stage.example.com"},
+				},
+				AuthInfos: map[string]*AuthInfo{
+					"admin": {Token: "admin"},
+					"view":  {Token: "view"},
+				},
+				Contexts: map[string]*Context{
+					"manual": {Cluster: "prod", AuthInfo: "admin"},
+				},
+				ContextTemplates: map[string]*ContextTemplate{
+					"matrix": {
+						Name:       "{{.cluster}}-{{.namespace}}-{{.user}}",
+						Clusters:   []string{"prod", "stage"},
+						Namespaces: []string{"api", "web"},
+						Users:      []string{"admin", "view"},
+						Labels:     map[string]string{"generated": "true"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestContextTemplateExpandGeneratesCartesianProduct(t *testing.T) {
+	tmpl := newTemplatesTestConfig().Kubeconfigs["payments"].ContextTemplates["matrix"]
+
+	names, contexts, err := tmpl.Expand()
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"prod-api-admin", "prod-api-view", "prod-web-admin", "prod-web-view",
+		"stage-api-admin", "stage-api-view", "stage-web-admin", "stage-web-view",
+	}, names)
+	require.Equal(t, &Context{
+		Cluster:   "stage",
+		AuthInfo:  "view",
+		Namespace: "web",
+		Labels:    map[string]string{"generated": "true"},
+	}, contexts["stage-web-view"])
+}
+
+func TestContextTemplateExpandWithoutNamespaces(t *testing.T) {
+	tmpl := &ContextTemplate{
+		Name:     "{{.cluster}}",
+		Clusters: []string{"prod"},
+		Users:    []string{"admin"},
+	}
+
+	names, contexts, err := tmpl.Expand()
+	require.NoError(t, err)
+	require.Equal(t, []string{"prod"}, names)
+	require.Empty(t, contexts["prod"].Namespace)
+}
+
+func TestContextTemplateExpandErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl ContextTemplate
+		err  string
+	}{
+		{
+			name: "missing name",
+			tmpl: ContextTemplate{Clusters: []string{"prod"}, Users: []string{"admin"}},
+			err:  "name is required",
+		},
+		{
+			name: "missing users",
+			tmpl: ContextTemplate{Name: "{{.cluster}}", Clusters: []string{"prod"}},
+			err:  "users is required",
+		},
+		{
+			name: "unknown key",
+			tmpl: ContextTemplate{Name: "{{.region}}", Clusters: []string{"prod"}, Users: []string{"admin"}},
+			err:  `name is not a valid template: template: name:1:2: executing "name" at <.region>: map has no entry for key "region"`,
+		},
+		{
+			name: "duplicate names",
+			tmpl: ContextTemplate{Name: "{{.cluster}}", Clusters: []string{"prod"}, Users: []string{"admin", "view"}},
+			err:  `name generates context "prod" more than once`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := tt.tmpl.Expand()
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestCompileExpandsContextTemplates(t *testing.T) {
+	cfg := newTemplatesTestConfig()
+	cfg.Kubeconfigs["payments"].ContextTemplates["edge"] = &ContextTemplate{
+		Name:     "edge-{{.user}}",
+		Clusters: []string{"shared:edge"},
+		Users:    []string{"view"},
+	}
+
+	rt, err := NewCompiler().Compile(cfg)
+	require.NoError(t, err)
+
+	rk := rt.Kubeconfigs["payments"]
+	require.Len(t, rk.Contexts, 10)
+	require.Equal(t, "prod-api-admin", rk.CurrentContext.Name)
+
+	ctx := rk.Context("stage-web-view")
+	require.Equal(t, "matrix", ctx.Template)
+	require.Equal(t, "stage", ctx.ClusterKey)
+	require.Equal(t, "view", ctx.AuthInfoKey)
+	require.Equal(t, "web", ctx.Namespace)
+	require.Equal(t, map[string]string{"team": "payments", "generated": "true"}, ctx.Labels)
+
+	edge := rk.Context("edge-view")
+	require.Equal(t, "edge", edge.Template)
+	require.Equal(t, "https://edge.example.com", edge.Cluster.Cluster.Server)
+	require.Equal(t, "default", edge.Namespace)
+
+	require.Empty(t, rk.Context("manual").Template)
+}
+
+func TestCompileRejectsGeneratedContextClash(t *testing.T) {
+	cfg := newTemplatesTestConfig()
+	cfg.Kubeconfigs["payments"].Contexts["prod-web-view"] = &Context{Cluster: "prod", AuthInfo: "view"}
+
+	_, err := NewCompiler().Compile(cfg)
+	require.EqualError(t, err, `kubeconfigs.payments.context_templates.matrix.name generates context "prod-web-view" which is already defined`)
+}
+
+func TestDiagnoseReportsContextTemplateErrors(t *testing.T) {
+	cfg := newTemplatesTestConfig()
+	require.Empty(t, cfg.Diagnose())
+
+	kc := cfg.Kubeconfigs["payments"]
+	kc.ContextTemplates["matrix"].Clusters = []string{"prod", "dev"}
+	kc.ContextTemplates["matrix"].Users = []string{"admin", "shared:ops"}
+	kc.ContextTemplates["broken"] = &ContextTemplate{Name: "{{.cluster", Clusters: []string{"prod"}, Users: []string{"admin"}}
+	kc.ContextTemplates["clash"] = &ContextTemplate{Name: "manual", Clusters: []string{"prod"}, Users: []string{"admin"}}
+	kc.ContextTemplates["empty"] = &ContextTemplate{Clusters: []string{"prod"}}
+	kc.DefaultContext = "dev-api-view"
+
+	diags := diagnosticStrings(cfg.Diagnose())
+	require.Len(t, diags, 6)
+	require.Contains(t, diags[0], `error: kubeconfigs.payments.context_templates.broken.name is not a valid template: `)
+	require.Equal(t, []string{
+		`error: kubeconfigs.payments.context_templates.clash.name generates context "manual" which is already defined`,
+		`error: kubeconfigs.payments.context_templates.empty.name is required`,
+		`error: kubeconfigs.payments.context_templates.matrix.clusters[1] references missing cluster "dev"`,
+		`error: kubeconfigs.payments.context_templates.matrix.users[1] references missing shared authinfo "ops"`,
+		`error: kubeconfigs.payments.default_context references missing context "dev-api-view"`,
+	}, diags[1:])
+}
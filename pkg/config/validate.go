@@ -0,0 +1,525 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Severity describes how serious a Diagnostic is.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a single problem found while validating a Config. Path is the
+// dotted field path of the offending value, for example
+// kubeconfigs.demo.contexts.admin.cluster. File is the file the value was
+// loaded from when the config is split across files. Line and Column are
+// 1-based and zero when the position in the source document is unknown.
+type Diagnostic struct {
+	File     string   `json:"file,omitempty"`
+	Path     string   `json:"path"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+}
+
+// Error implements error and mirrors the messages returned by the compiler.
+func (d Diagnostic) Error() string {
+	if d.Path == "" {
+		return d.Message
+	}
+	return d.Path + " " + d.Message
+}
+
+// String returns the diagnostic prefixed with its position and severity.
+func (d Diagnostic) String() string {
+	if d.Line > 0 {
+		return fmt.Sprintf("%d:%d: %s: %s", d.Line, d.Column, d.Severity, d.Error())
+	}
+	return fmt.Sprintf("%s: %s", d.Severity, d.Error())
+}
+
+// Diagnostics is a list of problems. It implements error so that it can be
+// returned from Validate.
+type Diagnostics []Diagnostic
+
+func (d Diagnostics) Error() string {
+	lines := make([]string, len(d))
+	for i, diag := range d {
+		lines[i] = diag.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// HasErrors returns true if at least one diagnostic has SeverityError.
+func (d Diagnostics) HasErrors() bool {
+	for _, diag := range d {
+		if diag.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors returns only the diagnostics with SeverityError.
+func (d Diagnostics) Errors() Diagnostics {
+	var res Diagnostics
+	for _, diag := range d {
+		if diag.Severity == SeverityError {
+			res = append(res, diag)
+		}
+	}
+	return res
+}
+
+// Sort orders diagnostics by path, then severity and message, so that output
+// is stable between runs regardless of map iteration order.
+func (d Diagnostics) Sort() {
+	sort.SliceStable(d, func(i, j int) bool {
+		if d[i].Path != d[j].Path {
+			return d[i].Path < d[j].Path
+		}
+		if d[i].Severity != d[j].Severity {
+			return d[i].Severity < d[j].Severity
+		}
+		return d[i].Message < d[j].Message
+	})
+}
+
+// Validate returns a Diagnostics error containing every error found in the
+// config, or nil if the config is valid. Warnings are not returned, use
+// Diagnose to get them.
+func (c *Config) Validate() error {
+	if errs := c.Diagnose().Errors(); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Diagnose walks the whole config and returns all problems found, sorted.
+func (c *Config) Diagnose() Diagnostics {
+	v := &validator{}
+	v.validate(c)
+	if c != nil {
+		for i := range v.diags {
+			v.diags[i].File = c.SourceOf(v.diags[i].Path)
+			v.diags[i].Path = c.LocalPath(v.diags[i].Path)
+		}
+	}
+	v.diags.Sort()
+	return v.diags
+}
+
+type validator struct {
+	diags Diagnostics
+}
+
+func (v *validator) errorf(path, format string, args ...any) {
+	v.diags = append(v.diags, Diagnostic{Path: path, Severity: SeverityError, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warnf(path, format string, args ...any) {
+	v.diags = append(v.diags, Diagnostic{Path: path, Severity: SeverityWarning, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) validate(cfg *Config) {
+	if cfg == nil {
+		v.errorf("", "config is nil")
+		return
+	}
+
+	if err := CheckVersion(cfg.Version); err != nil {
+		if errors.Is(err, ErrNeedsMigration) {
+			v.errorf("version", "%q is outdated, run kubecfg migrate to upgrade to %s", cfg.Version, CurrentVersion)
+		} else {
+			v.errorf("version", "%q is not supported by this kubecfg, expected %s", cfg.Version, CurrentVersion)
+		}
+	}
+
+	if cfg.DefaultWorkspace != "" && cfg.Workspace(cfg.DefaultWorkspace) == nil {
+		v.errorf("default_workspace", "references missing workspace %q", cfg.DefaultWorkspace)
+	}
+
+	if cfg.Backups != nil && *cfg.Backups < 0 {
+		v.errorf("backups", "must not be negative, use 0 to disable backups")
+	}
+
+	v.validateShared(cfg)
+
+	aliases := make(map[string]string)
+	for _, name := range sortedKeys(cfg.Kubeconfigs) {
+		v.validateKubeconfig(cfg, "kubeconfigs."+name, name, cfg.Kubeconfigs[name], aliases)
+	}
+
+	for _, name := range sortedKeys(cfg.Workspaces) {
+		v.validateWorkspace(cfg, "workspaces."+name, cfg.Workspaces[name])
+	}
+
+	for i, overlay := range cfg.Overlays {
+		v.validateOverlay(cfg, fmt.Sprintf("overlays[%d]", i), overlay)
+	}
+}
+
+// validateOverlay validates every overlay, including the ones that don't match
+// this host, so that mistakes show up wherever the config is used.
+func (v *validator) validateOverlay(cfg *Config, path string, overlay *Overlay) {
+	if overlay == nil {
+		v.errorf(path, "is nil")
+		return
+	}
+
+	if overlay.Match.IsEmpty() {
+		v.errorf(path+".match", "requires hostname, user or env")
+	} else if err := checkGlob(overlay.Match.Hostname); err != nil {
+		v.errorf(path+".match.hostname", "is not a valid glob: %v", err)
+	}
+
+	if overlay.DefaultWorkspace != "" && cfg.Workspace(overlay.DefaultWorkspace) == nil {
+		v.errorf(path+".default_workspace", "references missing workspace %q", overlay.DefaultWorkspace)
+	}
+
+	for _, name := range sortedKeys(overlay.LoginSources) {
+		if _, ok := cfg.LoginSources[name]; !ok {
+			v.errorf(path+".login_sources."+name, "references missing shared login source %q", name)
+		}
+	}
+
+	for _, name := range sortedKeys(overlay.Kubeconfigs) {
+		kcPath := path + ".kubeconfigs." + name
+		kc := cfg.Kubeconfig(name)
+		if kc == nil {
+			v.errorf(kcPath, "references missing kubeconfig %q", name)
+			continue
+		}
+		if patch := overlay.Kubeconfigs[name]; patch != nil {
+			for _, sourceName := range sortedKeys(patch.LoginSources) {
+				if _, ok := kc.LoginSources[sourceName]; !ok {
+					v.errorf(kcPath+".login_sources."+sourceName, "references missing login source %q", sourceName)
+				}
+			}
+		}
+	}
+}
+
+func (v *validator) validateWorkspace(cfg *Config, path string, ws *Workspace) {
+	if ws == nil {
+		v.errorf(path, "is nil")
+		return
+	}
+
+	seen := make(map[string]struct{}, len(ws.Kubeconfigs))
+	for i, name := range ws.Kubeconfigs {
+		itemPath := fmt.Sprintf("%s.kubeconfigs[%d]", path, i)
+		if _, ok := seen[name]; ok {
+			v.warnf(itemPath, "lists kubeconfig %q more than once", name)
+			continue
+		}
+		seen[name] = struct{}{}
+
+		if cfg.Kubeconfig(name) == nil {
+			v.errorf(itemPath, "references missing kubeconfig %q", name)
+		}
+	}
+
+	if ws.Selector != nil {
+		selector, err := ws.Selector.Selector()
+		if err != nil {
+			v.errorf(path+".selector", "is invalid: %v", err)
+		} else {
+			for name, kc := range cfg.Kubeconfigs {
+				if kc != nil && selector.Matches(labels.Set(kc.Labels)) {
+					seen[name] = struct{}{}
+				}
+			}
+		}
+	}
+
+	if ws.DefaultKubeconfig != "" {
+		if _, ok := seen[ws.DefaultKubeconfig]; !ok {
+			v.errorf(path+".default_kubeconfig", "references missing kubeconfig %q", ws.DefaultKubeconfig)
+		}
+	}
+
+	switch ws.Render {
+	case "", WorkspaceRenderSeparate:
+		if ws.Path != "" {
+			v.warnf(path+".path", "is ignored because render is not %s", WorkspaceRenderMerged)
+		}
+	case WorkspaceRenderMerged:
+	default:
+		v.errorf(path+".render", "%q is not supported, expected %s or %s", ws.Render, WorkspaceRenderSeparate, WorkspaceRenderMerged)
+	}
+}
+
+// validateKubeconfig validates the entries defined by kc itself. References
+// are checked against kc with everything inherited through extends merged in,
+// inherited entries are validated on the kubeconfig that defines them.
+func (v *validator) validateKubeconfig(cfg *Config, path, name string, kc *Kubeconfig, aliases map[string]string) {
+	if kc == nil {
+		v.errorf(path, "is nil")
+		return
+	}
+
+	if strings.TrimSpace(kc.Path) == "" {
+		v.errorf(path+".path", "is required")
+	}
+
+	switch kc.ProtectedConfirm {
+	case "", ProtectedConfirmYes, ProtectedConfirmTypeName:
+		if kc.ProtectedConfirm != "" && !kc.Protected {
+			v.warnf(path+".protected_confirm", "is ignored because protected is not set")
+		}
+	default:
+		v.errorf(path+".protected_confirm", "%q is not supported, expected %s or %s", kc.ProtectedConfirm, ProtectedConfirmYes, ProtectedConfirmTypeName)
+	}
+
+	if kc.Color != "" && !slices.Contains(KubeconfigColors, kc.Color) {
+		v.errorf(path+".color", "%q is not supported, expected one of %s", kc.Color, strings.Join(KubeconfigColors, ", "))
+	}
+
+	// The kubeconfig name itself is also a lookup alias.
+	if owner, ok := aliases[name]; ok && owner != name {
+		v.errorf(path, "name %q is also used as an alias by kubeconfig %q", name, owner)
+	}
+	aliases[name] = name
+
+	for i, alias := range kc.Aliases {
+		alias = strings.TrimSpace(alias)
+		aliasPath := fmt.Sprintf("%s.aliases[%d]", path, i)
+		if alias == "" {
+			v.warnf(aliasPath, "is empty")
+			continue
+		}
+		if owner, ok := aliases[alias]; ok && owner != name {
+			v.errorf(aliasPath, "alias %q is also used by kubeconfig %q", alias, owner)
+			continue
+		}
+		aliases[alias] = name
+	}
+
+	resolved, err := cfg.ResolveKubeconfig(name)
+	if err != nil {
+		// Extends errors are reported once, on the kubeconfig at fault. The
+		// entries can't be checked without knowing what is inherited.
+		var extendsErr *ExtendsError
+		if errors.As(err, &extendsErr) && extendsErr.Kubeconfig == name {
+			v.errorf(path+".extends", "%s", extendsErr.Message())
+		}
+		return
+	}
+
+	for _, clusterName := range sortedKeys(kc.Clusters) {
+		v.validateCluster(path+".clusters."+clusterName, resolved.Clusters[clusterName])
+	}
+
+	for _, authInfoName := range sortedKeys(kc.AuthInfos) {
+		if kc.AuthInfos[authInfoName] == nil {
+			v.errorf(path+".auth_infos."+authInfoName, "is nil")
+		}
+	}
+
+	for _, sourceName := range sortedKeys(kc.LoginSources) {
+		v.validateLoginSource(path+".login_sources."+sourceName, resolved.LoginSources[sourceName])
+	}
+
+	for _, contextName := range sortedKeys(kc.Contexts) {
+		v.validateContext(path+".contexts."+contextName, cfg, resolved, resolved.Contexts[contextName])
+	}
+
+	// Generated contexts are contexts of the kubeconfig like any other, they
+	// can't replace one and can be made current.
+	contexts := maps.Clone(resolved.Contexts)
+	if contexts == nil {
+		contexts = make(map[string]*Context)
+	}
+	for _, templateName := range sortedKeys(resolved.ContextTemplates) {
+		templatePath := path + ".context_templates." + templateName
+
+		var names []string
+		var generated map[string]*Context
+		if _, ok := kc.ContextTemplates[templateName]; ok {
+			names, generated = v.validateContextTemplate(templatePath, cfg, resolved, resolved.ContextTemplates[templateName])
+		} else if tmpl := resolved.ContextTemplates[templateName]; tmpl != nil {
+			// Inherited templates are validated on the kubeconfig that defines them.
+			names, generated, _ = tmpl.Expand()
+		}
+
+		for _, contextName := range names {
+			if _, ok := contexts[contextName]; ok {
+				v.errorf(templatePath+".name", "generates context %q which is already defined", contextName)
+				continue
+			}
+			contexts[contextName] = generated[contextName]
+		}
+	}
+
+	if current := strings.TrimSpace(kc.CurrentContext); current != "" && contexts[current] == nil {
+		v.errorf(path+".current_context", "references missing context %q", current)
+	}
+
+	if def := strings.TrimSpace(kc.DefaultContext); def != "" && contexts[def] == nil {
+		v.errorf(path+".default_context", "references missing context %q", def)
+	}
+}
+
+// validateShared validates the top level clusters, auth infos and login
+// sources that contexts reference as shared:<name>.
+func (v *validator) validateShared(cfg *Config) {
+	for _, name := range sortedKeys(cfg.Clusters) {
+		v.validateCluster("clusters."+name, cfg.Clusters[name])
+	}
+
+	for _, name := range sortedKeys(cfg.AuthInfos) {
+		if cfg.AuthInfos[name] == nil {
+			v.errorf("auth_infos."+name, "is nil")
+		}
+	}
+
+	for _, name := range sortedKeys(cfg.LoginSources) {
+		v.validateLoginSource("login_sources."+name, cfg.LoginSources[name])
+	}
+}
+
+func (v *validator) validateCluster(path string, cluster *Cluster) {
+	if cluster == nil {
+		v.errorf(path, "is nil")
+		return
+	}
+	if strings.TrimSpace(cluster.Server) == "" {
+		v.warnf(path+".server", "is empty")
+	}
+}
+
+func (v *validator) validateLoginSource(path string, source *LoginSource) {
+	if source == nil {
+		v.errorf(path, "is nil")
+		return
+	}
+	if strings.TrimSpace(source.Command) == "" {
+		v.errorf(path+".command", "is required")
+	}
+}
+
+func (v *validator) validateContext(path string, cfg *Config, kc *Kubeconfig, ctx *Context) {
+	if ctx == nil {
+		v.errorf(path, "is nil")
+		return
+	}
+
+	ref := ctx.ImportRef
+	loginSourceName := strings.TrimSpace(ref.LoginSourceName)
+	importContextName := strings.TrimSpace(ref.ContextName)
+	clusterKey := strings.TrimSpace(ctx.Cluster)
+	authInfoKey := strings.TrimSpace(ctx.AuthInfo)
+
+	if ref.isSet() {
+		if loginSourceName == "" {
+			v.errorf(path+".import_ref.login_source", "is required")
+		} else if name, ok := SharedName(loginSourceName); ok {
+			if _, ok := cfg.LoginSources[name]; !ok {
+				v.errorf(path+".import_ref.login_source", "references missing shared login source %q", name)
+			}
+		} else if _, ok := kc.LoginSources[loginSourceName]; !ok {
+			v.errorf(path+".import_ref.login_source", "references missing login source %q", loginSourceName)
+		}
+
+		if importContextName == "" {
+			v.errorf(path+".import_ref.context", "is required")
+		}
+
+		if clusterKey != "" {
+			v.warnf(path+".cluster", "is ignored because import_ref is set")
+		}
+		if authInfoKey != "" {
+			v.warnf(path+".authinfo", "is ignored because import_ref is set")
+		}
+		return
+	}
+
+	v.validateClusterRef(path+".cluster", cfg, kc, clusterKey)
+	v.validateAuthInfoRef(path+".authinfo", cfg, kc, authInfoKey)
+}
+
+// validateContextTemplate validates tmpl and returns the contexts it generates
+// as returned by Expand, or nothing if it can't be expanded.
+func (v *validator) validateContextTemplate(path string, cfg *Config, kc *Kubeconfig, tmpl *ContextTemplate) ([]string, map[string]*Context) {
+	if tmpl == nil {
+		v.errorf(path, "is nil")
+		return nil, nil
+	}
+
+	for i, cluster := range tmpl.Clusters {
+		v.validateClusterRef(fmt.Sprintf("%s.clusters[%d]", path, i), cfg, kc, strings.TrimSpace(cluster))
+	}
+	for i, user := range tmpl.Users {
+		v.validateAuthInfoRef(fmt.Sprintf("%s.users[%d]", path, i), cfg, kc, strings.TrimSpace(user))
+	}
+
+	names, generated, err := tmpl.Expand()
+	if err != nil {
+		var tmplErr *TemplateError
+		if errors.As(err, &tmplErr) {
+			v.errorf(path+"."+tmplErr.Field, "%s", tmplErr.Message)
+		}
+		return nil, nil
+	}
+	return names, generated
+}
+
+// validateClusterRef checks that key names a cluster of kc or a shared cluster.
+func (v *validator) validateClusterRef(path string, cfg *Config, kc *Kubeconfig, key string) {
+	if key == "" {
+		v.errorf(path, "is required")
+	} else if name, ok := SharedName(key); ok {
+		validateSharedRef(v, path, "cluster", name, cfg.Clusters, kc.Clusters)
+	} else if kc.Cluster(key) == nil {
+		v.errorf(path, "references missing cluster %q", key)
+	}
+}
+
+// validateAuthInfoRef checks that key names an auth info of kc or a shared
+// auth info.
+func (v *validator) validateAuthInfoRef(path string, cfg *Config, kc *Kubeconfig, key string) {
+	if key == "" {
+		v.errorf(path, "is required")
+	} else if name, ok := SharedName(key); ok {
+		validateSharedRef(v, path, "authinfo", name, cfg.AuthInfos, kc.AuthInfos)
+	} else if kc.AuthInfo(key) == nil {
+		v.errorf(path, "references missing authinfo %q", key)
+	}
+}
+
+// validateSharedRef checks that the shared entry called name exists and that
+// its name, which it is rendered under, is not also used by a local entry.
+func validateSharedRef[V any](v *validator, path, kind, name string, shared, local map[string]V) {
+	if _, ok := shared[name]; !ok {
+		v.errorf(path, "references missing shared %s %q", kind, name)
+		return
+	}
+	if _, ok := local[name]; ok {
+		v.errorf(path, "references shared %s %q which clashes with the %s of the same name in this kubeconfig", kind, name, kind)
+	}
+}
+
+// isSet returns true if any of the import_ref fields has a value.
+func (r ImportRef) isSet() bool {
+	return strings.TrimSpace(r.LoginSourceName) != "" ||
+		strings.TrimSpace(r.ContextName) != "" ||
+		strings.TrimSpace(r.ClusterName) != "" ||
+		strings.TrimSpace(r.AuthInfoName) != ""
+}
+
+// sortedKeys returns the keys of m in lexical order.
+func sortedKeys[V any](m map[string]V) []string {
+	return slices.Sorted(maps.Keys(m))
+}
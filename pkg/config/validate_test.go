@@ -0,0 +1,194 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateReturnsNilForValidConfig(t *testing.T) {
+	cfg := Config{
+		DefaultWorkspace: "work",
+		Workspaces: map[string]*Workspace{
+			"work": {
+				Kubeconfigs:       []string{"demo"},
+				DefaultKubeconfig: "demo",
+			},
+		},
+		Kubeconfigs: map[string]*Kubeconfig{
+			"demo": {
+				Path:           "/tmp/demo",
+				CurrentContext: "admin",
+				Clusters: map[string]*Cluster{
+					"cluster": {Server: "https://example.com"},
+				},
+				AuthInfos: map[string]*AuthInfo{
+					"user": {},
+				},
+				Contexts: map[string]*Context{
+					"admin": {Cluster: "cluster", AuthInfo: "user"},
+				},
+			},
+		},
+	}
+
+	require.NoError(t, cfg.Validate())
+	require.Empty(t, cfg.Diagnose())
+}
+
+func TestValidateReportsAllProblemsInStableOrder(t *testing.T) {
+	cfg := Config{
+		DefaultWorkspace: "missing",
+		Workspaces: map[string]*Workspace{
+			"work": {
+				Kubeconfigs:       []string{"demo", "other", "demo"},
+				DefaultKubeconfig: "nope",
+			},
+		},
+		Kubeconfigs: map[string]*Kubeconfig{
+			"demo": {
+				CurrentContext: "missing",
+				Aliases:        []string{"d"},
+				LoginSources: map[string]*LoginSource{
+					"oidc": {},
+				},
+				Contexts: map[string]*Context{
+					"admin": {Cluster: "cluster"},
+					"imported": {
+						Cluster:   "cluster",
+						ImportRef: ImportRef{LoginSourceName: "sso"},
+					},
+				},
+			},
+			"other": {
+				Path:    "/tmp/other",
+				Aliases: []string{"d"},
+			},
+		},
+	}
+
+	for range 5 {
+		diags := cfg.Diagnose()
+		require.Equal(t, []string{
+			"error: default_workspace references missing workspace \"missing\"",
+			"error: kubeconfigs.demo.contexts.admin.authinfo is required",
+			"error: kubeconfigs.demo.contexts.admin.cluster references missing cluster \"cluster\"",
+			"warning: kubeconfigs.demo.contexts.imported.cluster is ignored because import_ref is set",
+			"error: kubeconfigs.demo.contexts.imported.import_ref.context is required",
+			"error: kubeconfigs.demo.contexts.imported.import_ref.login_source references missing login source \"sso\"",
+			"error: kubeconfigs.demo.current_context references missing context \"missing\"",
+			"error: kubeconfigs.demo.login_sources.oidc.command is required",
+			"error: kubeconfigs.demo.path is required",
+			"error: kubeconfigs.other.aliases[0] alias \"d\" is also used by kubeconfig \"demo\"",
+			"error: workspaces.work.default_kubeconfig references missing kubeconfig \"nope\"",
+			"warning: workspaces.work.kubeconfigs[2] lists kubeconfig \"demo\" more than once",
+		}, diagnosticStrings(diags))
+	}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	diags, ok := err.(Diagnostics)
+	require.True(t, ok)
+	require.Len(t, diags, 10)
+	for _, diag := range diags {
+		require.Equal(t, SeverityError, diag.Severity)
+	}
+}
+
+func TestSourceMapLocatesDiagnostics(t *testing.T) {
+	src := []byte(`version: v1
+workspaces:
+  work:
+    kubeconfigs:
+      - demo
+      - missing
+kubeconfigs:
+  prod.eu:
+    path: /tmp/prod
+    contexts:
+      admin:
+        cluster: nope
+        authInfo: user
+`)
+
+	sourceMap, err := NewSourceMap(src)
+	require.NoError(t, err)
+
+	diags := Diagnostics{
+		{Path: "workspaces.work.kubeconfigs[1]", Severity: SeverityError},
+		{Path: "kubeconfigs.prod.eu.contexts.admin.cluster", Severity: SeverityError},
+		{Path: "kubeconfigs.prod.eu.contexts.admin.authinfo", Severity: SeverityError},
+		{Path: "kubeconfigs.prod.eu.current_context", Severity: SeverityError},
+		{Path: "default_workspace", Severity: SeverityError},
+	}
+	sourceMap.Locate(diags)
+
+	require.Equal(t, [2]int{6, 9}, [2]int{diags[0].Line, diags[0].Column})
+	require.Equal(t, [2]int{12, 9}, [2]int{diags[1].Line, diags[1].Column})
+	require.Equal(t, [2]int{13, 9}, [2]int{diags[2].Line, diags[2].Column})
+	// Missing keys fall back to the closest existing parent.
+	require.Equal(t, [2]int{8, 3}, [2]int{diags[3].Line, diags[3].Column})
+	require.Equal(t, [2]int{0, 0}, [2]int{diags[4].Line, diags[4].Column})
+}
+
+func diagnosticStrings(diags Diagnostics) []string {
+	res := make([]string, len(diags))
+	for i, diag := range diags {
+		res[i] = diag.String()
+	}
+	return res
+}
+
+func TestDiagnoseChecksProtectedConfirm(t *testing.T) {
+	cfg := &Config{
+		Kubeconfigs: map[string]*Kubeconfig{
+			"dev":  {Path: "/tmp/dev.yaml", ProtectedConfirm: ProtectedConfirmTypeName},
+			"prod": {Path: "/tmp/prod.yaml", Protected: true, ProtectedConfirm: "retype"},
+			"test": {Path: "/tmp/test.yaml", Protected: true, ProtectedConfirm: ProtectedConfirmTypeName},
+		},
+	}
+
+	require.Equal(t, []string{
+		`warning: kubeconfigs.dev.protected_confirm is ignored because protected is not set`,
+		`error: kubeconfigs.prod.protected_confirm "retype" is not supported, expected yes or type-name`,
+	}, diagnosticStrings(cfg.Diagnose()))
+}
+
+func TestDiagnoseChecksWorkspaceRender(t *testing.T) {
+	cfg := &Config{
+		Workspaces: map[string]*Workspace{
+			"home": {Path: "@/home.yaml"},
+			"team": {Render: WorkspaceRenderMerged, Path: "@/team.yaml"},
+			"work": {Render: "combined"},
+		},
+	}
+
+	require.Equal(t, []string{
+		`warning: workspaces.home.path is ignored because render is not merged`,
+		`error: workspaces.work.render "combined" is not supported, expected separate or merged`,
+	}, diagnosticStrings(cfg.Diagnose()))
+}
+
+func TestDiagnoseChecksBackups(t *testing.T) {
+	backups := -1
+	cfg := &Config{Backups: &backups}
+	require.Equal(t, []string{
+		`error: backups must not be negative, use 0 to disable backups`,
+	}, diagnosticStrings(cfg.Diagnose()))
+
+	backups = 0
+	require.Empty(t, diagnosticStrings(cfg.Diagnose()))
+}
+
+func TestDiagnoseChecksColor(t *testing.T) {
+	cfg := &Config{
+		Kubeconfigs: map[string]*Kubeconfig{
+			"dev":  {Path: "/tmp/dev.yaml", Color: "hi-green"},
+			"prod": {Path: "/tmp/prod.yaml", Color: "crimson"},
+		},
+	}
+
+	require.Equal(t, []string{
+		`error: kubeconfigs.prod.color "crimson" is not supported, expected one of black, red, green, yellow, blue, magenta, cyan, white, hi-black, hi-red, hi-green, hi-yellow, hi-blue, hi-magenta, hi-cyan, hi-white`,
+	}, diagnosticStrings(cfg.Diagnose()))
+}
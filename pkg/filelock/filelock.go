@@ -0,0 +1,145 @@
+// Package filelock provides advisory locks on files shared between processes.
+// A lock file records the process holding it, so that processes waiting for
+// it can tell the user what they are waiting for.
+package filelock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrTimeout is returned by Acquire when the lock isn't released in time.
+var ErrTimeout = errors.New("timed out waiting for lock")
+
+// pollInterval is how often Acquire retries a lock held by another process.
+var pollInterval = 100 * time.Millisecond
+
+// errLocked is returned by tryLock when another process holds the lock.
+var errLocked = errors.New("locked")
+
+// Holder is the process holding a lock.
+type Holder struct {
+	PID     int    `json:"pid"`
+	Command string `json:"command"`
+}
+
+// String returns h as "pid N (command)".
+func (h Holder) String() string {
+	if h.PID == 0 {
+		return "another process"
+	}
+	if h.Command == "" {
+		return fmt.Sprintf("pid %d", h.PID)
+	}
+	return fmt.Sprintf("pid %d (%s)", h.PID, h.Command)
+}
+
+// Lock is an exclusive lock on a lock file.
+type Lock struct {
+	file *os.File
+}
+
+// Acquire takes the exclusive lock on the lock file at path, creating the
+// file if needed. While another process holds the lock, waiting is called with
+// the holder when it is first seen and whenever it changes, and Acquire
+// retries until the lock is released, ctx is done or timeout has passed. A
+// timeout of zero fails immediately.
+func Acquire(ctx context.Context, path string, timeout time.Duration, waiting func(Holder)) (*Lock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	var (
+		last     Holder
+		reported bool
+	)
+	for {
+		err := tryLock(f)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, errLocked) {
+			f.Close()
+			return nil, fmt.Errorf("lock %s: %w", path, err)
+		}
+
+		holder := readHolder(path)
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("%w: %s is held by %s", ErrTimeout, path, holder)
+		}
+		// The holder is unknown for a moment while it is written or
+		// released, which is only worth reporting if nothing else was.
+		if waiting != nil && (!reported || holder.PID != 0 && holder != last) {
+			waiting(holder)
+			last, reported = holder, true
+		}
+
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return nil, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+
+	if err := writeHolder(f); err != nil {
+		unlock(f)
+		f.Close()
+		return nil, fmt.Errorf("lock %s: %w", path, err)
+	}
+	return &Lock{file: f}, nil
+}
+
+// Release releases the lock. The lock file is left in place, since removing
+// it would race with processes that are about to lock it.
+func (l *Lock) Release() error {
+	if err := l.file.Truncate(0); err != nil {
+		unlock(l.file)
+		l.file.Close()
+		return err
+	}
+	if err := unlock(l.file); err != nil {
+		l.file.Close()
+		return err
+	}
+	return l.file.Close()
+}
+
+// writeHolder records the current process in the lock file f.
+func writeHolder(f *os.File) error {
+	data, err := json.Marshal(Holder{PID: os.Getpid(), Command: commandLine()})
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err = f.WriteAt(data, 0)
+	return err
+}
+
+// readHolder returns the process recorded in the lock file at path. The
+// holder may not have written it yet, in which case it is unknown.
+func readHolder(path string) Holder {
+	var holder Holder
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return holder
+	}
+	_ = json.Unmarshal(data, &holder)
+	return holder
+}
+
+func commandLine() string {
+	args := append([]string{filepath.Base(os.Args[0])}, os.Args[1:]...)
+	return strings.Join(args, " ")
+}
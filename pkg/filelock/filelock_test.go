@@ -0,0 +1,62 @@
+package filelock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireWaitsForHolder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+
+	held, err := Acquire(context.Background(), path, 0, nil)
+	require.NoError(t, err)
+
+	// A second lock on the same file conflicts even within this process.
+	var waitedFor []Holder
+	released := make(chan error)
+	go func() {
+		time.Sleep(3 * pollInterval)
+		released <- held.Release()
+	}()
+
+	lock, err := Acquire(context.Background(), path, 5*time.Second, func(h Holder) {
+		waitedFor = append(waitedFor, h)
+	})
+	require.NoError(t, err)
+	require.NoError(t, <-released)
+
+	require.Len(t, waitedFor, 1)
+	require.Equal(t, os.Getpid(), waitedFor[0].PID)
+	require.Equal(t, commandLine(), waitedFor[0].Command)
+	require.NoError(t, lock.Release())
+}
+
+func TestAcquireTimesOut(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+
+	held, err := Acquire(context.Background(), path, 0, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, held.Release())
+	})
+
+	_, err = Acquire(context.Background(), path, pollInterval, nil)
+	require.ErrorIs(t, err, ErrTimeout)
+	require.ErrorContains(t, err, path+" is held by "+Holder{PID: os.Getpid(), Command: commandLine()}.String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Acquire(ctx, path, time.Minute, nil)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestHolderString(t *testing.T) {
+	require.Equal(t, "pid 42 (kubecfg render --all)", Holder{PID: 42, Command: "kubecfg render --all"}.String())
+	require.Equal(t, "pid 42", Holder{PID: 42}.String())
+	require.Equal(t, "another process", Holder{}.String())
+}
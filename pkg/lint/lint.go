@@ -0,0 +1,179 @@
+// Package lint runs rules over a kubecfg config and reports findings that are
+// valid configuration but likely mistakes, such as plaintext secrets.
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/amimof/kubecfg/pkg/config"
+)
+
+// Input is the data rules inspect. Runtime may be nil if the config could not
+// be compiled, rules must handle that.
+type Input struct {
+	Config  *config.Config
+	Runtime *config.RuntimeConfig
+}
+
+// Finding is a single problem reported by a rule.
+type Finding struct {
+	config.Diagnostic
+	Rule    string `json:"rule"`
+	Fixable bool   `json:"fixable,omitempty"`
+	Fixed   bool   `json:"fixed,omitempty"`
+}
+
+// Rule inspects the config and returns findings.
+type Rule interface {
+	ID() string
+	Description() string
+	Check(in *Input) []Finding
+}
+
+// Fixer is implemented by rules whose findings can be fixed mechanically by
+// editing the config document.
+type Fixer interface {
+	Fix(doc *config.Document, f Finding) error
+}
+
+type Option func(*Linter)
+
+// WithRules replaces the default rule set.
+func WithRules(rules ...Rule) Option {
+	return func(l *Linter) {
+		l.rules = rules
+	}
+}
+
+// WithDisabled disables the rules with the given IDs.
+func WithDisabled(ids ...string) Option {
+	return func(l *Linter) {
+		for _, id := range ids {
+			l.disabled[id] = struct{}{}
+		}
+	}
+}
+
+// Linter runs a set of rules.
+type Linter struct {
+	rules    []Rule
+	disabled map[string]struct{}
+}
+
+// NewLinter returns a Linter using DefaultRules unless WithRules is given.
+func NewLinter(opts ...Option) *Linter {
+	l := &Linter{
+		rules:    DefaultRules(),
+		disabled: make(map[string]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// Rules returns every rule known to the linter, including disabled ones.
+func (l *Linter) Rules() []Rule {
+	return l.rules
+}
+
+// Enabled returns true if the rule with id is enabled.
+func (l *Linter) Enabled(id string) bool {
+	_, disabled := l.disabled[id]
+	return !disabled
+}
+
+// Unknown returns disabled rule IDs that do not match any rule.
+func (l *Linter) Unknown() []string {
+	known := make(map[string]struct{}, len(l.rules))
+	for _, rule := range l.rules {
+		known[rule.ID()] = struct{}{}
+	}
+
+	var res []string
+	for id := range l.disabled {
+		if _, ok := known[id]; !ok {
+			res = append(res, id)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Lint runs all enabled rules and returns their findings sorted by path and
+// rule ID.
+func (l *Linter) Lint(in *Input) []Finding {
+	var findings []Finding
+
+	for _, rule := range l.rules {
+		if !l.Enabled(rule.ID()) {
+			continue
+		}
+		for _, f := range rule.Check(in) {
+			f.Rule = rule.ID()
+			if _, ok := rule.(Fixer); !ok {
+				f.Fixable = false
+			}
+			findings = append(findings, f)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Path != findings[j].Path {
+			return findings[i].Path < findings[j].Path
+		}
+		return findings[i].Rule < findings[j].Rule
+	})
+
+	return findings
+}
+
+// Fix applies the fix of every fixable finding to doc and marks it as fixed.
+// Fixes are applied bottom-up so that removing an entry never shifts the path
+// of another finding. Returns the number of findings fixed.
+func (l *Linter) Fix(doc *config.Document, findings []Finding) (int, error) {
+	rules := make(map[string]Rule, len(l.rules))
+	for _, rule := range l.rules {
+		rules[rule.ID()] = rule
+	}
+
+	order := make([]int, 0, len(findings))
+	for i, f := range findings {
+		if f.Fixable && !f.Fixed {
+			order = append(order, i)
+		}
+	}
+
+	type position struct{ line, column int }
+	positions := make(map[int]position, len(order))
+	for _, i := range order {
+		line, column, _ := doc.Position(findings[i].Path)
+		positions[i] = position{line, column}
+	}
+
+	sort.SliceStable(order, func(a, b int) bool {
+		pa, pb := positions[order[a]], positions[order[b]]
+		if pa.line != pb.line {
+			return pa.line > pb.line
+		}
+		return pa.column > pb.column
+	})
+
+	fixed := 0
+	for _, i := range order {
+		fixer, ok := rules[findings[i].Rule].(Fixer)
+		if !ok {
+			continue
+		}
+		if err := fixer.Fix(doc, findings[i]); err != nil {
+			return fixed, fmt.Errorf("%s: %w", findings[i].Rule, err)
+		}
+		findings[i].Fixed = true
+		fixed++
+	}
+
+	return fixed, nil
+}
@@ -0,0 +1,192 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/amimof/kubecfg/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintReportsDefaultRules(t *testing.T) {
+	cfg := newLintTestConfig()
+
+	findings := NewLinter().Lint(&Input{Config: &cfg})
+	require.Equal(t, []string{
+		"alias-shadows-kubeconfig warning: kubeconfigs.dev.aliases[0] alias \"PROD\" shadows kubeconfig \"prod\"",
+		"unused-kubeconfig warning: kubeconfigs.orphan is not referenced by any workspace",
+		"duplicate-path error: kubeconfigs.orphan.path \"/tmp/dev.yaml\" is also used by kubeconfig \"dev\"",
+		"plaintext-secret error: kubeconfigs.prod.auth_infos.admin.clientKeyData stores a plaintext secret; use encryptedClientKeyData instead",
+		"plaintext-secret warning: kubeconfigs.prod.auth_infos.admin.password is ignored because encryptedPassword is set",
+		"plaintext-secret error: kubeconfigs.prod.auth_infos.admin.token stores a plaintext secret; use encryptedToken instead",
+		"insecure-skip-tls-verify warning: kubeconfigs.prod.clusters.prod.insecure_skip_tls_verify disables TLS certificate verification",
+		"unused-login-source warning: kubeconfigs.prod.login_sources.unused is not imported by any context",
+	}, findingStrings(findings))
+}
+
+func TestLintSkipsDisabledRules(t *testing.T) {
+	cfg := newLintTestConfig()
+
+	linter := NewLinter(WithDisabled("plaintext-secret", "unused-kubeconfig", "nope"))
+	for _, f := range linter.Lint(&Input{Config: &cfg}) {
+		require.NotEqual(t, "plaintext-secret", f.Rule)
+		require.NotEqual(t, "unused-kubeconfig", f.Rule)
+	}
+	require.Equal(t, []string{"nope"}, linter.Unknown())
+}
+
+func TestLintFixRemovesFixableEntries(t *testing.T) {
+	cfg := newLintTestConfig()
+	doc, err := config.ParseDocument([]byte(`workspaces:
+  work:
+    kubeconfigs: [prod, dev]
+kubeconfigs:
+  prod:
+    path: /tmp/prod.yaml
+    login_sources:
+      # keep this one
+      sso:
+        command: login
+      unused:
+        command: login
+    auth_infos:
+      admin:
+        token: plain
+        password: plain
+        encryptedPassword: cipher
+  dev:
+    path: /tmp/dev.yaml
+    aliases:
+      - PROD
+      - d
+`))
+	require.NoError(t, err)
+
+	linter := NewLinter()
+	findings := linter.Lint(&Input{Config: &cfg})
+	fixed, err := linter.Fix(doc, findings)
+	require.NoError(t, err)
+	require.Equal(t, 3, fixed)
+
+	out, err := doc.Bytes()
+	require.NoError(t, err)
+	require.Equal(t, `workspaces:
+  work:
+    kubeconfigs: [prod, dev]
+kubeconfigs:
+  prod:
+    path: /tmp/prod.yaml
+    login_sources:
+      # keep this one
+      sso:
+        command: login
+    auth_infos:
+      admin:
+        token: plain
+        encryptedPassword: cipher
+  dev:
+    path: /tmp/dev.yaml
+    aliases:
+      - d
+`, string(out))
+}
+
+func findingStrings(findings []Finding) []string {
+	res := make([]string, len(findings))
+	for i, f := range findings {
+		res[i] = f.Rule + " " + f.String()
+	}
+	return res
+}
+
+func newLintTestConfig() config.Config {
+	return config.Config{
+		Workspaces: map[string]*config.Workspace{
+			"work": {Kubeconfigs: []string{"prod", "dev"}},
+		},
+		Kubeconfigs: map[string]*config.Kubeconfig{
+			"prod": {
+				Path: "/tmp/prod.yaml",
+				LoginSources: map[string]*config.LoginSource{
+					"sso":    {Command: "login"},
+					"unused": {Command: "login"},
+				},
+				Clusters: map[string]*config.Cluster{
+					"prod": {Server: "https://prod.example.com", InsecureSkipTLSVerify: true},
+				},
+				AuthInfos: map[string]*config.AuthInfo{
+					"admin": {Token: "plain", Password: "plain", EncryptedPassword: "cipher", ClientKeyData: []byte("plain")},
+				},
+				Contexts: map[string]*config.Context{
+					"admin": {ImportRef: config.ImportRef{LoginSourceName: "sso", ContextName: "admin"}},
+				},
+			},
+			"dev": {
+				Path:    "/tmp/dev.yaml",
+				Aliases: []string{"PROD", "d"},
+			},
+			"orphan": {
+				Path: "/tmp/dev.yaml",
+			},
+		},
+	}
+}
+
+func TestLintCountsExtendedKubeconfigsAsUsed(t *testing.T) {
+	cfg := config.Config{
+		Workspaces: map[string]*config.Workspace{
+			"work": {Kubeconfigs: []string{"prod"}},
+		},
+		Kubeconfigs: map[string]*config.Kubeconfig{
+			"base": {
+				Path: "/tmp/base.yaml",
+				LoginSources: map[string]*config.LoginSource{
+					"sso": {Command: "login"},
+				},
+			},
+			"prod": {
+				Extends: "base",
+				Path:    "/tmp/prod.yaml",
+				Contexts: map[string]*config.Context{
+					"admin": {ImportRef: config.ImportRef{LoginSourceName: "sso", ContextName: "admin"}},
+				},
+			},
+		},
+	}
+
+	linter := NewLinter(WithRules(&UnusedKubeconfigRule{}, &UnusedLoginSourceRule{}))
+	require.Empty(t, linter.Lint(&Input{Config: &cfg}))
+}
+
+func TestLintChecksSharedEntries(t *testing.T) {
+	cfg := config.Config{
+		Clusters: map[string]*config.Cluster{
+			"prod-eu": {Server: "https://prod-eu.example.com", InsecureSkipTLSVerify: true},
+		},
+		AuthInfos: map[string]*config.AuthInfo{
+			"oidc": {Token: "plain"},
+		},
+		LoginSources: map[string]*config.LoginSource{
+			"sso":    {Command: "login"},
+			"unused": {Command: "login"},
+		},
+		Workspaces: map[string]*config.Workspace{
+			"work": {Kubeconfigs: []string{"prod"}},
+		},
+		Kubeconfigs: map[string]*config.Kubeconfig{
+			"prod": {
+				Path: "/tmp/prod.yaml",
+				Contexts: map[string]*config.Context{
+					"prod": {Cluster: "shared:prod-eu", AuthInfo: "shared:oidc"},
+					"sso":  {ImportRef: config.ImportRef{LoginSourceName: "shared:sso", ContextName: "prod"}},
+				},
+			},
+		},
+	}
+
+	findings := NewLinter().Lint(&Input{Config: &cfg})
+	require.Equal(t, []string{
+		"plaintext-secret error: auth_infos.oidc.token stores a plaintext secret; use encryptedToken instead",
+		"insecure-skip-tls-verify warning: clusters.prod-eu.insecure_skip_tls_verify disables TLS certificate verification",
+		"unused-login-source warning: login_sources.unused is not imported by any context",
+	}, findingStrings(findings))
+}
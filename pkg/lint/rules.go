@@ -0,0 +1,372 @@
+package lint
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/amimof/kubecfg/pkg/config"
+)
+
+// DefaultRules returns the rules run by kubecfg lint.
+func DefaultRules() []Rule {
+	return []Rule{
+		&PlaintextSecretRule{},
+		&InsecureSkipTLSVerifyRule{},
+		&UnusedKubeconfigRule{},
+		&UnusedLoginSourceRule{},
+		&DuplicatePathRule{},
+		&AliasShadowsKubeconfigRule{},
+	}
+}
+
+// PlaintextSecretRule reports auth infos that store a token, password or
+// client key in plaintext instead of in the matching encrypted field.
+type PlaintextSecretRule struct{}
+
+func (r *PlaintextSecretRule) ID() string { return "plaintext-secret" }
+
+func (r *PlaintextSecretRule) Description() string {
+	return "token, password and client key data should use their encrypted fields"
+}
+
+func (r *PlaintextSecretRule) Check(in *Input) []Finding {
+	var findings []Finding
+
+	forEachAuthInfo(in.Config, func(path string, ai *config.AuthInfo) {
+		secrets := []struct {
+			field     string
+			encrypted string
+			plaintext bool
+			hasCipher bool
+		}{
+			{"token", "encryptedToken", ai.Token != "", ai.EncryptedToken != ""},
+			{"password", "encryptedPassword", ai.Password != "", ai.EncryptedPassword != ""},
+			{"clientKeyData", "encryptedClientKeyData", len(ai.ClientKeyData) > 0, len(ai.EncryptedClientKeyData) > 0},
+		}
+
+		for _, secret := range secrets {
+			if !secret.plaintext {
+				continue
+			}
+			if secret.hasCipher {
+				findings = append(findings, warning(path+"."+secret.field, true,
+					"is ignored because %s is set", secret.encrypted))
+				continue
+			}
+			findings = append(findings, errorf(path+"."+secret.field, false,
+				"stores a plaintext secret; use %s instead", secret.encrypted))
+		}
+	})
+
+	return findings
+}
+
+// Fix removes plaintext values that are shadowed by an encrypted value.
+func (r *PlaintextSecretRule) Fix(doc *config.Document, f Finding) error {
+	return deletePath(doc, f.Path)
+}
+
+// InsecureSkipTLSVerifyRule reports clusters that disable TLS verification.
+type InsecureSkipTLSVerifyRule struct{}
+
+func (r *InsecureSkipTLSVerifyRule) ID() string { return "insecure-skip-tls-verify" }
+
+func (r *InsecureSkipTLSVerifyRule) Description() string {
+	return "clusters should not disable TLS certificate verification"
+}
+
+func (r *InsecureSkipTLSVerifyRule) Check(in *Input) []Finding {
+	var findings []Finding
+
+	forEachCluster(in.Config, func(path string, cluster *config.Cluster) {
+		if cluster.InsecureSkipTLSVerify {
+			findings = append(findings, warning(path+".insecure_skip_tls_verify", false,
+				"disables TLS certificate verification"))
+		}
+	})
+
+	return findings
+}
+
+// UnusedKubeconfigRule reports kubeconfigs that are not a member of any
+// workspace and therefore can't be selected by render.
+type UnusedKubeconfigRule struct{}
+
+func (r *UnusedKubeconfigRule) ID() string { return "unused-kubeconfig" }
+
+func (r *UnusedKubeconfigRule) Description() string {
+	return "kubeconfigs should be referenced by at least one workspace"
+}
+
+func (r *UnusedKubeconfigRule) Check(in *Input) []Finding {
+	used := make(map[string]struct{})
+	if in.Runtime != nil {
+		for _, ws := range in.Runtime.Workspaces {
+			for name := range ws.Kubeconfigs {
+				used[name] = struct{}{}
+			}
+		}
+	} else if in.Config != nil {
+		for name, ws := range in.Config.Workspaces {
+			if ws == nil {
+				continue
+			}
+			// Invalid selectors are reported by validation.
+			names, _ := in.Config.WorkspaceKubeconfigs(name)
+			for _, name := range names {
+				used[name] = struct{}{}
+			}
+		}
+	}
+
+	// Kubeconfigs extended by a used kubeconfig are used as well.
+	for name := range used {
+		for _, base := range extendsChain(in.Config, name) {
+			used[base] = struct{}{}
+		}
+	}
+
+	var findings []Finding
+	forEachKubeconfig(in.Config, func(kcName string, kc *config.Kubeconfig) {
+		if _, ok := used[kcName]; !ok {
+			findings = append(findings, warning("kubeconfigs."+kcName, false, "is not referenced by any workspace"))
+		}
+	})
+
+	return findings
+}
+
+// UnusedLoginSourceRule reports login sources that no context imports from.
+// They still run on every render, which is slow and may prompt for login.
+type UnusedLoginSourceRule struct{}
+
+func (r *UnusedLoginSourceRule) ID() string { return "unused-login-source" }
+
+func (r *UnusedLoginSourceRule) Description() string {
+	return "login sources should be imported by at least one context"
+}
+
+func (r *UnusedLoginSourceRule) Check(in *Input) []Finding {
+	var findings []Finding
+
+	// Contexts import from login sources of the kubeconfig they are defined in
+	// and from every kubeconfig it extends. Shared login sources are recorded
+	// under the empty name.
+	imports := make(map[string]map[string]struct{})
+	forEachKubeconfig(in.Config, func(kcName string, kc *config.Kubeconfig) {
+		for _, owner := range append([]string{kcName, ""}, extendsChain(in.Config, kcName)...) {
+			if imports[owner] == nil {
+				imports[owner] = make(map[string]struct{})
+			}
+			for _, ctx := range kc.Contexts {
+				if ctx != nil {
+					imports[owner][strings.TrimSpace(ctx.ImportRef.LoginSourceName)] = struct{}{}
+				}
+			}
+		}
+	})
+
+	// Shared login sources can be imported by any context.
+	if in.Config != nil {
+		for _, name := range sortedKeys(in.Config.LoginSources) {
+			if _, ok := imports[""][config.SharedPrefix+name]; ok {
+				continue
+			}
+			findings = append(findings, warning("login_sources."+name, true, "is not imported by any context"))
+		}
+	}
+
+	forEachKubeconfig(in.Config, func(kcName string, kc *config.Kubeconfig) {
+		imported := imports[kcName]
+
+		for _, name := range sortedKeys(kc.LoginSources) {
+			if _, ok := imported[name]; ok {
+				continue
+			}
+			findings = append(findings, warning(
+				fmt.Sprintf("kubeconfigs.%s.login_sources.%s", kcName, name), true,
+				"is not imported by any context",
+			))
+		}
+	})
+
+	return findings
+}
+
+// Fix removes the unused login source.
+func (r *UnusedLoginSourceRule) Fix(doc *config.Document, f Finding) error {
+	return deletePath(doc, f.Path)
+}
+
+// DuplicatePathRule reports kubeconfigs that render to the same file and
+// therefore overwrite each other.
+type DuplicatePathRule struct{}
+
+func (r *DuplicatePathRule) ID() string { return "duplicate-path" }
+
+func (r *DuplicatePathRule) Description() string {
+	return "kubeconfigs must not render to the same path"
+}
+
+func (r *DuplicatePathRule) Check(in *Input) []Finding {
+	var findings []Finding
+	owners := make(map[string]string)
+
+	forEachKubeconfig(in.Config, func(kcName string, kc *config.Kubeconfig) {
+		path := config.ResolvePath(in.Config.BaseDir, kc.Path)
+		if in.Runtime != nil {
+			if rk, ok := in.Runtime.Kubeconfigs[kcName]; ok {
+				path = rk.Path
+			}
+		}
+		if path == "" {
+			return
+		}
+
+		if owner, ok := owners[path]; ok {
+			findings = append(findings, errorf("kubeconfigs."+kcName+".path", false,
+				"%q is also used by kubeconfig %q", path, owner))
+			return
+		}
+		owners[path] = kcName
+	})
+
+	return findings
+}
+
+// AliasShadowsKubeconfigRule reports aliases that match the name of another
+// kubeconfig when compared case-insensitively, which makes lookups ambiguous.
+type AliasShadowsKubeconfigRule struct{}
+
+func (r *AliasShadowsKubeconfigRule) ID() string { return "alias-shadows-kubeconfig" }
+
+func (r *AliasShadowsKubeconfigRule) Description() string {
+	return "aliases should not shadow the name of another kubeconfig"
+}
+
+func (r *AliasShadowsKubeconfigRule) Check(in *Input) []Finding {
+	var findings []Finding
+
+	names := make(map[string]string)
+	forEachKubeconfig(in.Config, func(kcName string, _ *config.Kubeconfig) {
+		names[strings.ToLower(kcName)] = kcName
+	})
+
+	forEachKubeconfig(in.Config, func(kcName string, kc *config.Kubeconfig) {
+		for i, alias := range kc.Aliases {
+			owner, ok := names[strings.ToLower(strings.TrimSpace(alias))]
+			if !ok || owner == kcName {
+				continue
+			}
+			findings = append(findings, warning(
+				fmt.Sprintf("kubeconfigs.%s.aliases[%d]", kcName, i), true,
+				"alias %q shadows kubeconfig %q", alias, owner,
+			))
+		}
+	})
+
+	return findings
+}
+
+// Fix removes the shadowing alias.
+func (r *AliasShadowsKubeconfigRule) Fix(doc *config.Document, f Finding) error {
+	return deletePath(doc, f.Path)
+}
+
+// extendsChain returns the kubeconfigs name extends, closest first. Cycles and
+// missing kubeconfigs end the chain.
+func extendsChain(cfg *config.Config, name string) []string {
+	if cfg == nil {
+		return nil
+	}
+
+	var chain []string
+	seen := map[string]struct{}{name: {}}
+
+	for kc := cfg.Kubeconfig(name); kc != nil; {
+		base := strings.TrimSpace(kc.Extends)
+		if _, ok := seen[base]; ok || base == "" {
+			break
+		}
+		seen[base] = struct{}{}
+		chain = append(chain, base)
+		kc = cfg.Kubeconfig(base)
+	}
+
+	return chain
+}
+
+func forEachKubeconfig(cfg *config.Config, fn func(name string, kc *config.Kubeconfig)) {
+	if cfg == nil {
+		return
+	}
+	for _, name := range sortedKeys(cfg.Kubeconfigs) {
+		if kc := cfg.Kubeconfigs[name]; kc != nil {
+			fn(name, kc)
+		}
+	}
+}
+
+// forEachAuthInfo calls fn with the path of every shared auth info followed by
+// the auth infos of every kubeconfig.
+func forEachAuthInfo(cfg *config.Config, fn func(path string, ai *config.AuthInfo)) {
+	if cfg == nil {
+		return
+	}
+	forEachEntry("auth_infos", cfg.AuthInfos, fn)
+	forEachKubeconfig(cfg, func(kcName string, kc *config.Kubeconfig) {
+		forEachEntry("kubeconfigs."+kcName+".auth_infos", kc.AuthInfos, fn)
+	})
+}
+
+// forEachCluster calls fn with the path of every shared cluster followed by
+// the clusters of every kubeconfig.
+func forEachCluster(cfg *config.Config, fn func(path string, cluster *config.Cluster)) {
+	if cfg == nil {
+		return
+	}
+	forEachEntry("clusters", cfg.Clusters, fn)
+	forEachKubeconfig(cfg, func(kcName string, kc *config.Kubeconfig) {
+		forEachEntry("kubeconfigs."+kcName+".clusters", kc.Clusters, fn)
+	})
+}
+
+func forEachEntry[V any](prefix string, m map[string]*V, fn func(path string, v *V)) {
+	for _, name := range sortedKeys(m) {
+		if v := m[name]; v != nil {
+			fn(prefix+"."+name, v)
+		}
+	}
+}
+
+func deletePath(doc *config.Document, path string) error {
+	if !doc.Delete(path) {
+		return fmt.Errorf("%s not found in document", path)
+	}
+	return nil
+}
+
+func warning(path string, fixable bool, format string, args ...any) Finding {
+	return newFinding(config.SeverityWarning, path, fixable, format, args...)
+}
+
+func errorf(path string, fixable bool, format string, args ...any) Finding {
+	return newFinding(config.SeverityError, path, fixable, format, args...)
+}
+
+func newFinding(severity config.Severity, path string, fixable bool, format string, args ...any) Finding {
+	return Finding{
+		Diagnostic: config.Diagnostic{
+			Path:     path,
+			Severity: severity,
+			Message:  fmt.Sprintf(format, args...),
+		},
+		Fixable: fixable,
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	return slices.Sorted(maps.Keys(m))
+}